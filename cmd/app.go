@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/kadyrbayev2005/studysync/internal/config"
+	"github.com/kadyrbayev2005/studysync/internal/migrations"
+	"github.com/kadyrbayev2005/studysync/internal/services"
+	"gorm.io/gorm"
+)
+
+// needs says which parts of the runtime a command bootstraps.
+type needs int
+
+const (
+	needDB     needs = 1 << iota
+	needSchema       // the database schema must be current; implies needDB
+	needRedis
+	needAuth
+)
+
+// app is what the commands share: the loaded config and the connections
+// they asked for.
+type app struct {
+	cfg *config.Config
+	db  *gorm.DB
+}
+
+func bootstrap(configPath string, n needs) (*app, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DevMode {
+		log.Println("WARNING: dev mode is enabled; insecure defaults are allowed")
+	}
+	a := &app{cfg: cfg}
+
+	if n&(needDB|needSchema) != 0 {
+		if a.db, err = services.ConnectDB(cfg.Database); err != nil {
+			return nil, fmt.Errorf("database connection failed: %w", err)
+		}
+	}
+	if n&needSchema != 0 {
+		if err := checkSchema(a.db); err != nil {
+			a.Close()
+			return nil, err
+		}
+	}
+	if n&needRedis != 0 {
+		services.InitRedis(cfg.Redis)
+	}
+	if n&needAuth != 0 {
+		if err := services.InitAuth(cfg.JWT); err != nil {
+			a.Close()
+			return nil, fmt.Errorf("auth setup failed: %w", err)
+		}
+	}
+	return a, nil
+}
+
+func (a *app) Close() {
+	if a.db != nil {
+		if sqlDB, err := a.db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+	if services.RedisClient != nil {
+		services.RedisClient.Close()
+	}
+}
+
+// checkSchema refuses to run on a database with pending migrations.
+func checkSchema(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	m, err := migrations.New(sqlDB)
+	if err != nil {
+		return err
+	}
+	if err := m.Check(context.Background()); err != nil {
+		return fmt.Errorf("%w (run \"studysync migrate up\")", err)
+	}
+	return nil
+}
+
+// newFlagSet returns the flag set of a command, printing args after the
+// command name in its usage line.
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: studysync %s [flags] %s\n\nflags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"github.com/kadyrbayev2005/studysync/internal/services"
+	"gorm.io/gorm"
+)
+
+const minAdminPasswordLength = 12
+
+func runCreateAdmin(configPath string, args []string) error {
+	fs := newFlagSet("create-admin", "")
+	email := fs.String("email", "", "email of the admin (required)")
+	name := fs.String("name", "Administrator", "display name for a new account")
+	password := fs.String("password", "", "password (default: $STUDYSYNC_ADMIN_PASSWORD, else read from stdin)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		fs.Usage()
+		return errors.New("-email is required")
+	}
+
+	pw := *password
+	if pw == "" {
+		pw = os.Getenv("STUDYSYNC_ADMIN_PASSWORD")
+	}
+
+	a, err := bootstrap(configPath, needSchema)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	users := repository.NewUserRepository(a.db)
+	user, err := users.GetByEmail(*email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if pw == "" {
+			if pw, err = readPassword(); err != nil {
+				return err
+			}
+		}
+		if len(pw) < minAdminPasswordLength {
+			return fmt.Errorf("password must be at least %d characters", minAdminPasswordLength)
+		}
+
+		user = models.User{
+			Name:         *name,
+			Email:        *email,
+			PasswordHash: services.HashPassword(pw),
+			Role:         services.RoleAdmin,
+			CreatedAt:    time.Now(),
+		}
+		if err := users.Create(&user); err != nil {
+			return err
+		}
+		fmt.Printf("Created admin %s (id %d)\n", user.Email, user.ID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// existing account: promote it, and reset the password if one was given
+	data := map[string]interface{}{"role": services.RoleAdmin}
+	if pw != "" {
+		if len(pw) < minAdminPasswordLength {
+			return fmt.Errorf("password must be at least %d characters", minAdminPasswordLength)
+		}
+		data["password_hash"] = services.HashPassword(pw)
+	}
+	if err := users.Update(user.ID, data); err != nil {
+		return err
+	}
+	fmt.Printf("Promoted %s (id %d) to admin\n", user.Email, user.ID)
+	return nil
+}
+
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("no password given")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
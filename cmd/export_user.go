@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"github.com/kadyrbayev2005/studysync/internal/services"
+)
+
+func runExportUser(configPath string, args []string) error {
+	fs := newFlagSet("export-user", "")
+	id := fs.Uint("id", 0, "id of the user")
+	email := fs.String("email", "", "email of the user (instead of -id)")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (*id == 0) == (*email == "") {
+		fs.Usage()
+		return errors.New("give exactly one of -id and -email")
+	}
+
+	a, err := bootstrap(configPath, needSchema)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	userID := *id
+	if *email != "" {
+		user, err := repository.NewUserRepository(a.db).GetByEmail(*email)
+		if err != nil {
+			return fmt.Errorf("user %s: %w", *email, err)
+		}
+		userID = user.ID
+	}
+
+	export, err := services.ExportUser(a.db, uint(userID))
+	if err != nil {
+		return fmt.Errorf("user %d: %w", userID, err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.OpenFile(*output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(export)
+}
@@ -24,51 +24,67 @@ package main
 import (
 	_ "github.com/kadyrbayev2005/studysync/docs"
 
-	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
-
-	"github.com/kadyrbayev2005/studysync/internal/api"
-	"github.com/kadyrbayev2005/studysync/internal/services"
 )
 
-func main() {
-	db, err := services.ConnectDB()
-	if err != nil {
-		log.Fatal("Database connection failed:", err)
-	}
-
-	services.InitRedis()
-
-	workerCtx, workerCancel := context.WithCancel(context.Background())
-	go services.StartReminderWorker(workerCtx, db)
+// command is a studysync subcommand. run gets the arguments after the
+// command name and the -config path given before it.
+type command struct {
+	name    string
+	summary string
+	run     func(configPath string, args []string) error
+}
 
-	router := api.SetupRouter(db)
+var commands = []command{
+	{"serve", "run the HTTP API (and, by default, the background workers)", runServe},
+	{"worker", "run the reminder, recurrence and trash workers without the API", runWorker},
+	{"migrate", "apply, roll back or create schema migrations", runMigrate},
+	{"seed", "load demo data for local development", runSeed},
+	{"create-admin", "create an admin account or promote an existing user", runCreateAdmin},
+	{"export-user", "write everything stored about a user as JSON", runExportUser},
+	{"purge-expired", "delete expired refresh tokens, old notifications, change log entries and trash", runPurgeExpired},
+}
 
-	srv := api.NewServer(router, ":8080")
+func main() {
+	configPath := flag.String("config", "", "path to a YAML or TOML config file (default: $STUDYSYNC_CONFIG)")
+	flag.Usage = usage
+	flag.Parse()
+
+	// without a command the binary serves, as it always has
+	name, args := "serve", flag.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
 
-	go func() {
-		if err := srv.Run(); err != nil {
-			log.Fatalf("server run error: %v", err)
+	for _, c := range commands {
+		if c.name != name {
+			continue
 		}
-	}()
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	log.Println("Shutting down server...")
-
-	workerCancel()
+		if err := c.run(*configPath, args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			log.Fatalf("%s: %v", name, err)
+		}
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server shutdown failed: %v", err)
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "usage: studysync [-config file] <command> [flags]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-14s %s\n", c.name, c.summary)
 	}
-	log.Println("Server stopped gracefully")
+	fmt.Fprintf(out, "\nflags:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(out, "\nRun \"studysync <command> -h\" for the flags of a command.\n")
 }
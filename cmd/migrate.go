@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/kadyrbayev2005/studysync/internal/migrations"
+)
+
+const migrateUsage = `usage: studysync migrate <command>
+
+commands:
+  up             apply all pending migrations
+  down [n]       roll back the last n migrations (default 1)
+  status         list migrations and when they were applied, and count
+                 subjects and tasks that have no owner
+  create <name>  add an empty migration pair to -dir`
+
+// runMigrate implements the "migrate" subcommand.
+func runMigrate(configPath string, args []string) error {
+	fs := newFlagSet("migrate", "up | down [n] | status | create <name>")
+	dir := fs.String("dir", "internal/migrations", "directory for new migration files (create)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			return errors.New("usage: studysync migrate create <name>")
+		}
+		up, down, err := migrations.Create(*dir, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Println("Created", up)
+		fmt.Println("Created", down)
+		return nil
+	}
+
+	a, err := bootstrap(configPath, needDB)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	sqlDB, err := a.db.DB()
+	if err != nil {
+		return err
+	}
+
+	m, err := migrations.New(sqlDB)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		done, err := m.Up(ctx)
+		for _, mig := range done {
+			fmt.Println("Applied", mig)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Println("Schema is up to date")
+		}
+		return reportOwnerless(ctx, sqlDB)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errors.New("down: n must be a positive number")
+			}
+		}
+		done, err := m.Down(ctx, steps)
+		for _, mig := range done {
+			fmt.Println("Rolled back", mig)
+		}
+		return err
+
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		owners := false
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+				owners = owners || s.Name == "owners"
+			}
+			fmt.Printf("%-40s %s\n", s, applied)
+		}
+		if owners {
+			return reportOwnerless(ctx, sqlDB)
+		}
+		return nil
+	}
+
+	return errors.New(migrateUsage)
+}
+
+// reportOwnerless prints how many subjects and tasks have no owner. They
+// are rows from before owners existed that 0002_owners could not assign,
+// and only admins can see them.
+func reportOwnerless(ctx context.Context, db *sql.DB) error {
+	for _, table := range []string{"subjects", "tasks"} {
+		var n int64
+		if err := db.QueryRowContext(ctx, "SELECT count(*) FROM "+table+" WHERE user_id IS NULL").Scan(&n); err != nil {
+			return err
+		}
+		if n > 0 {
+			fmt.Printf("%d %s have no owner and are only visible to admins; set their user_id to hand them to a user\n", n, table)
+		}
+	}
+	return nil
+}
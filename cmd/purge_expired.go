@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+)
+
+func runPurgeExpired(configPath string, args []string) error {
+	fs := newFlagSet("purge-expired", "")
+	notificationRetention := fs.Duration("notification-retention", 90*24*time.Hour, "keep sent and failed notifications this long")
+	changeRetention := fs.Duration("change-retention", 90*24*time.Hour, "keep the sync change log this long; older sync tokens expire")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := bootstrap(configPath, needSchema)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	now := time.Now()
+
+	tokens, err := repository.NewRefreshTokenRepository(a.db).DeleteExpired(now)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d expired refresh tokens\n", tokens)
+
+	notifications, err := repository.NewNotificationRepository(a.db).DeleteSettledBefore(now.Add(-*notificationRetention))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d notifications older than %s\n", notifications, *notificationRetention)
+
+	changes, err := repository.NewChangeRepository(a.db).Purge(now.Add(-*changeRetention))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d change log entries older than %s\n", changes, *changeRetention)
+
+	trashed, err := repository.NewTrashRepository(a.db).Purge(now.Add(-a.cfg.Trash.Retention.Duration))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d items that were in the trash longer than %s\n", trashed, a.cfg.Trash.Retention.Duration)
+	return nil
+}
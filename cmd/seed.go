@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/config"
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"github.com/kadyrbayev2005/studysync/internal/services"
+	"gorm.io/gorm"
+)
+
+type seedTask struct {
+	title  string
+	status string
+	due    time.Duration // from now
+	rule   string
+}
+
+// essayWorkflow shows a subject with statuses of its own.
+var essayWorkflow = &models.Workflow{
+	States: []models.WorkflowState{
+		{Name: "draft", Kind: models.KindTodo},
+		{Name: "submitted", Kind: models.KindActive},
+		{Name: "graded", Kind: models.KindDone},
+	},
+	Transitions: []models.WorkflowTransition{
+		{From: "draft", To: "submitted"},
+		{From: "submitted", To: "draft"},
+		{From: "submitted", To: "graded"},
+	},
+}
+
+var seedSubjects = []struct {
+	name     string
+	workflow *models.Workflow
+	tasks    []seedTask
+}{
+	{"Calculus", nil, []seedTask{
+		{"Problem set 1", "done", -48 * time.Hour, ""},
+		{"Problem set 2", "in-progress", 3 * 24 * time.Hour, ""},
+		{"Midterm revision", "todo", 14 * 24 * time.Hour, ""},
+	}},
+	{"Databases", nil, []seedTask{
+		{"ER diagram for the course project", "todo", 5 * 24 * time.Hour, ""},
+		{"Weekly lab report", "todo", 2 * 24 * time.Hour, "FREQ=WEEKLY;COUNT=10"},
+	}},
+	{"English", essayWorkflow, []seedTask{
+		{"Essay", "draft", 7 * 24 * time.Hour, ""},
+	}},
+}
+
+func runSeed(configPath string, args []string) error {
+	fs := newFlagSet("seed", "")
+	email := fs.String("email", "demo@studysync.local", "email of the demo user")
+	password := fs.String("password", "demo-password", "password of the demo user")
+	force := fs.Bool("force", false, "seed even when APP_ENV is production")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := bootstrap(configPath, needSchema)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	if a.cfg.Env == config.EnvProduction && !*force {
+		return errors.New("refusing to seed a production database (use -force)")
+	}
+
+	users := repository.NewUserRepository(a.db)
+	if _, err := users.GetByEmail(*email); err == nil {
+		fmt.Printf("User %s already exists; nothing to do\n", *email)
+		return nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		user := models.User{
+			Name:         "Demo Student",
+			Email:        *email,
+			PasswordHash: services.HashPassword(*password),
+			Role:         services.RoleUser,
+			CreatedAt:    time.Now(),
+		}
+		if err := repository.NewUserRepository(tx).Create(&user); err != nil {
+			return err
+		}
+
+		subjects := repository.NewSubjectRepository(tx)
+		tasks := repository.NewTaskRepository(tx)
+		deadlines := repository.NewDeadlineRepository(tx)
+		now := time.Now().Truncate(time.Hour)
+
+		count := 0
+		for _, s := range seedSubjects {
+			subject := models.Subject{Name: s.name, Workflow: s.workflow, UserID: user.ID}
+			if err := subjects.Create(&subject); err != nil {
+				return err
+			}
+
+			for _, t := range s.tasks {
+				task := models.Task{
+					Title:          t.title,
+					Status:         t.status,
+					Deadline:       now.Add(t.due),
+					SubjectID:      subject.ID,
+					Subject:        subject,
+					UserID:         user.ID,
+					RecurrenceRule: t.rule,
+				}
+				if err := services.StartStatus(subject.TaskWorkflow(), &task, now); err != nil {
+					return err
+				}
+				if task.IsSeriesMaster() {
+					if err := tasks.CreateSeries(&task); err != nil {
+						return err
+					}
+					if err := services.GenerateSeries(tasks, task, time.Now()); err != nil {
+						return err
+					}
+				} else {
+					if err := tasks.Create(&task); err != nil {
+						return err
+					}
+					if err := deadlines.Create(&models.Deadline{TaskID: task.ID, UserID: user.ID, DueDate: task.Deadline}); err != nil {
+						return err
+					}
+				}
+				count++
+			}
+		}
+
+		fmt.Printf("Seeded %s with %d subjects and %d tasks; it signs in with the password given to -password (see \"studysync seed -h\")\n", *email, len(seedSubjects), count)
+		return nil
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/kadyrbayev2005/studysync/internal/api"
+	"github.com/kadyrbayev2005/studysync/internal/services"
+)
+
+func runServe(configPath string, args []string) error {
+	fs := newFlagSet("serve", "")
+	workers := fs.Bool("workers", true, "also run the background workers in this process (disable when running \"studysync worker\" separately)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := bootstrap(configPath, needSchema|needRedis|needAuth)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	workerCtx, workerCancel := context.WithCancel(context.Background())
+	defer workerCancel()
+	waitWorkers := func() {}
+	if *workers {
+		if waitWorkers, err = startWorkers(workerCtx, a); err != nil {
+			return err
+		}
+	}
+
+	router := api.SetupRouter(a.db, a.cfg)
+	srv := api.NewServer(router, a.cfg.HTTP)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := srv.Run(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-serveErr:
+		return err
+	case <-quit:
+	}
+
+	log.Println("Shutting down server...")
+
+	workerCancel()
+	defer waitWorkers()
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.HTTP.ShutdownTimeout.Duration)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		return err
+	}
+	log.Println("Server stopped gracefully")
+	return nil
+}
+
+// startWorkers runs the reminder, recurrence and trash workers until ctx is
+// cancelled. Any number of processes may run them: reminders are claimed a
+// batch at a time and the other workers take turns. The returned func
+// waits for them to stop.
+func startWorkers(ctx context.Context, a *app) (func(), error) {
+	mailer, err := services.NewMailer(a.cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		services.StartReminderWorker(ctx, a.db, mailer)
+	}()
+	go func() {
+		defer wg.Done()
+		services.StartRecurrenceWorker(ctx, a.db)
+	}()
+	go func() {
+		defer wg.Done()
+		services.StartTrashWorker(ctx, a.db, a.cfg.Trash.Retention.Duration)
+	}()
+	return wg.Wait, nil
+}
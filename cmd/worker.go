@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os/signal"
+	"syscall"
+)
+
+func runWorker(configPath string, args []string) error {
+	fs := newFlagSet("worker", "")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := bootstrap(configPath, needSchema)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	wait, err := startWorkers(ctx, a)
+	if err != nil {
+		return err
+	}
+	log.Println("Workers running")
+
+	<-ctx.Done()
+	log.Println("Shutting down workers...")
+	wait()
+	log.Println("Workers stopped")
+	return nil
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	_ "github.com/kadyrbayev2005/studysync/docs"
+	"github.com/kadyrbayev2005/studysync/internal/config"
 	"github.com/kadyrbayev2005/studysync/internal/controllers"
 	"github.com/kadyrbayev2005/studysync/internal/middleware"
 	"github.com/kadyrbayev2005/studysync/internal/repository"
@@ -13,30 +14,59 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupRouter(db *gorm.DB) *gin.Engine {
+func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
+	if cfg.Env == config.EnvProduction {
+		gin.SetMode(gin.ReleaseMode)
+	}
 	r := gin.Default()
 
 	// repositories
 	userRepo := repository.NewUserRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
 	subjectRepo := repository.NewSubjectRepository(db)
 	taskRepo := repository.NewTaskRepository(db)
 	deadlineRepo := repository.NewDeadlineRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	reminderRepo := repository.NewReminderRepository(db)
+	calendarFeedRepo := repository.NewCalendarFeedRepository(db)
+	trashRepo := repository.NewTrashRepository(db)
+	dependencyRepo := repository.NewDependencyRepository(db)
+	subtaskRepo := repository.NewSubtaskRepository(db)
+	tagRepo := repository.NewTagRepository(db)
+	searchRepo := repository.NewSearchRepository(db)
 
 	// controllers
-	userController := controllers.NewUserController(userRepo)
+	userController := controllers.NewUserController(userRepo, refreshTokenRepo)
 	subjectController := controllers.NewSubjectController(subjectRepo)
-	taskController := controllers.NewTaskController(taskRepo)
+	taskController := controllers.NewTaskController(taskRepo, subjectRepo, dependencyRepo, subtaskRepo)
 	deadlineController := controllers.NewDeadlineController(deadlineRepo, taskRepo)
+	notificationController := controllers.NewNotificationController(notificationRepo)
+	reminderController := controllers.NewReminderController(reminderRepo, deadlineRepo)
+	calendarController := controllers.NewCalendarController(calendarFeedRepo, deadlineRepo, taskRepo, reminderRepo)
+	importController := controllers.NewImportController(services.NewImporter(db))
+	syncController := controllers.NewSyncController(services.NewSyncer(db))
+	trashController := controllers.NewTrashController(trashRepo, cfg.Trash.Retention.Duration)
+	dependencyController := controllers.NewDependencyController(dependencyRepo)
+	subtaskController := controllers.NewSubtaskController(subtaskRepo, taskRepo, subjectRepo)
+	tagController := controllers.NewTagController(tagRepo, taskRepo, subtaskRepo)
+	searchController := controllers.NewSearchController(searchRepo)
 
 	// auth routes
 	auth := r.Group("/auth")
 	{
 		auth.POST("/register", userController.Register)
 		auth.POST("/login", userController.Login)
+		auth.POST("/refresh", userController.Refresh)
+		auth.POST("/logout", middleware.AuthMiddleware(), userController.Logout)
+		auth.POST("/logout-all", middleware.AuthMiddleware(), userController.LogoutAll)
 	}
 
 	// public routes
 	r.GET("/health", func(c *gin.Context) { c.JSON(200, gin.H{"status": "ok"}) })
+	r.GET("/.well-known/jwks.json", controllers.GetJWKS)
+
+	// calendar clients cannot send a Bearer header; the URL token authenticates
+	r.GET("/calendar/:token/feed.ics", calendarController.GetFeed)
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -51,6 +81,18 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 			users.GET("", userController.GetAll)
 			users.GET("/:id", userController.GetByID)
 			users.DELETE("/:id", userController.Delete)
+			users.GET("/:id/notifications", notificationController.GetUserNotifications)
+		}
+
+		// Current user
+		me := protected.Group("/me")
+		{
+			me.GET("/notifications", notificationController.GetMyNotifications)
+			me.GET("/reminder-preferences", reminderController.GetMyPreferences)
+			me.PUT("/reminder-preferences", reminderController.UpdateMyPreferences)
+			me.GET("/calendar-feed", calendarController.GetMyFeed)
+			me.POST("/calendar-feed", calendarController.CreateMyFeed)
+			me.DELETE("/calendar-feed", calendarController.DeleteMyFeed)
 		}
 
 		// Subjects
@@ -60,6 +102,7 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 			subjectRoutes.GET("", subjectController.GetAllSubjects)
 			subjectRoutes.GET("/:id", subjectController.GetSubjectByID)
 			subjectRoutes.PUT("/:id", subjectController.UpdateSubject)
+			subjectRoutes.PATCH("/:id", subjectController.UpdateSubject)
 			subjectRoutes.DELETE("/:id", subjectController.DeleteSubject)
 		}
 
@@ -68,9 +111,39 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 		{
 			taskRoutes.POST("", taskController.CreateTask)
 			taskRoutes.GET("", taskController.GetAllTasks)
+			taskRoutes.GET("/graph", dependencyController.GetDependencyGraph)
 			taskRoutes.GET("/:id", taskController.GetTaskByID)
 			taskRoutes.PUT("/:id", taskController.UpdateTask)
+			taskRoutes.PATCH("/:id", taskController.UpdateTask)
 			taskRoutes.DELETE("/:id", taskController.DeleteTask)
+			taskRoutes.GET("/:id/transitions", taskController.GetTaskTransitions)
+			taskRoutes.POST("/:id/transitions", taskController.TransitionTask)
+
+			taskRoutes.GET("/:id/dependencies", dependencyController.GetBlockers)
+			taskRoutes.POST("/:id/dependencies", dependencyController.AddDependency)
+			taskRoutes.DELETE("/:id/dependencies/:blocker_id", dependencyController.RemoveDependency)
+
+			taskRoutes.GET("/:id/subtasks", subtaskController.GetSubtasks)
+			taskRoutes.POST("/:id/subtasks", subtaskController.CreateSubtask)
+			taskRoutes.GET("/:id/checklist", subtaskController.GetChecklist)
+			taskRoutes.POST("/:id/checklist", subtaskController.AddChecklistItem)
+			taskRoutes.PUT("/:id/checklist/:item_id", subtaskController.UpdateChecklistItem)
+			taskRoutes.PATCH("/:id/checklist/:item_id", subtaskController.UpdateChecklistItem)
+			taskRoutes.DELETE("/:id/checklist/:item_id", subtaskController.DeleteChecklistItem)
+
+			taskRoutes.POST("/:id/tags", tagController.TagTask)
+			taskRoutes.DELETE("/:id/tags", tagController.UntagTask)
+		}
+
+		// Tags
+		tagRoutes := protected.Group("/tags")
+		{
+			tagRoutes.POST("", tagController.CreateTag)
+			tagRoutes.GET("", tagController.GetAllTags)
+			tagRoutes.GET("/:id", tagController.GetTagByID)
+			tagRoutes.PUT("/:id", tagController.UpdateTag)
+			tagRoutes.PATCH("/:id", tagController.UpdateTag)
+			tagRoutes.DELETE("/:id", tagController.DeleteTag)
 		}
 
 		// Deadlines
@@ -80,7 +153,25 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 			deadlineRoutes.GET("", deadlineController.GetAllDeadlines)
 			deadlineRoutes.GET("/:id", deadlineController.GetDeadlineByID)
 			deadlineRoutes.DELETE("/:id", deadlineController.DeleteDeadline)
+
+			deadlineRoutes.GET("/:id/reminders", reminderController.GetDeadlineReminders)
+			deadlineRoutes.POST("/:id/reminders", reminderController.CreateDeadlineReminder)
+			deadlineRoutes.PUT("/:id/reminders", reminderController.ReplaceDeadlineReminders)
+			deadlineRoutes.DELETE("/:id/reminders/:reminder_id", reminderController.DeleteDeadlineReminder)
 		}
+
+		// Search
+		protected.GET("/search", searchController.Search)
+
+		// Import
+		protected.POST("/import/ics", importController.ImportICS)
+
+		// Offline sync
+		protected.POST("/sync", syncController.Sync)
+
+		// Trash
+		protected.GET("/trash", trashController.GetTrash)
+		protected.POST("/trash/:type/:id/restore", trashController.RestoreFromTrash)
 	}
 
 	return r
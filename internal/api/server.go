@@ -3,23 +3,23 @@ package api
 import (
 	"context"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/config"
 )
 
 type Server struct {
 	httpServer *http.Server
 }
 
-func NewServer(router *gin.Engine, addr string) *Server {
+func NewServer(router *gin.Engine, cfg config.HTTPConfig) *Server {
 	return &Server{
 		httpServer: &http.Server{
-			Addr:         addr,
+			Addr:         cfg.Addr,
 			Handler:      router,
-			ReadTimeout:  10 * time.Second,
-			WriteTimeout: 10 * time.Second,
-			IdleTimeout:  60 * time.Second,
+			ReadTimeout:  cfg.ReadTimeout.Duration,
+			WriteTimeout: cfg.WriteTimeout.Duration,
+			IdleTimeout:  cfg.IdleTimeout.Duration,
 		},
 	}
 }
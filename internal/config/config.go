@@ -0,0 +1,257 @@
+// Package config loads the StudySync runtime configuration. Values come
+// from built-in defaults, then an optional YAML or TOML file, then
+// environment variables, in that order of precedence.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+
+	// InsecureJWTSecret is the placeholder secret shipped in examples. It is
+	// only accepted in dev mode.
+	InsecureJWTSecret = "replace-with-secure-secret"
+
+	minJWTSecretLen = 32
+
+	// DefaultDSN is the database of docker-compose.yml, with its
+	// well-known password. It is only accepted in dev mode.
+	DefaultDSN = "user=postgres password=postgres dbname=studysync port=5433 sslmode=disable"
+
+	JWTAlgHS256 = "HS256"
+	JWTAlgRS256 = "RS256"
+	JWTAlgEdDSA = "EdDSA"
+)
+
+type Config struct {
+	Env     string `yaml:"env" toml:"env"`
+	DevMode bool   `yaml:"dev_mode" toml:"dev_mode"`
+
+	HTTP     HTTPConfig     `yaml:"http" toml:"http"`
+	Database DatabaseConfig `yaml:"database" toml:"database"`
+	Redis    RedisConfig    `yaml:"redis" toml:"redis"`
+	JWT      JWTConfig      `yaml:"jwt" toml:"jwt"`
+	Mail     MailConfig     `yaml:"mail" toml:"mail"`
+	Trash    TrashConfig    `yaml:"trash" toml:"trash"`
+}
+
+type HTTPConfig struct {
+	Addr            string   `yaml:"addr" toml:"addr"`
+	ReadTimeout     Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout    Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout     Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+}
+
+type DatabaseConfig struct {
+	DSN string `yaml:"dsn" toml:"dsn"`
+}
+
+type RedisConfig struct {
+	Addr     string `yaml:"addr" toml:"addr"`
+	Password string `yaml:"password" toml:"password"`
+	DB       int    `yaml:"db" toml:"db"`
+}
+
+type JWTConfig struct {
+	// Algorithm is HS256 (shared Secret), RS256 or EdDSA. The asymmetric
+	// algorithms load PEM keys named <kid>.pem from KeysDir: the private key
+	// named SigningKeyID signs new tokens, every key in the directory is
+	// accepted for verification and published in the JWKS.
+	Algorithm    string `yaml:"algorithm" toml:"algorithm"`
+	Secret       string `yaml:"secret" toml:"secret"`
+	KeysDir      string `yaml:"keys_dir" toml:"keys_dir"`
+	SigningKeyID string `yaml:"signing_key_id" toml:"signing_key_id"`
+
+	Issuer   string `yaml:"issuer" toml:"issuer"`
+	Audience string `yaml:"audience" toml:"audience"`
+
+	AccessTTL  Duration `yaml:"access_ttl" toml:"access_ttl"`
+	RefreshTTL Duration `yaml:"refresh_ttl" toml:"refresh_ttl"`
+}
+
+type MailConfig struct {
+	Transport string `yaml:"transport" toml:"transport"` // smtp | outbox | log
+	From      string `yaml:"from" toml:"from"`
+
+	SMTPHost     string `yaml:"smtp_host" toml:"smtp_host"`
+	SMTPPort     int    `yaml:"smtp_port" toml:"smtp_port"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password"`
+
+	OutboxDir string `yaml:"outbox_dir" toml:"outbox_dir"`
+}
+
+type TrashConfig struct {
+	// Retention is how long deleted subjects, tasks and deadlines can be
+	// restored before the worker purges them.
+	Retention Duration `yaml:"retention" toml:"retention"`
+}
+
+// Default returns the configuration used when nothing is overridden. It
+// matches docker-compose.yml and is only valid in dev mode.
+func Default() *Config {
+	return &Config{
+		Env: EnvDevelopment,
+		HTTP: HTTPConfig{
+			Addr:            ":8080",
+			ReadTimeout:     Duration{10 * time.Second},
+			WriteTimeout:    Duration{10 * time.Second},
+			IdleTimeout:     Duration{60 * time.Second},
+			ShutdownTimeout: Duration{10 * time.Second},
+		},
+		Database: DatabaseConfig{
+			DSN: DefaultDSN,
+		},
+		Redis: RedisConfig{
+			Addr: "localhost:6379",
+		},
+		JWT: JWTConfig{
+			Algorithm:  JWTAlgHS256,
+			Secret:     InsecureJWTSecret,
+			Issuer:     "studysync",
+			Audience:   "studysync-api",
+			AccessTTL:  Duration{15 * time.Minute},
+			RefreshTTL: Duration{30 * 24 * time.Hour},
+		},
+		Mail: MailConfig{
+			Transport: "outbox",
+			From:      "StudySync <no-reply@studysync.local>",
+			SMTPHost:  "localhost",
+			SMTPPort:  1025,
+			OutboxDir: "tmp/outbox",
+		},
+		Trash: TrashConfig{
+			Retention: Duration{30 * 24 * time.Hour},
+		},
+	}
+}
+
+// Load builds the configuration. path may be empty, in which case the
+// STUDYSYNC_CONFIG environment variable is consulted; when both are empty
+// only defaults and the environment are used.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path == "" {
+		path = os.Getenv("STUDYSYNC_CONFIG")
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".toml":
+		err = toml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("config: unsupported file type %q (use .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("config: parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks that the configuration is complete and, outside dev
+// mode, that it does not rely on insecure defaults.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Env != EnvDevelopment && c.Env != EnvProduction {
+		errs = append(errs, fmt.Errorf("env must be %q or %q, got %q", EnvDevelopment, EnvProduction, c.Env))
+	}
+	if c.HTTP.Addr == "" {
+		errs = append(errs, errors.New("http.addr is required"))
+	}
+	for name, d := range map[string]Duration{
+		"http.read_timeout":     c.HTTP.ReadTimeout,
+		"http.write_timeout":    c.HTTP.WriteTimeout,
+		"http.idle_timeout":     c.HTTP.IdleTimeout,
+		"http.shutdown_timeout": c.HTTP.ShutdownTimeout,
+		"jwt.access_ttl":        c.JWT.AccessTTL,
+		"jwt.refresh_ttl":       c.JWT.RefreshTTL,
+		"trash.retention":       c.Trash.Retention,
+	} {
+		if d.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", name))
+		}
+	}
+	if c.Database.DSN == "" {
+		errs = append(errs, errors.New("database.dsn is required"))
+	}
+	if c.Redis.Addr == "" {
+		errs = append(errs, errors.New("redis.addr is required"))
+	}
+	if c.Redis.DB < 0 {
+		errs = append(errs, errors.New("redis.db must not be negative"))
+	}
+	switch c.JWT.Algorithm {
+	case JWTAlgHS256:
+		if c.JWT.Secret == "" {
+			errs = append(errs, errors.New("jwt.secret is required for HS256"))
+		}
+	case JWTAlgRS256, JWTAlgEdDSA:
+		if c.JWT.KeysDir == "" || c.JWT.SigningKeyID == "" {
+			errs = append(errs, fmt.Errorf("jwt.keys_dir and jwt.signing_key_id are required for %s", c.JWT.Algorithm))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("jwt.algorithm must be %s, %s or %s, got %q", JWTAlgHS256, JWTAlgRS256, JWTAlgEdDSA, c.JWT.Algorithm))
+	}
+	if c.JWT.Issuer == "" || c.JWT.Audience == "" {
+		errs = append(errs, errors.New("jwt.issuer and jwt.audience are required"))
+	}
+	if c.Mail.From == "" {
+		errs = append(errs, errors.New("mail.from is required"))
+	}
+
+	if !c.DevMode {
+		if c.Database.DSN == DefaultDSN {
+			errs = append(errs, errors.New("database.dsn is the docker-compose default; set DATABASE_DSN or enable DEV_MODE"))
+		}
+		if c.JWT.Algorithm == JWTAlgHS256 {
+			if c.JWT.Secret == InsecureJWTSecret {
+				errs = append(errs, errors.New("jwt.secret is the insecure placeholder; set JWT_SECRET or enable DEV_MODE"))
+			} else if len(c.JWT.Secret) < minJWTSecretLen {
+				errs = append(errs, fmt.Errorf("jwt.secret must be at least %d bytes outside dev mode", minJWTSecretLen))
+			}
+		}
+		if c.Env == EnvProduction && c.Mail.Transport != "smtp" {
+			errs = append(errs, errors.New("mail.transport must be smtp in production"))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("config: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package config
+
+import "time"
+
+// Duration is a time.Duration that can be written as "10s" or "24h" in
+// YAML and TOML files.
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// applyEnv overrides the configuration with any environment variables that
+// are set. Variable names are listed next to the field they control.
+func (c *Config) applyEnv() error {
+	var err error
+	set := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+
+	envString("APP_ENV", &c.Env)
+	set(envBool("DEV_MODE", &c.DevMode))
+
+	envString("HTTP_ADDR", &c.HTTP.Addr)
+	set(envDuration("HTTP_READ_TIMEOUT", &c.HTTP.ReadTimeout))
+	set(envDuration("HTTP_WRITE_TIMEOUT", &c.HTTP.WriteTimeout))
+	set(envDuration("HTTP_IDLE_TIMEOUT", &c.HTTP.IdleTimeout))
+	set(envDuration("HTTP_SHUTDOWN_TIMEOUT", &c.HTTP.ShutdownTimeout))
+
+	envString("DATABASE_DSN", &c.Database.DSN)
+
+	envString("REDIS_ADDR", &c.Redis.Addr)
+	envString("REDIS_PASSWORD", &c.Redis.Password)
+	set(envInt("REDIS_DB", &c.Redis.DB))
+
+	envString("JWT_ALGORITHM", &c.JWT.Algorithm)
+	envString("JWT_SECRET", &c.JWT.Secret)
+	envString("JWT_KEYS_DIR", &c.JWT.KeysDir)
+	envString("JWT_SIGNING_KEY_ID", &c.JWT.SigningKeyID)
+	envString("JWT_ISSUER", &c.JWT.Issuer)
+	envString("JWT_AUDIENCE", &c.JWT.Audience)
+	set(envDuration("JWT_ACCESS_TTL", &c.JWT.AccessTTL))
+	set(envDuration("JWT_REFRESH_TTL", &c.JWT.RefreshTTL))
+
+	envString("MAIL_TRANSPORT", &c.Mail.Transport)
+	envString("MAIL_FROM", &c.Mail.From)
+	envString("SMTP_HOST", &c.Mail.SMTPHost)
+	set(envInt("SMTP_PORT", &c.Mail.SMTPPort))
+	envString("SMTP_USERNAME", &c.Mail.SMTPUsername)
+	envString("SMTP_PASSWORD", &c.Mail.SMTPPassword)
+	envString("MAIL_OUTBOX_DIR", &c.Mail.OutboxDir)
+
+	set(envDuration("TRASH_RETENTION", &c.Trash.Retention))
+
+	return err
+}
+
+func envString(key string, dst *string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = v
+	}
+}
+
+func envInt(key string, dst *int) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", key, err)
+	}
+	*dst = n
+	return nil
+}
+
+func envBool(key string, dst *bool) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", key, err)
+	}
+	*dst = b
+	return nil
+}
+
+func envDuration(key string, dst *Duration) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", key, err)
+	}
+	dst.Duration = d
+	return nil
+}
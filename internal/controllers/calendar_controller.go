@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/ical"
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"github.com/kadyrbayev2005/studysync/internal/services"
+	"gorm.io/gorm"
+)
+
+type CalendarController struct {
+	Repo         *repository.CalendarFeedRepository
+	DeadlineRepo repository.DeadlineStore
+	TaskRepo     repository.TaskStore
+	ReminderRepo *repository.ReminderRepository
+}
+
+func NewCalendarController(
+	repo *repository.CalendarFeedRepository,
+	deadlineRepo repository.DeadlineStore,
+	taskRepo repository.TaskStore,
+	reminderRepo *repository.ReminderRepository,
+) *CalendarController {
+	return &CalendarController{Repo: repo, DeadlineRepo: deadlineRepo, TaskRepo: taskRepo, ReminderRepo: reminderRepo}
+}
+
+// GetMyFeed godoc
+// @Summary      Get my calendar feed status
+// @Description  Tells whether a calendar feed URL is active. The URL itself is only shown when it is created.
+// @Tags         calendar
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Success      200 {object} map[string]interface{}
+// @Failure      401 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /me/calendar-feed [get]
+// @Security     BearerAuth
+func (c *CalendarController) GetMyFeed(ctx *gin.Context) {
+	feed, err := c.Repo.GetByUser(scopeFrom(ctx).UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch calendar feed"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"enabled": true, "created_at": feed.UpdatedAt})
+}
+
+// CreateMyFeed godoc
+// @Summary      Create or rotate my calendar feed URL
+// @Description  Issues a secret feed URL for Google Calendar, Apple Calendar and other iCalendar clients. Calling it again revokes the previous URL.
+// @Tags         calendar
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Success      201 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /me/calendar-feed [post]
+// @Security     BearerAuth
+func (c *CalendarController) CreateMyFeed(ctx *gin.Context) {
+	raw, hash, err := services.GenerateFeedToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create calendar feed"})
+		return
+	}
+
+	feed := models.CalendarFeed{UserID: scopeFrom(ctx).UserID, TokenHash: hash}
+	if err := c.Repo.Upsert(&feed); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create calendar feed"})
+		return
+	}
+
+	url := feedURL(ctx, raw)
+	ctx.JSON(http.StatusCreated, gin.H{
+		"url":        url,
+		"webcal_url": "webcal://" + strings.SplitN(url, "://", 2)[1],
+	})
+}
+
+// DeleteMyFeed godoc
+// @Summary      Revoke my calendar feed URL
+// @Tags         calendar
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Success      200 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /me/calendar-feed [delete]
+// @Security     BearerAuth
+func (c *CalendarController) DeleteMyFeed(ctx *gin.Context) {
+	if err := c.Repo.DeleteByUser(scopeFrom(ctx).UserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "calendar feed not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke calendar feed"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "calendar feed revoked"})
+}
+
+// GetFeed godoc
+// @Summary      iCalendar feed
+// @Description  The owner's deadlines as VEVENTs with VALARM reminders and tasks as VTODOs. Authenticated by the secret token in the URL.
+// @Tags         calendar
+// @Produce      text/calendar
+// @Param        token path string true "Feed token"
+// @Success      200 {string} string "text/calendar"
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /calendar/{token}/feed.ics [get]
+func (c *CalendarController) GetFeed(ctx *gin.Context) {
+	feed, err := c.Repo.GetByTokenHash(services.FeedTokenHash(ctx.Param("token")))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "calendar feed not found"})
+		return
+	}
+
+	now := time.Now()
+	scope := repository.Scope{UserID: feed.UserID}
+
+	deadlines, err := c.DeadlineRepo.GetDueAfter(scope, services.CalendarSince(now))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch deadlines"})
+		return
+	}
+	tasks, err := c.TaskRepo.GetOpenOrDueAfter(scope, services.CalendarSince(now))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch tasks"})
+		return
+	}
+
+	ids := make([]uint, 0, len(deadlines))
+	for _, d := range deadlines {
+		ids = append(ids, d.ID)
+	}
+	overrides, err := c.ReminderRepo.GetOverrides(ids)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch reminders"})
+		return
+	}
+	defaults, err := c.ReminderRepo.GetDefaults([]uint{feed.UserID})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch reminders"})
+		return
+	}
+
+	cal := services.BuildCalendar(deadlines, tasks, func(d models.Deadline) []int {
+		return services.EffectiveOffsets(overrides[d.ID], defaults[feed.UserID])
+	}, now)
+
+	var buf bytes.Buffer
+	if err := ical.Encode(&buf, cal); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render calendar"})
+		return
+	}
+
+	ctx.Header("Cache-Control", "private, max-age=300")
+	ctx.Data(http.StatusOK, "text/calendar; charset=utf-8", buf.Bytes())
+}
+
+func feedURL(ctx *gin.Context, token string) string {
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := ctx.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + ctx.Request.Host + "/calendar/" + token + "/feed.ics"
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -10,14 +11,15 @@ import (
 	"github.com/kadyrbayev2005/studysync/internal/models"
 	"github.com/kadyrbayev2005/studysync/internal/repository"
 	"github.com/kadyrbayev2005/studysync/internal/services"
+	"gorm.io/gorm"
 )
 
 type DeadlineController struct {
-	Repo     *repository.DeadlineRepository
-	TaskRepo *repository.TaskRepository
+	Repo     repository.DeadlineStore
+	TaskRepo repository.TaskStore
 }
 
-func NewDeadlineController(repo *repository.DeadlineRepository, taskRepo *repository.TaskRepository) *DeadlineController {
+func NewDeadlineController(repo repository.DeadlineStore, taskRepo repository.TaskStore) *DeadlineController {
 	return &DeadlineController{Repo: repo, TaskRepo: taskRepo}
 }
 
@@ -44,14 +46,16 @@ func (c *DeadlineController) CreateDeadline(ctx *gin.Context) {
 		return
 	}
 
-	// verify task exists
-	if _, err := c.TaskRepo.GetByID(payload.TaskID); err != nil {
+	// verify task exists and is visible to the caller
+	task, err := c.TaskRepo.GetByID(scopeFrom(ctx), payload.TaskID)
+	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "task not found"})
 		return
 	}
 
 	d := models.Deadline{
 		TaskID:    payload.TaskID,
+		UserID:    task.UserID,
 		DueDate:   payload.DueDate,
 		CreatedAt: time.Now(),
 	}
@@ -60,85 +64,139 @@ func (c *DeadlineController) CreateDeadline(ctx *gin.Context) {
 		return
 	}
 
-	services.RedisClient.Del(services.Ctx, "deadlines:all")
+	invalidateList("deadlines", d.UserID)
 
 	ctx.JSON(http.StatusCreated, d)
 }
 
 // GetAllDeadlines godoc
 // @Summary List deadlines
-// @Description Get all deadlines
+// @Description Get a page of the current user's deadlines (every user's for admins) with their task. Pages are numbered by default; send cursor (empty for the first page) to page with cursors instead: meta then carries next_cursor and prev_cursor, null at the ends of the list, to send back with the same filters and sort. meta.total is counted in page mode and, with total=true, in cursor mode.
 // @Tags deadlines
 // @Produce json
 // @Param Authorization header string true "Bearer token"
-// @Success 200 {array} models.Deadline
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 10, at most 100)"
+// @Param cursor query string false "Opaque cursor from meta.next_cursor or meta.prev_cursor; empty for the first page"
+// @Param total query bool false "Count the matching deadlines (default: true for pages, false for cursors)"
+// @Param sort query string false "Sort by id, due_date or created_at with optional 'desc' (default: id)"
+// @Param task_id query int false "Filter by task ID"
+// @Param due_after query string false "Due at or after this time (RFC3339)"
+// @Param due_before query string false "Due at or before this time (RFC3339)"
+// @Param created_after query string false "Created at or after this time (RFC3339)"
+// @Param created_before query string false "Created at or before this time (RFC3339)"
+// @Success 200 {object} map[string]interface{} "Paginated response: data + meta"
+// @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Router /deadlines [get]
 // @Security BearerAuth
 func (c *DeadlineController) GetAllDeadlines(ctx *gin.Context) {
-	cached, _ := services.RedisClient.Get(services.Ctx, "deadlines:all").Result()
-    if cached != "" {
-        ctx.Data(200, "application/json", []byte(cached))
-        return
-    }
+	scope := scopeFrom(ctx)
+	cacheKey := listCacheKey("deadlines", scope)
+
+	// Only the first page with no query at all is cached
+	plain := ctx.Request.URL.RawQuery == ""
+	if plain {
+		cached, _ := services.RedisClient.Get(services.Ctx, cacheKey).Result()
+		if cached != "" {
+			ctx.Data(200, "application/json", []byte(cached))
+			return
+		}
+	}
 
-	deadlines, err := c.Repo.GetAll()
+	q, ok := listQuery(ctx, repository.DeadlineList)
+	if !ok {
+		return
+	}
+	page, err := c.Repo.List(scope, q)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch deadlines"})
+		writeListError(ctx, err, "deadlines")
 		return
 	}
 
-	jsonData, _ := json.Marshal(deadlines)
-    services.RedisClient.Set(services.Ctx, "deadlines:all", jsonData, 30*time.Second)
-	
-	ctx.JSON(http.StatusOK, deadlines)
+	resp := listResponse(q, page)
+	if plain {
+		jsonData, _ := json.Marshal(resp)
+		services.RedisClient.Set(services.Ctx, cacheKey, jsonData, 30*time.Second)
+	}
+
+	ctx.JSON(http.StatusOK, resp)
 }
 
 // GetDeadlineByID godoc
 // @Summary Get deadline by ID
-// @Description Get a deadline by its ID
+// @Description Get a deadline by its ID. The response carries an ETag; send it back in If-None-Match to get 304 while the deadline is unchanged.
 // @Tags deadlines
 // @Produce json
 // @Param Authorization header string true "Bearer token"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Param id path int true "Deadline ID"
 // @Success 200 {object} models.Deadline
+// @Header 200 {string} ETag "Entity tag of the deadline"
+// @Success 304 "Not Modified"
 // @Failure 401 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /deadlines/{id} [get]
 // @Security BearerAuth
 func (c *DeadlineController) GetDeadlineByID(ctx *gin.Context) {
 	id, _ := strconv.Atoi(ctx.Param("id"))
-	d, err := c.Repo.GetByID(uint(id))
+	d, err := c.Repo.GetByID(scopeFrom(ctx), uint(id))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "deadline not found"})
 		return
 	}
 
-	services.RedisClient.Del(services.Ctx, "deadlines:all")
+	invalidateList("deadlines", d.UserID)
 
+	if !preconditions(ctx, deadlineETag(d)) {
+		return
+	}
 	ctx.JSON(http.StatusOK, d)
 }
 
 // DeleteDeadline godoc
 // @Summary Delete a deadline
-// @Description Delete a deadline by ID
+// @Description Moves a deadline to the trash, from where POST /trash/deadlines/{id}/restore brings it back. With If-Match the deadline is only deleted while it still has that ETag.
 // @Tags deadlines
 // @Produce json
 // @Param Authorization header string true "Bearer token"
+// @Param If-Match header string false "ETag the deadline must still have"
 // @Param id path int true "Deadline ID"
 // @Success 200 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "Concurrent update without If-Match"
+// @Failure 412 {object} map[string]string "The deadline no longer matches If-Match"
 // @Failure 500 {object} map[string]string
 // @Router /deadlines/{id} [delete]
 // @Security BearerAuth
 func (c *DeadlineController) DeleteDeadline(ctx *gin.Context) {
 	id, _ := strconv.Atoi(ctx.Param("id"))
-	if err := c.Repo.Delete(uint(id)); err != nil {
+
+	scope := scopeFrom(ctx)
+	d, err := c.Repo.GetByID(scope, uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "deadline not found"})
+		return
+	}
+	if !preconditions(ctx, deadlineETag(d)) {
+		return
+	}
+
+	if err := c.Repo.Delete(scope, d); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "deadline not found"})
+			return
+		}
+		if errors.Is(err, repository.ErrConflict) {
+			writeConflict(ctx)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete deadline"})
 		return
 	}
 
-	services.RedisClient.Del(services.Ctx, "deadlines:all")
+	invalidateList("deadlines", d.UserID)
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "deadline deleted"})
 }
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"github.com/kadyrbayev2005/studysync/internal/services"
+	"gorm.io/gorm"
+)
+
+type DependencyController struct {
+	Repo repository.DependencyStore
+}
+
+func NewDependencyController(repo repository.DependencyStore) *DependencyController {
+	return &DependencyController{Repo: repo}
+}
+
+// GetBlockers godoc
+// @Summary      List the tasks a task waits for
+// @Description  Returns the tasks that block the task, by deadline. The task cannot be done while any of them is open. Requires authentication.
+// @Tags         dependencies
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Task ID"
+// @Success      200 {array} models.Task
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /tasks/{id}/dependencies [get]
+// @Security     BearerAuth
+func (c *DependencyController) GetBlockers(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	tasks, err := c.Repo.Blockers(scopeFrom(ctx), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch dependencies"})
+		return
+	}
+	ctx.JSON(http.StatusOK, tasks)
+}
+
+// AddDependency godoc
+// @Summary      Make a task wait for another
+// @Description  Makes the task wait for the task in blocker_id, another task of the same user. Dependencies that would make a task wait for itself, directly or through other tasks, answer 409. Adding an existing dependency changes nothing. Requires authentication.
+// @Tags         dependencies
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Task ID"
+// @Param        data body models.DependencyRequest true "The task to wait for"
+// @Success      201 {object} models.TaskDependency
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string "The dependency would create a cycle"
+// @Failure      500 {object} map[string]string
+// @Router       /tasks/{id}/dependencies [post]
+// @Security     BearerAuth
+func (c *DependencyController) AddDependency(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	var req models.DependencyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dep, err := c.Repo.Add(scopeFrom(ctx), uint(id), req.BlockerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "task or blocker not found"})
+		case errors.Is(err, repository.ErrDependencyCycle):
+			ctx.JSON(http.StatusConflict, gin.H{"error": "the task would end up waiting for itself"})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add dependency"})
+		}
+		return
+	}
+	ctx.JSON(http.StatusCreated, dep)
+}
+
+// RemoveDependency godoc
+// @Summary      Stop a task waiting for another
+// @Description  Removes the dependency of the task on the blocker. Requires authentication.
+// @Tags         dependencies
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Task ID"
+// @Param        blocker_id path int true "Blocker task ID"
+// @Success      200 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /tasks/{id}/dependencies/{blocker_id} [delete]
+// @Security     BearerAuth
+func (c *DependencyController) RemoveDependency(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+	blockerID, _ := strconv.Atoi(ctx.Param("blocker_id"))
+
+	if err := c.Repo.Remove(scopeFrom(ctx), uint(id), uint(blockerID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "dependency not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove dependency"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// GetDependencyGraph godoc
+// @Summary      Dependency graph and critical path
+// @Description  Returns the tasks that wait for or block other tasks, the dependencies between them and the critical path. Open tasks are planned as early as possible from now, each taking its estimate_minutes once its open blockers are finished; a task whose earliest finish is after its deadline is late. The critical path is the chain leading to the task with the least slack, first blocker first. Requires authentication.
+// @Tags         dependencies
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Success      200 {object} models.DependencyGraph
+// @Failure      401 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /tasks/graph [get]
+// @Security     BearerAuth
+func (c *DependencyController) GetDependencyGraph(ctx *gin.Context) {
+	tasks, edges, err := c.Repo.Graph(scopeFrom(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch dependencies"})
+		return
+	}
+	ctx.JSON(http.StatusOK, services.DependencyGraph(tasks, edges, time.Now()))
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/models"
+)
+
+// etag builds a strong entity tag from the versions of the rows in a
+// representation: the resource's own row first, then the rows it embeds.
+func etag(versions ...uint) string {
+	parts := make([]string, len(versions))
+	for i, v := range versions {
+		parts[i] = strconv.FormatUint(uint64(v), 10)
+	}
+	return `"` + strings.Join(parts, ".") + `"`
+}
+
+func subjectETag(s models.Subject) string   { return etag(s.Version) }
+func deadlineETag(d models.Deadline) string { return etag(d.Version, d.Task.Version) }
+
+// taskETag also covers a task's progress, which changes with its subtasks
+// and checklist rather than its own row.
+func taskETag(t models.Task) string {
+	if t.Progress != nil {
+		return etag(t.Version, t.Subject.Version, uint(*t.Progress))
+	}
+	return etag(t.Version, t.Subject.Version)
+}
+
+// preconditions evaluates If-Match and If-None-Match (RFC 9110, section
+// 13) against the current ETag of a resource. It sets the ETag header and,
+// when a condition fails, answers 412, or 304 for reads. It reports
+// whether the request may go ahead.
+func preconditions(ctx *gin.Context, current string) bool {
+	ctx.Header("ETag", current)
+
+	if h := ctx.GetHeader("If-Match"); h != "" && !matchETag(h, current, false) {
+		ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": "resource has changed, fetch it again"})
+		return false
+	}
+	if h := ctx.GetHeader("If-None-Match"); h != "" && matchETag(h, current, true) {
+		if ctx.Request.Method == http.MethodGet || ctx.Request.Method == http.MethodHead {
+			ctx.Status(http.StatusNotModified)
+			return false
+		}
+		ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": "resource exists"})
+		return false
+	}
+	return true
+}
+
+// matchETag reports whether current is among the comma-separated entity
+// tags in header. If-Match compares strongly, so weak tags never match it;
+// If-None-Match compares weakly.
+func matchETag(header, current string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if rest, ok := strings.CutPrefix(tag, "W/"); ok {
+			if !weak {
+				continue
+			}
+			tag = rest
+		}
+		if tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+// writeConflict answers a write that lost a race with another one, after
+// its preconditions held: 412 when the client sent If-Match, 409 otherwise.
+func writeConflict(ctx *gin.Context) {
+	if ctx.GetHeader("If-Match") != "" {
+		ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": "resource has changed, fetch it again"})
+		return
+	}
+	ctx.JSON(http.StatusConflict, gin.H{"error": "resource was modified concurrently, retry"})
+}
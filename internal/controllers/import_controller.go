@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/ical"
+	"github.com/kadyrbayev2005/studysync/internal/services"
+)
+
+// maxImportSize caps uploaded calendars; a full semester is far smaller.
+const maxImportSize = 5 << 20
+
+type ImportController struct {
+	Importer *services.Importer
+}
+
+func NewImportController(importer *services.Importer) *ImportController {
+	return &ImportController{Importer: importer}
+}
+
+// ImportICS godoc
+// @Summary      Import an iCalendar file
+// @Description  Imports the VEVENTs and VTODOs of an .ics export (course schedule, LMS calendar) as tasks with deadlines. CATEGORIES, else the calendar name, else "Imported" selects the subject, which is created when missing. Entries are matched to earlier imports by UID, so re-importing updates changed entries and skips the rest. With dry_run=true nothing is saved and the response previews what would happen. Send the file as the multipart field "file" or as a text/calendar body.
+// @Tags         import
+// @Accept       mpfd
+// @Accept       text/calendar
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        file formData file false "iCalendar file"
+// @Param        dry_run query bool false "Preview without saving"
+// @Success      200 {object} models.ImportResult "dry run"
+// @Success      201 {object} models.ImportResult
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      413 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /import/ics [post]
+// @Security     BearerAuth
+func (c *ImportController) ImportICS(ctx *gin.Context) {
+	dryRun, err := strconv.ParseBool(ctx.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportSize)
+
+	var body io.Reader = ctx.Request.Body
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		fh, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "multipart field 'file' is required"})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to read uploaded file"})
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	cal, err := ical.Parse(body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "calendar file is too large"})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if cal.Name != "VCALENDAR" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "expected a VCALENDAR"})
+		return
+	}
+
+	userID := scopeFrom(ctx).UserID
+	result, err := c.Importer.ImportCalendar(userID, cal, dryRun)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import calendar"})
+		return
+	}
+
+	if dryRun {
+		ctx.JSON(http.StatusOK, result)
+		return
+	}
+
+	invalidateList("subjects", userID)
+	invalidateList("tasks", userID)
+	invalidateList("deadlines", userID)
+
+	ctx.JSON(http.StatusCreated, result)
+}
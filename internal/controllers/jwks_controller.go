@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/services"
+)
+
+// GetJWKS godoc
+// @Summary      JSON Web Key Set
+// @Description  Public keys for verifying StudySync access tokens. Pick the key whose kid matches the token header.
+// @Tags         auth
+// @Produce      json
+// @Success      200 {object} services.JWKS
+// @Router       /.well-known/jwks.json [get]
+func GetJWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, services.PublicJWKS())
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+)
+
+// listQuery reads the list query of a request to a list endpoint: page and
+// limit, or cursor (empty for the first page) to page with cursors, sort,
+// total, a parameter per field of spec and <range>_after and
+// <range>_before per range, in RFC3339. It answers 400 and returns false
+// when the request asks for something the list does not have.
+func listQuery(ctx *gin.Context, spec repository.ListSpec) (*repository.ListQuery, bool) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	q := &repository.ListQuery{
+		Page:  page,
+		Limit: limit,
+		Sort:  strings.TrimSpace(ctx.Query("sort")),
+	}
+	if q.Sort != "" && !spec.Sortable(q.Sort) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "cannot sort by " + q.Sort})
+		return nil, false
+	}
+
+	cursorStr, cursorMode := ctx.GetQuery("cursor")
+	q.Cursors = cursorMode
+	if cursorStr = strings.TrimSpace(cursorStr); cursorStr != "" {
+		var err error
+		if q.Cursor, err = spec.DecodeCursor(cursorStr, spec.SortOrDefault(q.Sort)); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return nil, false
+		}
+	}
+
+	// counting is what makes deep pages slow, so cursors skip it by default
+	withTotal := !cursorMode
+	if totalStr := strings.TrimSpace(ctx.Query("total")); totalStr != "" {
+		b, err := strconv.ParseBool(totalStr)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "total must be true or false"})
+			return nil, false
+		}
+		withTotal = b
+	}
+	q.SkipTotal = !withTotal
+
+	for name := range spec.Fields {
+		raw := strings.TrimSpace(ctx.Query(name))
+		if raw == "" {
+			continue
+		}
+		value, err := spec.Parse(name, raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+			return nil, false
+		}
+		if q.Filters == nil {
+			q.Filters = map[string]interface{}{}
+		}
+		q.Filters[name] = value
+	}
+
+	for name := range spec.Ranges {
+		after, ok := queryTime(ctx, name+"_after")
+		if !ok {
+			return nil, false
+		}
+		before, ok := queryTime(ctx, name+"_before")
+		if !ok {
+			return nil, false
+		}
+		if after == nil && before == nil {
+			continue
+		}
+		if q.Ranges == nil {
+			q.Ranges = map[string]repository.TimeRange{}
+		}
+		q.Ranges[name] = repository.TimeRange{After: after, Before: before}
+	}
+	return q, true
+}
+
+// listResponse is the data + meta envelope of a page read with q: page,
+// limit and pages for numbered pages, limit, next_cursor and prev_cursor
+// for cursors, and total unless q skipped it.
+func listResponse[T any](q *repository.ListQuery, page repository.Page[T]) gin.H {
+	meta := gin.H{"limit": q.Limit}
+	if q.Cursors {
+		meta["next_cursor"] = encodeCursor(page.Next)
+		meta["prev_cursor"] = encodeCursor(page.Prev)
+	} else {
+		meta["page"] = q.Page
+	}
+	if !q.SkipTotal {
+		meta["total"] = page.Total
+		if !q.Cursors {
+			meta["pages"] = (page.Total + int64(q.Limit) - 1) / int64(q.Limit)
+		}
+	}
+	return gin.H{"data": page.Items, "meta": meta}
+}
+
+// queryTime reads an optional RFC3339 time from the query string,
+// answering 400 when it is malformed.
+func queryTime(ctx *gin.Context, param string) (*time.Time, bool) {
+	raw := strings.TrimSpace(ctx.Query(param))
+	if raw == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": param + " must be RFC3339"})
+		return nil, false
+	}
+	return &t, true
+}
+
+func encodeCursor(c *repository.Cursor) *string {
+	if c == nil {
+		return nil
+	}
+	s := c.Encode()
+	return &s
+}
+
+// writeListError answers a failed read of a list of resource.
+func writeListError(ctx *gin.Context, err error, resource string) {
+	if errors.Is(err, repository.ErrInvalidQuery) || errors.Is(err, repository.ErrInvalidCursor) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch " + resource})
+}
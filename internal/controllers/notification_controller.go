@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+)
+
+type NotificationController struct {
+	Repo *repository.NotificationRepository
+}
+
+func NewNotificationController(repo *repository.NotificationRepository) *NotificationController {
+	return &NotificationController{Repo: repo}
+}
+
+// GetMyNotifications godoc
+// @Summary      List my notification history
+// @Description  Returns the reminders sent (or attempted) for the current user, newest first.
+// @Tags         notifications
+// @Produce      json
+// @Param        Authorization header string true  "Bearer token"
+// @Param        page          query  int    false "Page number (default: 1)"
+// @Param        limit         query  int    false "Items per page (default: 10)"
+// @Success      200 {object} map[string]interface{} "Paginated response: data + meta"
+// @Failure      401 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /me/notifications [get]
+// @Security     BearerAuth
+func (c *NotificationController) GetMyNotifications(ctx *gin.Context) {
+	c.list(ctx, scopeFrom(ctx).UserID)
+}
+
+// GetUserNotifications godoc
+// @Summary      List a user's notification history
+// @Description  Returns the reminders sent (or attempted) for any user (admin only).
+// @Tags         notifications
+// @Produce      json
+// @Param        Authorization header string true  "Bearer token"
+// @Param        id            path   int    true  "User ID"
+// @Param        page          query  int    false "Page number (default: 1)"
+// @Param        limit         query  int    false "Items per page (default: 10)"
+// @Success      200 {object} map[string]interface{} "Paginated response: data + meta"
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /users/{id}/notifications [get]
+// @Security     BearerAuth
+func (c *NotificationController) GetUserNotifications(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	c.list(ctx, uint(id))
+}
+
+func (c *NotificationController) list(ctx *gin.Context, userID uint) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+
+	notifications, total, err := c.Repo.GetByUser(userID, page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch notifications"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": notifications,
+		"meta": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
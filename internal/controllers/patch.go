@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kadyrbayev2005/studysync/internal/patch"
+)
+
+// validator is a patch DTO with checks beyond its binding tags.
+type validator interface {
+	Validate() error
+}
+
+// bindPatch applies the request body, a JSON Merge Patch or a JSON Patch
+// by Content-Type, to current (the resource's patch DTO) and decodes the
+// result into dst, rejecting fields the DTO does not have and validating
+// the rest. On failure it has already answered the request.
+func bindPatch(ctx *gin.Context, current, dst interface{}) bool {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		return false
+	}
+	doc, err := json.Marshal(current)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode resource"})
+		return false
+	}
+
+	patched, err := patch.Apply(ctx.ContentType(), doc, body)
+	switch {
+	case errors.Is(err, patch.ErrUnsupportedMediaType):
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return false
+	case errors.Is(err, patch.ErrTestFailed):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return false
+	case err != nil:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	if err := patch.Decode(patched, dst); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := binding.Validator.ValidateStruct(dst); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if v, ok := dst.(validator); ok {
+		if err := v.Validate(); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return false
+		}
+	}
+	return true
+}
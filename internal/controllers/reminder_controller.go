@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"github.com/kadyrbayev2005/studysync/internal/services"
+	"gorm.io/gorm"
+)
+
+type ReminderController struct {
+	Repo         *repository.ReminderRepository
+	DeadlineRepo repository.DeadlineStore
+}
+
+func NewReminderController(repo *repository.ReminderRepository, deadlineRepo repository.DeadlineStore) *ReminderController {
+	return &ReminderController{Repo: repo, DeadlineRepo: deadlineRepo}
+}
+
+// GetMyPreferences godoc
+// @Summary      Get my default reminder schedule
+// @Description  Returns the offsets (minutes before the due date) used for deadlines without their own schedule.
+// @Tags         reminders
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Success      200 {object} map[string]interface{}
+// @Failure      401 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /me/reminder-preferences [get]
+// @Security     BearerAuth
+func (c *ReminderController) GetMyPreferences(ctx *gin.Context) {
+	userID := scopeFrom(ctx).UserID
+	rs, err := c.Repo.GetUserDefaults(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch reminder preferences"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"reminders":                 rs,
+		"effective_offsets_minutes": services.EffectiveOffsets(nil, offsetsOf(rs)),
+	})
+}
+
+// UpdateMyPreferences godoc
+// @Summary      Replace my default reminder schedule
+// @Description  Replaces the default offsets. An empty list restores the system default (15 minutes).
+// @Tags         reminders
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        schedule body models.ReminderScheduleRequest true "Offsets in minutes before the due date"
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /me/reminder-preferences [put]
+// @Security     BearerAuth
+func (c *ReminderController) UpdateMyPreferences(ctx *gin.Context) {
+	var payload models.ReminderScheduleRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := scopeFrom(ctx).UserID
+	rs, err := c.Repo.ReplaceUserDefaults(userID, normalizeOffsets(payload.OffsetsMinutes))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update reminder preferences"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"reminders":                 rs,
+		"effective_offsets_minutes": services.EffectiveOffsets(nil, offsetsOf(rs)),
+	})
+}
+
+// GetDeadlineReminders godoc
+// @Summary      List reminders of a deadline
+// @Description  Returns the deadline's own reminder offsets and the schedule that will actually be used.
+// @Tags         reminders
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Deadline ID"
+// @Success      200 {object} map[string]interface{}
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /deadlines/{id}/reminders [get]
+// @Security     BearerAuth
+func (c *ReminderController) GetDeadlineReminders(ctx *gin.Context) {
+	d, ok := c.deadline(ctx)
+	if !ok {
+		return
+	}
+
+	c.respondDeadline(ctx, http.StatusOK, d)
+}
+
+// CreateDeadlineReminder godoc
+// @Summary      Add a reminder to a deadline
+// @Description  Adds one offset to the deadline's schedule. The deadline stops using the owner's defaults.
+// @Tags         reminders
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Deadline ID"
+// @Param        reminder body models.ReminderOffsetRequest true "Offset in minutes before the due date"
+// @Success      201 {object} models.ReminderOffset
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /deadlines/{id}/reminders [post]
+// @Security     BearerAuth
+func (c *ReminderController) CreateDeadlineReminder(ctx *gin.Context) {
+	d, ok := c.deadline(ctx)
+	if !ok {
+		return
+	}
+
+	var payload models.ReminderOffsetRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existing, err := c.Repo.GetForDeadline(d.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch reminders"})
+		return
+	}
+	if slices.Contains(offsetsOf(existing), payload.OffsetMinutes) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "reminder already exists"})
+		return
+	}
+	if len(existing) >= 10 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "a deadline can have at most 10 reminders"})
+		return
+	}
+
+	rem := models.ReminderOffset{
+		UserID:        d.UserID,
+		DeadlineID:    &d.ID,
+		OffsetMinutes: payload.OffsetMinutes,
+	}
+	if err := c.Repo.Create(&rem); errors.Is(err, repository.ErrReminderExists) {
+		// another request added the same offset since the check above
+		ctx.JSON(http.StatusConflict, gin.H{"error": "reminder already exists"})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create reminder"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, rem)
+}
+
+// ReplaceDeadlineReminders godoc
+// @Summary      Replace the reminders of a deadline
+// @Description  Replaces the deadline's schedule. An empty list makes it fall back to the owner's defaults.
+// @Tags         reminders
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Deadline ID"
+// @Param        schedule body models.ReminderScheduleRequest true "Offsets in minutes before the due date"
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /deadlines/{id}/reminders [put]
+// @Security     BearerAuth
+func (c *ReminderController) ReplaceDeadlineReminders(ctx *gin.Context) {
+	d, ok := c.deadline(ctx)
+	if !ok {
+		return
+	}
+
+	var payload models.ReminderScheduleRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := c.Repo.ReplaceForDeadline(d.ID, d.UserID, normalizeOffsets(payload.OffsetsMinutes)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update reminders"})
+		return
+	}
+
+	c.respondDeadline(ctx, http.StatusOK, d)
+}
+
+// DeleteDeadlineReminder godoc
+// @Summary      Remove a reminder from a deadline
+// @Description  Removes one offset from the deadline's schedule.
+// @Tags         reminders
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Deadline ID"
+// @Param        reminder_id path int true "Reminder ID"
+// @Success      200 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /deadlines/{id}/reminders/{reminder_id} [delete]
+// @Security     BearerAuth
+func (c *ReminderController) DeleteDeadlineReminder(ctx *gin.Context) {
+	d, ok := c.deadline(ctx)
+	if !ok {
+		return
+	}
+
+	reminderID, _ := strconv.Atoi(ctx.Param("reminder_id"))
+	if err := c.Repo.DeleteForDeadline(d.ID, uint(reminderID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "reminder not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete reminder"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "reminder deleted"})
+}
+
+// deadline loads the deadline in the :id path parameter, answering 404
+// when it does not exist or belongs to someone else.
+func (c *ReminderController) deadline(ctx *gin.Context) (models.Deadline, bool) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+	d, err := c.DeadlineRepo.GetByID(scopeFrom(ctx), uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "deadline not found"})
+		return d, false
+	}
+	return d, true
+}
+
+func (c *ReminderController) respondDeadline(ctx *gin.Context, code int, d models.Deadline) {
+	own, err := c.Repo.GetForDeadline(d.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch reminders"})
+		return
+	}
+	defaults, err := c.Repo.GetUserDefaults(d.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch reminders"})
+		return
+	}
+
+	ctx.JSON(code, gin.H{
+		"reminders":                 own,
+		"effective_offsets_minutes": services.EffectiveOffsets(offsetsOf(own), offsetsOf(defaults)),
+	})
+}
+
+func offsetsOf(rs []models.ReminderOffset) []int {
+	out := make([]int, 0, len(rs))
+	for _, r := range rs {
+		out = append(out, r.OffsetMinutes)
+	}
+	return out
+}
+
+// normalizeOffsets sorts offsets from earliest reminder to latest and drops duplicates.
+func normalizeOffsets(offsets []int) []int {
+	out := slices.Clone(offsets)
+	slices.Sort(out)
+	out = slices.Compact(out)
+	slices.Reverse(out)
+	return out
+}
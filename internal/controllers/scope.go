@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"github.com/kadyrbayev2005/studysync/internal/services"
+)
+
+// scopeFrom builds the repository scope for the user that AuthMiddleware
+// put into the context.
+func scopeFrom(ctx *gin.Context) repository.Scope {
+	userID, _ := ctx.Get("user_id")
+	role, _ := ctx.Get("user_role")
+
+	id, _ := userID.(uint)
+	r, _ := role.(string)
+	return repository.Scope{UserID: id, Admin: r == services.RoleAdmin}
+}
+
+// listCacheKey is the Redis key for a cached list. Admins share the
+// cross-tenant key, every other user gets a key of their own.
+func listCacheKey(resource string, scope repository.Scope) string {
+	if scope.Admin {
+		return resource + ":all"
+	}
+	return fmt.Sprintf("%s:user:%d", resource, scope.UserID)
+}
+
+// invalidateList drops the cached lists that may contain a row owned by ownerID.
+func invalidateList(resource string, ownerID uint) {
+	services.RedisClient.Del(services.Ctx,
+		resource+":all",
+		fmt.Sprintf("%s:user:%d", resource, ownerID),
+	)
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+)
+
+type SearchController struct {
+	Repo repository.SearchStore
+}
+
+func NewSearchController(repo repository.SearchStore) *SearchController {
+	return &SearchController{Repo: repo}
+}
+
+// Search godoc
+// @Summary      Search subjects, tasks and deadlines
+// @Description  Full-text search over the current user's subjects (name and description), tasks (title and description) and deadlines (by their task), or everyone's for admins. Words are stemmed as English, every word must match and matches the start of longer words too. Hits come best first; matches in titles and names rank above matches in descriptions. Each hit has a snippet with the matching words between <mark> and </mark>. Requires authentication.
+// @Tags         search
+// @Produce      json
+// @Param        Authorization header   string  true   "Bearer token"
+// @Param        q             query    string  true   "Search text"
+// @Param        types         query    string  false  "Comma-separated subset of subjects, tasks and deadlines (default: all)"
+// @Param        limit         query    int     false  "Maximum number of hits (default: 20, at most 100)"
+// @Success      200 {array} models.SearchHit
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /search [get]
+// @Security     BearerAuth
+func (c *SearchController) Search(ctx *gin.Context) {
+	q := strings.TrimSpace(ctx.Query("q"))
+	if q == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	types := queryList(ctx.Query("types"))
+	for _, t := range types {
+		switch t {
+		case models.ResourceSubjects, models.ResourceTasks, models.ResourceDeadlines:
+		default:
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "types must be subjects, tasks or deadlines"})
+			return
+		}
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+
+	hits, err := c.Repo.Search(scopeFrom(ctx), &repository.SearchFilter{Query: q, Types: types, Limit: limit})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "search failed"})
+		return
+	}
+	ctx.JSON(http.StatusOK, hits)
+}
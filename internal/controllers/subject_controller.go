@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -10,19 +11,20 @@ import (
 	"github.com/kadyrbayev2005/studysync/internal/models"
 	"github.com/kadyrbayev2005/studysync/internal/repository"
 	"github.com/kadyrbayev2005/studysync/internal/services"
+	"gorm.io/gorm"
 )
 
 type SubjectController struct {
-	Repo *repository.SubjectRepository
+	Repo repository.SubjectStore
 }
 
-func NewSubjectController(repo *repository.SubjectRepository) *SubjectController {
+func NewSubjectController(repo repository.SubjectStore) *SubjectController {
 	return &SubjectController{Repo: repo}
 }
 
 // CreateSubject godoc
 // @Summary Create a subject
-// @Description Create a new subject. Requires authentication.
+// @Description Create a new subject owned by the current user. An optional workflow replaces the default task statuses (todo, in-progress, blocked, done, cancelled) for its tasks: a list of states, each with a kind of todo, active, blocked, done or cancelled, and the transitions allowed between them. New tasks start in the first state. Requires authentication.
 // @Tags subjects
 // @Accept json
 // @Produce json
@@ -39,123 +41,226 @@ func (c *SubjectController) CreateSubject(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+
+	subject.ID = 0
+	subject.Version = 0
+	subject.UserID = scopeFrom(ctx).UserID
+	if subject.Workflow != nil {
+		if err := subject.Workflow.Validate(); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	if err := c.Repo.Create(&subject); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create subject"})
 		return
 	}
 
-	services.RedisClient.Del(services.Ctx, "subjects:all")
+	invalidateList("subjects", subject.UserID)
 
 	ctx.JSON(http.StatusCreated, subject)
 }
 
 // GetAllSubjects godoc
 // @Summary List subjects
-// @Description Get all subjects
+// @Description Get a page of the current user's subjects (every user's for admins). Pages are numbered by default; send cursor (empty for the first page) to page with cursors instead: meta then carries next_cursor and prev_cursor, null at the ends of the list, to send back with the same filters and sort. meta.total is counted in page mode and, with total=true, in cursor mode.
 // @Tags subjects
 // @Produce json
 // @Param Authorization header string true "Bearer token"
-// @Success 200 {array} models.Subject
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 10, at most 100)"
+// @Param cursor query string false "Opaque cursor from meta.next_cursor or meta.prev_cursor; empty for the first page"
+// @Param total query bool false "Count the matching subjects (default: true for pages, false for cursors)"
+// @Param sort query string false "Sort by id, name or created_at with optional 'desc' (default: id)"
+// @Param name query string false "Filter by name, ignoring case"
+// @Param created_after query string false "Created at or after this time (RFC3339)"
+// @Param created_before query string false "Created at or before this time (RFC3339)"
+// @Success 200 {object} map[string]interface{} "Paginated response: data + meta"
+// @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Router /subjects [get]
 // @Security BearerAuth
 func (c *SubjectController) GetAllSubjects(ctx *gin.Context) {
-	cached, _ := services.RedisClient.Get(services.Ctx, "subjects:all").Result()
-    if cached != "" {
-        ctx.Data(200, "application/json", []byte(cached))
-        return
-    }
+	scope := scopeFrom(ctx)
+	cacheKey := listCacheKey("subjects", scope)
 
-	subjects, err := c.Repo.GetAll()
+	// Only the first page with no query at all is cached
+	plain := ctx.Request.URL.RawQuery == ""
+	if plain {
+		cached, _ := services.RedisClient.Get(services.Ctx, cacheKey).Result()
+		if cached != "" {
+			ctx.Data(200, "application/json", []byte(cached))
+			return
+		}
+	}
+
+	q, ok := listQuery(ctx, repository.SubjectList)
+	if !ok {
+		return
+	}
+	page, err := c.Repo.List(scope, q)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch subjects"})
+		writeListError(ctx, err, "subjects")
 		return
 	}
 
-    jsonData, _ := json.Marshal(subjects)
-    services.RedisClient.Set(services.Ctx, "subjects:all", jsonData, 30*time.Second)
-	
-	ctx.JSON(http.StatusOK, subjects)
+	resp := listResponse(q, page)
+	if plain {
+		jsonData, _ := json.Marshal(resp)
+		services.RedisClient.Set(services.Ctx, cacheKey, jsonData, 30*time.Second)
+	}
+
+	ctx.JSON(http.StatusOK, resp)
 }
 
 // GetSubjectByID godoc
 // @Summary Get subject by ID
-// @Description Get a subject by its ID
+// @Description Get a subject by its ID. The response carries an ETag; send it back in If-None-Match to get 304 while the subject is unchanged.
 // @Tags subjects
 // @Produce json
 // @Param Authorization header string true "Bearer token"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Param id path int true "Subject ID"
 // @Success 200 {object} models.Subject
+// @Header 200 {string} ETag "Entity tag of the subject"
+// @Success 304 "Not Modified"
 // @Failure 401 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /subjects/{id} [get]
 // @Security BearerAuth
 func (c *SubjectController) GetSubjectByID(ctx *gin.Context) {
 	id, _ := strconv.Atoi(ctx.Param("id"))
-	subject, err := c.Repo.GetByID(uint(id))
+	subject, err := c.Repo.GetByID(scopeFrom(ctx), uint(id))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "subject not found"})
 		return
 	}
 
-	services.RedisClient.Del(services.Ctx, "subjects:all")
+	invalidateList("subjects", subject.UserID)
 
+	if !preconditions(ctx, subjectETag(subject)) {
+		return
+	}
 	ctx.JSON(http.StatusOK, subject)
 }
 
 // UpdateSubject godoc
 // @Summary Update a subject
-// @Description Update a subject by its ID
+// @Description Partially updates a subject. The body is a JSON Merge Patch (application/merge-patch+json, or plain application/json) or a JSON Patch (application/json-patch+json) over the writable fields shown in the payload model; any other field is rejected. Setting workflow to null brings back the default workflow; tasks whose status the new workflow lacks can move to any of its states. With If-Match the update only happens while the subject still has that ETag. Returns the updated subject.
 // @Tags subjects
 // @Accept json
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
 // @Produce json
 // @Param Authorization header string true "Bearer token"
+// @Param If-Match header string false "ETag the subject must still have"
 // @Param id path int true "Subject ID"
-// @Param subject body map[string]interface{} true "Update payload"
-// @Success 200 {object} map[string]string
+// @Param subject body models.SubjectPatch true "Merge patch of the writable subject fields, or a JSON Patch array"
+// @Success 200 {object} models.Subject
+// @Header 200 {string} ETag "Entity tag of the updated subject"
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "JSON Patch test operation failed, or a concurrent update without If-Match"
+// @Failure 412 {object} map[string]string "The subject no longer matches If-Match"
+// @Failure 415 {object} map[string]string
 // @Failure 500 {object} map[string]string
+// @Router /subjects/{id} [patch]
 // @Router /subjects/{id} [put]
 // @Security BearerAuth
 func (c *SubjectController) UpdateSubject(ctx *gin.Context) {
 	id, _ := strconv.Atoi(ctx.Param("id"))
-	var data map[string]interface{}
-	if err := ctx.ShouldBindJSON(&data); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+	scope := scopeFrom(ctx)
+	subject, err := c.Repo.GetByID(scope, uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "subject not found"})
+		return
+	}
+	if !preconditions(ctx, subjectETag(subject)) {
 		return
 	}
 
-	if err := c.Repo.Update(uint(id), data); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+	var p models.SubjectPatch
+	if !bindPatch(ctx, models.NewSubjectPatch(subject), &p) {
 		return
 	}
 
-	services.RedisClient.Del(services.Ctx, "subjects:all")
+	if data := p.Changes(subject); len(data) > 0 {
+		if err := c.Repo.Update(scope, subject, data); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "subject not found"})
+				return
+			}
+			if errors.Is(err, repository.ErrConflict) {
+				writeConflict(ctx)
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+			return
+		}
+
+		invalidateList("subjects", subject.UserID)
+		// tasks embed their subject
+		invalidateList("tasks", subject.UserID)
+	}
+
+	updated, err := c.Repo.GetByID(scope, subject.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch subject"})
+		return
+	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "subject updated"})
+	ctx.Header("ETag", subjectETag(updated))
+	ctx.JSON(http.StatusOK, updated)
 }
 
 // DeleteSubject godoc
 // @Summary Delete a subject
-// @Description Delete a subject by ID
+// @Description Moves a subject to the trash together with its tasks and their deadlines; POST /trash/subjects/{id}/restore brings them all back. With If-Match the subject is only deleted while it still has that ETag.
 // @Tags subjects
 // @Produce json
 // @Param Authorization header string true "Bearer token"
+// @Param If-Match header string false "ETag the subject must still have"
 // @Param id path int true "Subject ID"
 // @Success 200 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "Concurrent update without If-Match"
+// @Failure 412 {object} map[string]string "The subject no longer matches If-Match"
 // @Failure 500 {object} map[string]string
 // @Router /subjects/{id} [delete]
 // @Security BearerAuth
 func (c *SubjectController) DeleteSubject(ctx *gin.Context) {
 	id, _ := strconv.Atoi(ctx.Param("id"))
-	if err := c.Repo.Delete(uint(id)); err != nil {
+
+	scope := scopeFrom(ctx)
+	subject, err := c.Repo.GetByID(scope, uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "subject not found"})
+		return
+	}
+	if !preconditions(ctx, subjectETag(subject)) {
+		return
+	}
+
+	if err := c.Repo.Delete(scope, subject); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "subject not found"})
+			return
+		}
+		if errors.Is(err, repository.ErrConflict) {
+			writeConflict(ctx)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
 	}
 
-	services.RedisClient.Del(services.Ctx, "subjects:all")
+	invalidateList("subjects", subject.UserID)
+	invalidateList("tasks", subject.UserID)
+	invalidateList("deadlines", subject.UserID)
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "subject deleted"})
 }
@@ -0,0 +1,325 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"github.com/kadyrbayev2005/studysync/internal/services"
+	"gorm.io/gorm"
+)
+
+type SubtaskController struct {
+	Repo        repository.SubtaskStore
+	TaskRepo    repository.TaskStore
+	SubjectRepo repository.SubjectStore
+}
+
+func NewSubtaskController(repo repository.SubtaskStore, taskRepo repository.TaskStore, subjectRepo repository.SubjectStore) *SubtaskController {
+	return &SubtaskController{Repo: repo, TaskRepo: taskRepo, SubjectRepo: subjectRepo}
+}
+
+// withProgress fills in the progress of the tasks that have subtasks or
+// checklist items.
+func withProgress(repo repository.SubtaskStore, scope repository.Scope, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	subtasks, items, err := repo.Descendants(scope, ids)
+	if err != nil {
+		return err
+	}
+	services.FillProgress(tasks, subtasks, items)
+	return nil
+}
+
+// taskWithProgress reads a task with its progress, the way GET /tasks/{id}
+// shows it.
+func taskWithProgress(repo repository.TaskStore, subtaskRepo repository.SubtaskStore, scope repository.Scope, id uint) (models.Task, error) {
+	task, err := repo.GetByID(scope, id)
+	if err != nil {
+		return task, err
+	}
+	tasks := []models.Task{task}
+	err = withProgress(subtaskRepo, scope, tasks)
+	return tasks[0], err
+}
+
+// GetSubtasks godoc
+// @Summary      List the subtasks of a task
+// @Description  Returns the direct subtasks of the task, with their own progress. Requires authentication.
+// @Tags         subtasks
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Task ID"
+// @Success      200 {array} models.Task
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /tasks/{id}/subtasks [get]
+// @Security     BearerAuth
+func (c *SubtaskController) GetSubtasks(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	scope := scopeFrom(ctx)
+	tasks, err := c.Repo.Subtasks(scope, uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch subtasks"})
+		return
+	}
+	if err := withProgress(c.Repo, scope, tasks); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch subtasks"})
+		return
+	}
+	ctx.JSON(http.StatusOK, tasks)
+}
+
+// CreateSubtask godoc
+// @Summary      Create a subtask
+// @Description  Creates a task under the task, owned by the same user. The subject defaults to the parent's; the status follows the subject's workflow as for POST /tasks. Subtasks can have subtasks of their own but cannot recur. They are changed and deleted like any task; deleting or restoring a task takes its subtasks with it. Requires authentication.
+// @Tags         subtasks
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Parent task ID"
+// @Param        task body models.Task true "Subtask payload"
+// @Success      201 {object} models.Task
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /tasks/{id}/subtasks [post]
+// @Security     BearerAuth
+func (c *SubtaskController) CreateSubtask(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	var task models.Task
+	if err := ctx.ShouldBindJSON(&task); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scope := scopeFrom(ctx)
+	parent, err := c.TaskRepo.GetByID(scope, uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	if task.RecurrenceRule != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "subtasks cannot recur"})
+		return
+	}
+	if task.EstimateMinutes < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "estimate_minutes cannot be negative"})
+		return
+	}
+
+	task.ID = 0
+	task.UserID = parent.UserID
+	task.ParentID = &parent.ID
+	task.Progress = nil
+	task.Tags = nil
+	task.SeriesID = nil
+	task.OccurrenceAt = nil
+	task.RecurrenceGeneratedUntil = nil
+	task.ExternalUID = nil
+	task.Version = 0
+
+	subject := parent.Subject
+	if task.SubjectID != 0 && task.SubjectID != parent.SubjectID {
+		subject, err = c.SubjectRepo.GetByID(scope, task.SubjectID)
+		if err != nil || subject.UserID != parent.UserID {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "subject not found"})
+			return
+		}
+	}
+	task.SubjectID = subject.ID
+	task.Subject = subject
+	if err := services.StartStatus(subject.TaskWorkflow(), &task, time.Now()); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.TaskRepo.Create(&task); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create subtask"})
+		return
+	}
+
+	invalidateList("tasks", task.UserID)
+
+	ctx.JSON(http.StatusCreated, task)
+}
+
+// GetChecklist godoc
+// @Summary      List the checklist of a task
+// @Description  Returns the checklist items of the task by position, then in the order they were added. Requires authentication.
+// @Tags         subtasks
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Task ID"
+// @Success      200 {array} models.ChecklistItem
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /tasks/{id}/checklist [get]
+// @Security     BearerAuth
+func (c *SubtaskController) GetChecklist(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	items, err := c.Repo.Checklist(scopeFrom(ctx), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch checklist"})
+		return
+	}
+	ctx.JSON(http.StatusOK, items)
+}
+
+// AddChecklistItem godoc
+// @Summary      Add a checklist item
+// @Description  Adds an item to the checklist of the task. Items and subtasks count towards the task's progress. Requires authentication.
+// @Tags         subtasks
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Task ID"
+// @Param        item body models.ChecklistItemPatch true "Checklist item"
+// @Success      201 {object} models.ChecklistItem
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /tasks/{id}/checklist [post]
+// @Security     BearerAuth
+func (c *SubtaskController) AddChecklistItem(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	var req models.ChecklistItemPatch
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item := models.ChecklistItem{TaskID: uint(id), Title: req.Title, Done: req.Done, Position: req.Position}
+	if err := c.Repo.AddItem(scopeFrom(ctx), &item); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add checklist item"})
+		return
+	}
+
+	invalidateList("tasks", item.UserID)
+
+	ctx.JSON(http.StatusCreated, item)
+}
+
+// UpdateChecklistItem godoc
+// @Summary      Update a checklist item
+// @Description  Partially updates a checklist item with a JSON Merge Patch or a JSON Patch, as for PATCH /tasks/{id}: tick it off with {"done": true}, rename it or move it to another position. Returns the updated item. Requires authentication.
+// @Tags         subtasks
+// @Accept       json
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Task ID"
+// @Param        item_id path int true "Checklist item ID"
+// @Param        data body models.ChecklistItemPatch true "Merge patch of the item, or a JSON Patch array"
+// @Success      200 {object} models.ChecklistItem
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string "JSON Patch test operation failed"
+// @Failure      415 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /tasks/{id}/checklist/{item_id} [patch]
+// @Router       /tasks/{id}/checklist/{item_id} [put]
+// @Security     BearerAuth
+func (c *SubtaskController) UpdateChecklistItem(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+	itemID, _ := strconv.Atoi(ctx.Param("item_id"))
+
+	scope := scopeFrom(ctx)
+	item, err := c.Repo.GetItem(scope, uint(id), uint(itemID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "checklist item not found"})
+		return
+	}
+
+	var p models.ChecklistItemPatch
+	if !bindPatch(ctx, models.NewChecklistItemPatch(item), &p) {
+		return
+	}
+	if data := p.Changes(item); len(data) > 0 {
+		if err := c.Repo.UpdateItem(scope, item, data); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "checklist item not found"})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update checklist item"})
+			return
+		}
+		invalidateList("tasks", item.UserID)
+	}
+
+	updated, err := c.Repo.GetItem(scope, item.TaskID, item.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch checklist item"})
+		return
+	}
+	ctx.JSON(http.StatusOK, updated)
+}
+
+// DeleteChecklistItem godoc
+// @Summary      Delete a checklist item
+// @Description  Removes an item from the checklist of the task. Requires authentication.
+// @Tags         subtasks
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Task ID"
+// @Param        item_id path int true "Checklist item ID"
+// @Success      200 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /tasks/{id}/checklist/{item_id} [delete]
+// @Security     BearerAuth
+func (c *SubtaskController) DeleteChecklistItem(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+	itemID, _ := strconv.Atoi(ctx.Param("item_id"))
+
+	scope := scopeFrom(ctx)
+	item, err := c.Repo.GetItem(scope, uint(id), uint(itemID))
+	if err == nil {
+		err = c.Repo.DeleteItem(scope, item)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "checklist item not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete checklist item"})
+		return
+	}
+
+	invalidateList("tasks", item.UserID)
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
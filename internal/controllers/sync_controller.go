@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/services"
+)
+
+// maxSyncChanges caps the change set of one sync; clients with more
+// pending edits send them over several requests.
+const maxSyncChanges = 500
+
+type SyncController struct {
+	Syncer *services.Syncer
+}
+
+func NewSyncController(syncer *services.Syncer) *SyncController {
+	return &SyncController{Syncer: syncer}
+}
+
+// Sync godoc
+// @Summary      Sync offline changes
+// @Description  Applies the client's offline changes to subjects, tasks and deadlines and returns everything that changed on the server since the given token, with tombstones for deleted rows, and a token for the next sync. Without a token the response holds all of the user's rows and full is true. Updates are merged per field: a field only the client changed takes its value, and a field both sides changed goes to the later change by changed_at and is listed in conflicts. Creates carry a client_id, which later changes in the same request may use as subject_id or task_id; the ids given to new rows are listed in created. Changes that cannot be applied are listed in rejected and do not stop the others. A token older than the retained change log answers 410; sync again without one.
+// @Tags         sync
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        input body models.SyncRequest true "Token and offline changes"
+// @Success      200 {object} models.SyncResponse
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      410 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /sync [post]
+// @Security     BearerAuth
+func (c *SyncController) Sync(ctx *gin.Context) {
+	var req models.SyncRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Changes) > maxSyncChanges {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d changes can be sent at once", maxSyncChanges)})
+		return
+	}
+
+	userID := scopeFrom(ctx).UserID
+	resp, err := c.Syncer.Sync(userID, req)
+	switch {
+	case errors.Is(err, services.ErrSyncTokenInvalid):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrSyncTokenExpired):
+		ctx.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to sync"})
+		return
+	}
+
+	if len(req.Changes) > 0 {
+		invalidateList("subjects", userID)
+		invalidateList("tasks", userID)
+		invalidateList("deadlines", userID)
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
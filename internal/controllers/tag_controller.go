@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+type TagController struct {
+	Repo        repository.TagStore
+	TaskRepo    repository.TaskStore
+	SubtaskRepo repository.SubtaskStore
+}
+
+func NewTagController(repo repository.TagStore, taskRepo repository.TaskStore, subtaskRepo repository.SubtaskStore) *TagController {
+	return &TagController{Repo: repo, TaskRepo: taskRepo, SubtaskRepo: subtaskRepo}
+}
+
+// writeTagError answers a failed tag write.
+func writeTagError(ctx *gin.Context, err error, failed string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+	case errors.Is(err, repository.ErrTagExists):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "a tag with that name already exists"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": failed})
+	}
+}
+
+// GetAllTags godoc
+// @Summary      List tags
+// @Description  Returns the current user's tags (every user's for admins) by name. Requires authentication.
+// @Tags         tags
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Success      200 {array} models.Tag
+// @Failure      401 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /tags [get]
+// @Security     BearerAuth
+func (c *TagController) GetAllTags(ctx *gin.Context) {
+	tags, err := c.Repo.GetAll(scopeFrom(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch tags"})
+		return
+	}
+	ctx.JSON(http.StatusOK, tags)
+}
+
+// CreateTag godoc
+// @Summary      Create a tag
+// @Description  Creates a tag owned by the current user. Names are unique per user, ignoring case, and cannot contain commas; the colour is a hex colour and defaults to grey. Requires authentication.
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        tag body models.TagPatch true "Tag payload"
+// @Success      201 {object} models.Tag
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      409 {object} map[string]string "A tag with that name already exists"
+// @Failure      500 {object} map[string]string
+// @Router       /tags [post]
+// @Security     BearerAuth
+func (c *TagController) CreateTag(ctx *gin.Context) {
+	var tag models.Tag
+	if err := ctx.ShouldBindJSON(&tag); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag.ID = 0
+	tag.UserID = scopeFrom(ctx).UserID
+	if tag.Color == "" {
+		tag.Color = models.DefaultTagColor
+	}
+
+	if err := c.Repo.Create(&tag); err != nil {
+		writeTagError(ctx, err, "failed to create tag")
+		return
+	}
+	ctx.JSON(http.StatusCreated, tag)
+}
+
+// GetTagByID godoc
+// @Summary      Get a tag by ID
+// @Description  Retrieves a tag by its ID. Requires authentication.
+// @Tags         tags
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Tag ID"
+// @Success      200 {object} models.Tag
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Router       /tags/{id} [get]
+// @Security     BearerAuth
+func (c *TagController) GetTagByID(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+	tag, err := c.Repo.GetByID(scopeFrom(ctx), uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	}
+	ctx.JSON(http.StatusOK, tag)
+}
+
+// UpdateTag godoc
+// @Summary      Update a tag
+// @Description  Renames or recolours a tag with a JSON Merge Patch or a JSON Patch, as for PATCH /tasks/{id}. The tasks that carry the tag get a new ETag. Returns the updated tag. Requires authentication.
+// @Tags         tags
+// @Accept       json
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Tag ID"
+// @Param        data body models.TagPatch true "Merge patch of the tag, or a JSON Patch array"
+// @Success      200 {object} models.Tag
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string "A tag with that name already exists, or a JSON Patch test operation failed"
+// @Failure      415 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /tags/{id} [patch]
+// @Router       /tags/{id} [put]
+// @Security     BearerAuth
+func (c *TagController) UpdateTag(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	scope := scopeFrom(ctx)
+	tag, err := c.Repo.GetByID(scope, uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	}
+
+	var p models.TagPatch
+	if !bindPatch(ctx, models.NewTagPatch(tag), &p) {
+		return
+	}
+	if data := p.Changes(tag); len(data) > 0 {
+		if err := c.Repo.Update(scope, tag.ID, data); err != nil {
+			writeTagError(ctx, err, "failed to update tag")
+			return
+		}
+		invalidateList("tasks", tag.UserID)
+	}
+
+	updated, err := c.Repo.GetByID(scope, tag.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch tag"})
+		return
+	}
+	ctx.JSON(http.StatusOK, updated)
+}
+
+// DeleteTag godoc
+// @Summary      Delete a tag
+// @Description  Deletes a tag and takes it off every task that carries it. Requires authentication.
+// @Tags         tags
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Tag ID"
+// @Success      200 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /tags/{id} [delete]
+// @Security     BearerAuth
+func (c *TagController) DeleteTag(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	scope := scopeFrom(ctx)
+	tag, err := c.Repo.GetByID(scope, uint(id))
+	if err == nil {
+		err = c.Repo.Delete(scope, tag.ID)
+	}
+	if err != nil {
+		writeTagError(ctx, err, "delete failed")
+		return
+	}
+
+	invalidateList("tasks", tag.UserID)
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// TagTask godoc
+// @Summary      Tag a task
+// @Description  Adds tags of the task's owner to the task; tags it already has are left alone. With If-Match the task is only tagged while it still has that ETag. Returns the updated task. Requires authentication.
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        If-Match header string false "ETag the task must still have"
+// @Param        id path int true "Task ID"
+// @Param        data body models.TaskTagsRequest true "Tags to add"
+// @Success      200 {object} models.Task
+// @Header       200 {string} ETag "Entity tag of the updated task"
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string "Task or tag not found"
+// @Failure      409 {object} map[string]string "Concurrent update without If-Match"
+// @Failure      412 {object} map[string]string "The task no longer matches If-Match"
+// @Failure      500 {object} map[string]string
+// @Router       /tasks/{id}/tags [post]
+// @Security     BearerAuth
+func (c *TagController) TagTask(ctx *gin.Context) {
+	c.retag(ctx, c.Repo.Tag)
+}
+
+// UntagTask godoc
+// @Summary      Untag a task
+// @Description  Takes tags off the task; tags it does not have are ignored. With If-Match the task is only untagged while it still has that ETag. Returns the updated task. Requires authentication.
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        If-Match header string false "ETag the task must still have"
+// @Param        id path int true "Task ID"
+// @Param        data body models.TaskTagsRequest true "Tags to take off"
+// @Success      200 {object} models.Task
+// @Header       200 {string} ETag "Entity tag of the updated task"
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string "Task or tag not found"
+// @Failure      409 {object} map[string]string "Concurrent update without If-Match"
+// @Failure      412 {object} map[string]string "The task no longer matches If-Match"
+// @Failure      500 {object} map[string]string
+// @Router       /tasks/{id}/tags [delete]
+// @Security     BearerAuth
+func (c *TagController) UntagTask(ctx *gin.Context) {
+	c.retag(ctx, c.Repo.Untag)
+}
+
+// retag runs a bulk tag or untag of the task in the request.
+func (c *TagController) retag(ctx *gin.Context, write func(repository.Scope, models.Task, []uint) error) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	var req models.TaskTagsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scope := scopeFrom(ctx)
+	task, err := taskWithProgress(c.TaskRepo, c.SubtaskRepo, scope, uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	if !preconditions(ctx, taskETag(task)) {
+		return
+	}
+
+	if err := write(scope, task, req.TagIDs); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "task or tag not found"})
+		case errors.Is(err, repository.ErrConflict):
+			writeConflict(ctx)
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update task tags"})
+		}
+		return
+	}
+
+	invalidateList("tasks", task.UserID)
+
+	updated, err := taskWithProgress(c.TaskRepo, c.SubtaskRepo, scope, task.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch task"})
+		return
+	}
+	ctx.Header("ETag", taskETag(updated))
+	ctx.JSON(http.StatusOK, updated)
+}
@@ -2,29 +2,41 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/recurrence"
 	"github.com/kadyrbayev2005/studysync/internal/repository"
 	"github.com/kadyrbayev2005/studysync/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type TaskController struct {
-	Repo *repository.TaskRepository
+	Repo        repository.TaskStore
+	SubjectRepo repository.SubjectStore
+	DepRepo     repository.DependencyStore
+	SubtaskRepo repository.SubtaskStore
 }
 
-func NewTaskController(repo *repository.TaskRepository) *TaskController {
-	return &TaskController{Repo: repo}
+func NewTaskController(repo repository.TaskStore, subjectRepo repository.SubjectStore, depRepo repository.DependencyStore, subtaskRepo repository.SubtaskStore) *TaskController {
+	return &TaskController{Repo: repo, SubjectRepo: subjectRepo, DepRepo: depRepo, SubtaskRepo: subtaskRepo}
+}
+
+// getTask reads a task with its progress.
+func (c *TaskController) getTask(scope repository.Scope, id uint) (models.Task, error) {
+	return taskWithProgress(c.Repo, c.SubtaskRepo, scope, id)
 }
 
 // CreateTask godoc
 // @Summary      Create a new task
-// @Description  Creates a new task. Requires authentication.
+// @Description  Creates a new top-level task owned by the current user; subtasks are created with POST /tasks/{id}/subtasks. With a recurrence_rule (RRULE subset: FREQ, INTERVAL, BYDAY, COUNT, UNTIL) the task starts a series: its deadline is the series start and later occurrences are generated with their deadlines. The status must be one of the subject's workflow and defaults to its first; see GET /tasks/{id}/transitions. Requires authentication.
 // @Tags         tasks
 // @Accept       json
 // @Produce      json
@@ -42,31 +54,82 @@ func (c *TaskController) CreateTask(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := c.Repo.Create(&task); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create task"})
+
+	scope := scopeFrom(ctx)
+	task.ID = 0
+	task.UserID = scope.UserID
+	task.ParentID = nil
+	task.Progress = nil
+	task.Tags = nil
+	task.SeriesID = nil
+	task.OccurrenceAt = nil
+	task.RecurrenceGeneratedUntil = nil
+	task.Version = 0
+
+	if task.EstimateMinutes < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "estimate_minutes cannot be negative"})
 		return
 	}
 
-	services.RedisClient.Del(services.Ctx, "tasks:all")
+	var subject models.Subject
+	if task.SubjectID != 0 {
+		var err error
+		if subject, err = c.SubjectRepo.GetByID(scope, task.SubjectID); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "subject not found"})
+			return
+		}
+	}
+	task.Subject = subject
+	if err := services.StartStatus(subject.TaskWorkflow(), &task, time.Now()); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if task.RecurrenceRule == "" {
+		if err := c.Repo.Create(&task); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create task"})
+			return
+		}
+	} else {
+		if err := services.StartSeries(c.Repo, &task, time.Now()); err != nil {
+			if services.IsSeriesError(err) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create task"})
+			return
+		}
+		invalidateList("deadlines", task.UserID)
+	}
+
+	invalidateList("tasks", task.UserID)
 
 	ctx.JSON(http.StatusCreated, task)
 }
 
 // GetAllTasks godoc
 // @Summary      List tasks with pagination, filtering and sorting
-// @Description  Returns a paginated list of tasks with optional filters: status, subject_id, search, date range and sorting.
+// @Description  Returns a paginated list of the current user's tasks (every user's for admins) with optional filters: status, subject_id, search, date range, top-level tasks only, tags (matched by name, ignoring case), a filter expression that combines conditions with and and or, and sorting. Tasks with subtasks or checklist items report their progress in percent. Pages are numbered by default; send cursor (empty for the first page) to page with cursors instead, which do not shift while tasks are added or removed: meta then carries next_cursor and prev_cursor, null at the ends of the list, to send back with the same filters and sort. Cursor pages of a search are ordered by sort rather than by rank. meta.total is counted in page mode and, with total=true, in cursor mode.
 // @Tags         tasks
 // @Produce      json
 // @Param        Authorization   header   string  true   "Bearer token"
 // @Param        page            query    int     false  "Page number (default: 1)"
+// @Param        cursor          query    string  false  "Opaque cursor from meta.next_cursor or meta.prev_cursor; empty for the first page"
+// @Param        total           query    bool    false  "Count the matching tasks (default: true for pages, false for cursors)"
 // @Param        limit           query    int     false  "Items per page (default: 10)"
-// @Param        status          query    string  false  "Filter by status (todo | in-progress | done)"
+// @Param        status          query    string  false  "Filter by status, e.g. todo, in-progress, blocked, done or cancelled"
 // @Param        subject_id      query    int     false  "Filter by subject ID"
-// @Param        search          query    string  false  "Search text in title or description"
+// @Param        search          query    string  false  "Full-text search in title and description; see GET /search. Without sort the best matches come first"
 // @Param        sort            query    string  false  "Sort by field (created_at, deadline, title) with optional 'desc'. Example: 'deadline desc'"
 // @Param        deadline_before query    string  false  "Return tasks with deadline before this timestamp (RFC3339 format)"
 // @Param        deadline_after  query    string  false  "Return tasks with deadline after this timestamp (RFC3339 format)"
+// @Param        top_level       query    bool    false  "Leave out subtasks"
+// @Param        tags_any        query    string  false  "Comma-separated tag names; return tasks with at least one of them"
+// @Param        tags_all        query    string  false  "Comma-separated tag names; return tasks with all of them"
+// @Param        tags_none       query    string  false  "Comma-separated tag names; return tasks with none of them"
+// @Param        filter          query    string  false  "Filter expression in RSQL, with ; and + sent as they are or escaped, e.g. deadline=le=now+7d;status!=done;(subject==Math,tag==exam): ';' is and, ',' is or, parentheses group. Operators ==, !=, =lt=, =le=, =gt=, =ge=, =in=, =out=. Fields title, description, status, subject, subject_id, tag, parent_id, estimate_minutes, deadline, created_at, started_at, completed_at. Text ignores case and * matches anything; times are RFC3339, a date, or now or today with an offset like +7d; null matches a missing parent_id, started_at or completed_at"
 // @Success      200 {object} map[string]interface{} "Paginated response: data + meta"
+// @Failure      400 {object} map[string]string
 // @Failure      401 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Router       /tasks [get]
@@ -81,6 +144,21 @@ func (c *TaskController) GetAllTasks(ctx *gin.Context) {
 	sort := strings.TrimSpace(ctx.Query("sort"))
 	deadlineBeforeStr := strings.TrimSpace(ctx.Query("deadline_before"))
 	deadlineAfterStr := strings.TrimSpace(ctx.Query("deadline_after"))
+	topLevelStr := strings.TrimSpace(ctx.Query("top_level"))
+	tagsAny := queryList(ctx.Query("tags_any"))
+	tagsAll := queryList(ctx.Query("tags_all"))
+	tagsNone := queryList(ctx.Query("tags_none"))
+	cursorStr, cursorMode := ctx.GetQuery("cursor")
+	totalStr := strings.TrimSpace(ctx.Query("total"))
+	whereStr, err := rawQuery(ctx, "filter")
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": "invalid filter"})
+		return
+	}
+	whereStr = strings.TrimSpace(whereStr)
+
+	scope := scopeFrom(ctx)
+	cacheKey := listCacheKey("tasks", scope)
 
 	// Detect if request has ANY filters
 	hasFilters := status != "" ||
@@ -89,12 +167,19 @@ func (c *TaskController) GetAllTasks(ctx *gin.Context) {
 		sort != "" ||
 		deadlineBeforeStr != "" ||
 		deadlineAfterStr != "" ||
+		topLevelStr != "" ||
+		len(tagsAny) > 0 ||
+		len(tagsAll) > 0 ||
+		len(tagsNone) > 0 ||
+		whereStr != "" ||
+		cursorMode ||
+		totalStr != "" ||
 		page != 1 ||
 		limit != 10
 
 	// Only cache if no filters at all
 	if !hasFilters {
-		cached, _ := services.RedisClient.Get(services.Ctx, "tasks:all").Result()
+		cached, _ := services.RedisClient.Get(services.Ctx, cacheKey).Result()
 		if cached != "" {
 			ctx.Data(200, "application/json", []byte(cached))
 			return
@@ -133,6 +218,24 @@ func (c *TaskController) GetAllTasks(ctx *gin.Context) {
 		deadlineAfter = &t
 	}
 
+	var topLevel bool
+	if topLevelStr != "" {
+		b, err := strconv.ParseBool(topLevelStr)
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": "top_level must be true or false"})
+			return
+		}
+		topLevel = b
+	}
+
+	var where *repository.Where
+	if whereStr != "" {
+		if where, err = repository.ParseTaskWhere(whereStr, time.Now()); err != nil {
+			ctx.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	filter := &repository.TaskFilter{
 		Page:           page,
 		Limit:          limit,
@@ -142,110 +245,423 @@ func (c *TaskController) GetAllTasks(ctx *gin.Context) {
 		Sort:           sort,
 		DeadlineBefore: deadlineBefore,
 		DeadlineAfter:  deadlineAfter,
+		TopLevel:       topLevel,
+		Where:          where,
+		TagsAny:        tagsAny,
+		TagsAll:        tagsAll,
+		TagsNone:       tagsNone,
 	}
 
-	tasks, total, err := c.Repo.GetTasks(filter)
+	// counting is what makes deep pages slow, so cursors skip it by default
+	withTotal := !cursorMode
+	if totalStr != "" {
+		b, err := strconv.ParseBool(totalStr)
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": "total must be true or false"})
+			return
+		}
+		withTotal = b
+	}
+	filter.SkipTotal = !withTotal
+
+	if cursorMode {
+		c.getTaskPage(ctx, scope, filter, strings.TrimSpace(cursorStr))
+		return
+	}
+
+	tasks, total, err := c.Repo.GetTasks(scope, filter)
+	if err == nil {
+		err = withProgress(c.SubtaskRepo, scope, tasks)
+	}
 	if err != nil {
 		ctx.JSON(500, gin.H{"error": "failed to fetch tasks"})
 		return
 	}
 
-	resp := gin.H{
-		"data": tasks,
-		"meta": gin.H{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-			"pages": (total + int64(limit) - 1) / int64(limit),
-		},
-	}
+	q := &repository.ListQuery{Page: filter.Page, Limit: filter.Limit, SkipTotal: filter.SkipTotal}
+	resp := listResponse(q, repository.Page[models.Task]{Items: tasks, Total: total})
 
 	// Cache only unfiltered result
 	if !hasFilters {
 		jsonData, _ := json.Marshal(resp)
-		services.RedisClient.Set(services.Ctx, "tasks:all", jsonData, 30*time.Second)
+		services.RedisClient.Set(services.Ctx, cacheKey, jsonData, 30*time.Second)
 	}
 
 	ctx.JSON(200, resp)
 }
 
+// getTaskPage answers GetAllTasks in cursor mode.
+func (c *TaskController) getTaskPage(ctx *gin.Context, scope repository.Scope, filter *repository.TaskFilter, cursorStr string) {
+	var cursor *repository.Cursor
+	if cursorStr != "" {
+		var err error
+		if cursor, err = repository.TaskList.DecodeCursor(cursorStr, repository.TaskList.SortOrDefault(filter.Sort)); err != nil {
+			ctx.JSON(400, gin.H{"error": "invalid cursor"})
+			return
+		}
+	}
+
+	page, err := c.Repo.GetTaskPage(scope, filter, cursor)
+	if err == nil {
+		err = withProgress(c.SubtaskRepo, scope, page.Items)
+	}
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": "failed to fetch tasks"})
+		return
+	}
+
+	q := &repository.ListQuery{Limit: filter.Limit, Cursors: true, SkipTotal: filter.SkipTotal}
+	ctx.JSON(200, listResponse(q, page))
+}
+
 // GetTaskByID godoc
 // @Summary      Get a task by ID
-// @Description  Retrieves a task by its ID. Requires authentication.
+// @Description  Retrieves a task by its ID. A task with subtasks or checklist items reports its progress in percent. The response carries an ETag; send it back in If-None-Match to get 304 while the task is unchanged. Requires authentication.
 // @Tags         tasks
 // @Produce      json
 // @Param        Authorization header string true "Bearer token"
+// @Param        If-None-Match header string false "ETag of a cached copy"
 // @Param        id path int true "Task ID"
 // @Success      200 {object} models.Task
+// @Header       200 {string} ETag "Entity tag of the task"
+// @Success      304 "Not Modified"
 // @Failure      401 {object} map[string]string
 // @Failure      404 {object} map[string]string
 // @Router       /tasks/{id} [get]
 // @Security     BearerAuth
 func (c *TaskController) GetTaskByID(ctx *gin.Context) {
 	id, _ := strconv.Atoi(ctx.Param("id"))
-	task, err := c.Repo.GetByID(uint(id))
+	task, err := c.getTask(scopeFrom(ctx), uint(id))
 	if err != nil {
 		ctx.JSON(404, gin.H{"error": "task not found"})
 		return
 	}
 
-	services.RedisClient.Del(services.Ctx, "tasks:all")
+	invalidateList("tasks", task.UserID)
 
+	if !preconditions(ctx, taskETag(task)) {
+		return
+	}
 	ctx.JSON(200, task)
 }
 
 // UpdateTask godoc
 // @Summary      Update a task
-// @Description  Updates fields of a task. Requires authentication.
+// @Description  Partially updates a task. The body is a JSON Merge Patch (application/merge-patch+json, or plain application/json) or a JSON Patch (application/json-patch+json) over the writable fields shown in the payload model; any other field is rejected. A new status must be reachable from the current one in the workflow of the task's subject, as with POST /tasks/{id}/transitions. For recurring tasks, apply_to=this (default) edits only this occurrence; apply_to=following also edits every later occurrence and may change the recurrence_rule. With If-Match the update only happens while the task still has that ETag. Returns the updated task. Requires authentication.
 // @Tags         tasks
 // @Accept       json
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
 // @Produce      json
 // @Param        Authorization header string true "Bearer token"
+// @Param        If-Match header string false "ETag the task must still have"
 // @Param        id path int true "Task ID"
-// @Param        data body map[string]interface{} true "Task fields to update"
-// @Success      200 {object} map[string]string
+// @Param        apply_to query string false "this | following (recurring tasks)"
+// @Param        data body models.TaskPatch true "Merge patch of the writable task fields, or a JSON Patch array"
+// @Success      200 {object} models.Task
+// @Header       200 {string} ETag "Entity tag of the updated task"
 // @Failure      400 {object} map[string]string
 // @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string "JSON Patch test operation failed, the workflow does not allow the new status, the task waits for open tasks, or a concurrent update without If-Match"
+// @Failure      412 {object} map[string]string "The task no longer matches If-Match"
+// @Failure      415 {object} map[string]string
 // @Failure      500 {object} map[string]string
+// @Router       /tasks/{id} [patch]
 // @Router       /tasks/{id} [put]
 // @Security     BearerAuth
 func (c *TaskController) UpdateTask(ctx *gin.Context) {
 	id, _ := strconv.Atoi(ctx.Param("id"))
-	var data map[string]interface{}
-	if err := ctx.ShouldBindJSON(&data); err != nil {
+
+	applyTo := ctx.DefaultQuery("apply_to", "this")
+	if applyTo != "this" && applyTo != "following" {
+		ctx.JSON(400, gin.H{"error": "apply_to must be 'this' or 'following'"})
+		return
+	}
+
+	scope := scopeFrom(ctx)
+	task, err := c.getTask(scope, uint(id))
+	if err != nil {
+		ctx.JSON(404, gin.H{"error": "task not found"})
+		return
+	}
+	if !preconditions(ctx, taskETag(task)) {
+		return
+	}
+
+	var p models.TaskPatch
+	if !bindPatch(ctx, models.NewTaskPatch(task), &p) {
+		return
+	}
+	data := p.Changes(task)
+
+	// a task can only move to another subject of its owner, whoever edits it
+	wf := task.Subject.TaskWorkflow()
+	if _, ok := data["subject_id"]; ok {
+		subject, err := c.SubjectRepo.GetByID(repository.Scope{UserID: task.UserID}, p.SubjectID)
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": "subject not found"})
+			return
+		}
+		wf = subject.TaskWorkflow()
+	}
+	if err := services.ChangeStatus(wf, task, data, time.Now()); err != nil {
+		writeStatusError(ctx, err)
+		return
+	}
+	if err := services.CheckBlockers(c.DepRepo, scope, task, data); err != nil {
+		writeStatusError(ctx, err)
+		return
+	}
+
+	if _, ok := data["recurrence_rule"]; ok {
+		if (task.IsSeriesMaster() || task.SeriesID != nil) && applyTo != "following" {
+			ctx.JSON(400, gin.H{"error": "changing the recurrence_rule requires apply_to=following"})
+			return
+		}
+		if p.RecurrenceRule != "" && task.ParentID != nil {
+			ctx.JSON(400, gin.H{"error": "subtasks cannot recur"})
+			return
+		}
+		if p.RecurrenceRule != "" {
+			rule, err := recurrence.Parse(p.RecurrenceRule)
+			if err != nil {
+				ctx.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+			data["recurrence_rule"] = rule.String()
+		}
+	}
+
+	if len(data) > 0 {
+		if applyTo == "following" {
+			err = c.Repo.UpdateFollowing(scope, task, data)
+		} else {
+			err = c.Repo.UpdateOccurrence(scope, task, data)
+		}
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(404, gin.H{"error": "task not found"})
+				return
+			}
+			if errors.Is(err, repository.ErrConflict) {
+				writeConflict(ctx)
+				return
+			}
+			ctx.JSON(500, gin.H{"error": "failed to update task"})
+			return
+		}
+
+		invalidateList("tasks", task.UserID)
+		invalidateList("deadlines", task.UserID)
+	}
+
+	updated, err := c.getTask(scope, task.ID)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": "failed to fetch task"})
+		return
+	}
+
+	ctx.Header("ETag", taskETag(updated))
+	ctx.JSON(200, updated)
+}
+
+// GetTaskTransitions godoc
+// @Summary      List the statuses a task can move to
+// @Description  Returns the task's status, the statuses it can move to next and the workflow of its subject. Subjects without a workflow of their own use the default one: todo, in-progress, blocked, done and cancelled. Requires authentication.
+// @Tags         tasks
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path int true "Task ID"
+// @Success      200 {object} models.TaskTransitions
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Router       /tasks/{id}/transitions [get]
+// @Security     BearerAuth
+func (c *TaskController) GetTaskTransitions(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	task, err := c.Repo.GetByID(scopeFrom(ctx), uint(id))
+	if err != nil {
+		ctx.JSON(404, gin.H{"error": "task not found"})
+		return
+	}
+
+	wf := task.Subject.TaskWorkflow()
+	ctx.JSON(200, models.TaskTransitions{Status: task.Status, Next: wf.Next(task.Status), Workflow: wf})
+}
+
+// TransitionTask godoc
+// @Summary      Move a task to another status
+// @Description  Moves the task to the status in "to" if the workflow of its subject allows it from the current one. Entering an active or done status records started_at, entering a done status completed_at; going back to a todo status clears both. A task cannot be done while tasks it waits for are open. For recurring tasks only this occurrence changes. With If-Match the move only happens while the task still has that ETag. Returns the updated task. Requires authentication.
+// @Tags         tasks
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        If-Match header string false "ETag the task must still have"
+// @Param        id path int true "Task ID"
+// @Param        data body models.TransitionRequest true "Target status"
+// @Success      200 {object} models.Task
+// @Header       200 {string} ETag "Entity tag of the updated task"
+// @Failure      400 {object} map[string]string "Unknown status"
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string "The workflow does not allow the move, the task waits for open tasks, or a concurrent update without If-Match"
+// @Failure      412 {object} map[string]string "The task no longer matches If-Match"
+// @Failure      500 {object} map[string]string
+// @Router       /tasks/{id}/transitions [post]
+// @Security     BearerAuth
+func (c *TaskController) TransitionTask(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Param("id"))
+
+	var req models.TransitionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
-	if err := c.Repo.Update(uint(id), data); err != nil {
-		ctx.JSON(500, gin.H{"error": "failed to update task"})
+
+	scope := scopeFrom(ctx)
+	task, err := c.getTask(scope, uint(id))
+	if err != nil {
+		ctx.JSON(404, gin.H{"error": "task not found"})
+		return
+	}
+	if !preconditions(ctx, taskETag(task)) {
 		return
 	}
 
-	services.RedisClient.Del(services.Ctx, "tasks:all")
+	if req.To != task.Status {
+		data := map[string]interface{}{"status": req.To}
+		if err := services.ChangeStatus(task.Subject.TaskWorkflow(), task, data, time.Now()); err != nil {
+			writeStatusError(ctx, err)
+			return
+		}
+		if err := services.CheckBlockers(c.DepRepo, scope, task, data); err != nil {
+			writeStatusError(ctx, err)
+			return
+		}
+		if err := c.Repo.UpdateOccurrence(scope, task, data); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(404, gin.H{"error": "task not found"})
+				return
+			}
+			if errors.Is(err, repository.ErrConflict) {
+				writeConflict(ctx)
+				return
+			}
+			ctx.JSON(500, gin.H{"error": "failed to update task"})
+			return
+		}
+
+		invalidateList("tasks", task.UserID)
+		invalidateList("deadlines", task.UserID)
+	}
+
+	updated, err := c.getTask(scope, task.ID)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": "failed to fetch task"})
+		return
+	}
 
-	ctx.JSON(200, gin.H{"message": "task updated"})
+	ctx.Header("ETag", taskETag(updated))
+	ctx.JSON(200, updated)
+}
+
+// writeStatusError answers a status change the workflow rejects: 400 for a
+// status it does not have, 409 for a move it does not allow.
+func writeStatusError(ctx *gin.Context, err error) {
+	var se *services.StatusError
+	if !errors.As(err, &se) {
+		ctx.JSON(500, gin.H{"error": "failed to update task"})
+		return
+	}
+	if se.Unknown {
+		ctx.JSON(400, gin.H{"error": se.Error()})
+		return
+	}
+	ctx.JSON(409, gin.H{"error": se.Error()})
 }
 
 // DeleteTask godoc
 // @Summary      Delete a task
-// @Description  Deletes a task by ID. Requires authentication.
+// @Description  Moves a task, its subtasks and its deadlines to the trash, from where POST /trash/tasks/{id}/restore brings them back. With If-Match the task is only deleted while it still has that ETag. Requires authentication.
 // @Tags         tasks
 // @Produce      json
 // @Param        Authorization header string true "Bearer token"
+// @Param        If-Match header string false "ETag the task must still have"
 // @Param        id path int true "Task ID"
+// @Param        apply_to query string false "this | following (recurring tasks)"
 // @Success      200 {object} map[string]string
+// @Failure      400 {object} map[string]string
 // @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string "Concurrent update without If-Match"
+// @Failure      412 {object} map[string]string "The task no longer matches If-Match"
 // @Failure      500 {object} map[string]string
 // @Router       /tasks/{id} [delete]
 // @Security     BearerAuth
 func (c *TaskController) DeleteTask(ctx *gin.Context) {
 	id, _ := strconv.Atoi(ctx.Param("id"))
-	if err := c.Repo.Delete(uint(id)); err != nil {
+
+	applyTo := ctx.DefaultQuery("apply_to", "this")
+	if applyTo != "this" && applyTo != "following" {
+		ctx.JSON(400, gin.H{"error": "apply_to must be 'this' or 'following'"})
+		return
+	}
+
+	scope := scopeFrom(ctx)
+	task, err := c.getTask(scope, uint(id))
+	if err != nil {
+		ctx.JSON(404, gin.H{"error": "task not found"})
+		return
+	}
+	if !preconditions(ctx, taskETag(task)) {
+		return
+	}
+
+	if applyTo == "following" {
+		err = c.Repo.DeleteFollowing(scope, task)
+	} else {
+		err = c.Repo.DeleteOccurrence(scope, task)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(404, gin.H{"error": "task not found"})
+			return
+		}
+		if errors.Is(err, repository.ErrConflict) {
+			writeConflict(ctx)
+			return
+		}
 		ctx.JSON(500, gin.H{"error": "delete failed"})
 		return
 	}
 
-	services.RedisClient.Del(services.Ctx, "tasks:all")
+	invalidateList("tasks", task.UserID)
+	invalidateList("deadlines", task.UserID)
 
 	ctx.JSON(200, gin.H{"message": "deleted"})
 }
+
+// rawQuery returns a query parameter as it was sent, unescaped. Filter
+// expressions need this: net/url drops parameters with an unescaped ; and
+// reads + as a space.
+func rawQuery(ctx *gin.Context, name string) (string, error) {
+	for _, pair := range strings.Split(ctx.Request.URL.RawQuery, "&") {
+		if key, value, _ := strings.Cut(pair, "="); key == name {
+			return url.PathUnescape(value)
+		}
+	}
+	return "", nil
+}
+
+// queryList splits a comma-separated query parameter, dropping empty items.
+func queryList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
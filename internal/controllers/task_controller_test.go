@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"github.com/kadyrbayev2005/studysync/internal/repository/memory"
+	"github.com/kadyrbayev2005/studysync/internal/services"
+	"github.com/redis/go-redis/v9"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	// cache invalidation is best effort; nothing listens here
+	services.RedisClient = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 10 * time.Millisecond})
+}
+
+// as serves h to the user AuthMiddleware would have put in the context.
+func as(userID uint, role string, method, route, url string, h gin.HandlerFunc, body string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.Handle(method, route, func(ctx *gin.Context) {
+		ctx.Set("user_id", userID)
+		ctx.Set("user_role", role)
+	}, h)
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUpdateTaskSubjectOfOwner(t *testing.T) {
+	st := memory.NewStore()
+	c := NewTaskController(st.Tasks(), st.Subjects(), st.Dependencies(), st.Subtasks())
+
+	mine := models.Subject{Name: "Math", UserID: 1}
+	other := models.Subject{Name: "Math", UserID: 1}
+	theirs := models.Subject{Name: "History", UserID: 2}
+	for _, s := range []*models.Subject{&mine, &other, &theirs} {
+		if err := st.Subjects().Create(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	task := models.Task{Title: "Essay", SubjectID: mine.ID, UserID: 1, Deadline: time.Now().Add(24 * time.Hour)}
+	if err := st.Tasks().Create(&task); err != nil {
+		t.Fatal(err)
+	}
+	url := "/tasks/" + strconv.Itoa(int(task.ID))
+
+	tests := []struct {
+		name    string
+		userID  uint
+		role    string
+		subject uint
+		code    int
+	}{
+		{"owner onto another user's subject", 1, services.RoleUser, theirs.ID, http.StatusBadRequest},
+		{"admin onto another user's subject", 99, services.RoleAdmin, theirs.ID, http.StatusBadRequest},
+		{"admin onto a subject of the owner", 99, services.RoleAdmin, other.ID, http.StatusOK},
+	}
+	for _, tt := range tests {
+		body := `{"subject_id":` + strconv.Itoa(int(tt.subject)) + `}`
+		w := as(tt.userID, tt.role, http.MethodPatch, "/tasks/:id", url, c.UpdateTask, body)
+		if w.Code != tt.code {
+			t.Errorf("%s: got %d %s, want %d", tt.name, w.Code, w.Body, tt.code)
+		}
+	}
+
+	got, err := st.Tasks().GetByID(repository.Scope{Admin: true}, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SubjectID != other.ID {
+		t.Errorf("task is in subject %d, want %d", got.SubjectID, other.ID)
+	}
+}
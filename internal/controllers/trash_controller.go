@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+type TrashController struct {
+	Repo repository.TrashStore
+	// Retention is how long items stay in the trash before they are purged.
+	Retention time.Duration
+}
+
+func NewTrashController(repo repository.TrashStore, retention time.Duration) *TrashController {
+	return &TrashController{Repo: repo, Retention: retention}
+}
+
+// GetTrash godoc
+// @Summary      List the trash
+// @Description  Returns the deleted subjects, tasks and deadlines that can still be restored, latest deletion first, with the time each will be purged. Tasks and deadlines deleted together with their subject or task are not listed; they come back with it.
+// @Tags         trash
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Success      200 {array} models.TrashItem
+// @Failure      401 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /trash [get]
+// @Security     BearerAuth
+func (c *TrashController) GetTrash(ctx *gin.Context) {
+	items, err := c.Repo.List(ownTrash(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch trash"})
+		return
+	}
+	for i := range items {
+		items[i].PurgeAt = items[i].DeletedAt.Add(c.Retention)
+	}
+	ctx.JSON(http.StatusOK, items)
+}
+
+// RestoreFromTrash godoc
+// @Summary      Restore from the trash
+// @Description  Restores a deleted subject, task or deadline together with everything deleted along with it: a subject brings back its tasks, a task its deadlines and, for a recurring series, its later occurrences. Items whose subject or task is still in the trash answer 409; restore that first.
+// @Tags         trash
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        type path string true "subjects | tasks | deadlines"
+// @Param        id path int true "ID"
+// @Success      200 {object} map[string]string
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string "The subject or task it belongs to is in the trash"
+// @Failure      500 {object} map[string]string
+// @Router       /trash/{type}/{id}/restore [post]
+// @Security     BearerAuth
+func (c *TrashController) RestoreFromTrash(ctx *gin.Context) {
+	resource := ctx.Param("type")
+	switch resource {
+	case models.ResourceSubjects, models.ResourceTasks, models.ResourceDeadlines:
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "type must be subjects, tasks or deadlines"})
+		return
+	}
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	scope := ownTrash(ctx)
+	if err := c.Repo.Restore(scope, resource, uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "not in the trash"})
+			return
+		}
+		if errors.Is(err, repository.ErrParentDeleted) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "the subject or task it belongs to is in the trash; restore that first"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "restore failed"})
+		return
+	}
+
+	invalidateList("subjects", scope.UserID)
+	invalidateList("tasks", scope.UserID)
+	invalidateList("deadlines", scope.UserID)
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "restored"})
+}
+
+// ownTrash scopes the trash to the caller's own items, for admins too.
+func ownTrash(ctx *gin.Context) repository.Scope {
+	return repository.Scope{UserID: scopeFrom(ctx).UserID}
+}
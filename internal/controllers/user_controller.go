@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -13,18 +14,18 @@ import (
 )
 
 type UserController struct {
-	Repo *repository.UserRepository
+	Repo      repository.UserStore
+	TokenRepo *repository.RefreshTokenRepository
 }
 
-func NewUserController(repo *repository.UserRepository) *UserController {
-	return &UserController{Repo: repo}
+func NewUserController(repo repository.UserStore, tokenRepo *repository.RefreshTokenRepository) *UserController {
+	return &UserController{Repo: repo, TokenRepo: tokenRepo}
 }
 
 type registerPayload struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
-	Role     string `json:"role"`
 }
 
 type loginPayload struct {
@@ -34,7 +35,7 @@ type loginPayload struct {
 
 // Register godoc
 // @Summary Register a new user
-// @Description Register a new user. Role is optional; defaults to "user".
+// @Description Register a new user with the "user" role. Admins can see and edit every user's data, so they are only made with "studysync create-admin".
 // @Tags users
 // @Accept json
 // @Produce json
@@ -55,14 +56,10 @@ func (c *UserController) Register(ctx *gin.Context) {
 		Name:         p.Name,
 		Email:        p.Email,
 		PasswordHash: hashed,
-		Role:         p.Role,
+		Role:         services.RoleUser,
 		CreatedAt:    time.Now(),
 	}
 
-	if user.Role == "" {
-		user.Role = services.RoleUser
-	}
-
 	if err := c.Repo.Create(&user); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
 		return
@@ -77,12 +74,12 @@ func (c *UserController) Register(ctx *gin.Context) {
 
 // Login godoc
 // @Summary Login user
-// @Description Authenticate user and return JWT
+// @Description Authenticate user and return a short-lived access token plus a refresh token
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param credentials body loginPayload true "Login credentials"
-// @Success 200 {object} map[string]string
+// @Success 200 {object} models.TokenResponse
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 500 {object} map[string]string
@@ -100,7 +97,7 @@ func (c *UserController) Login(ctx *gin.Context) {
 		return
 	}
 
-	token, err := services.GenerateJWT(user.ID, user.Role)
+	tokens, err := services.IssueTokenPair(c.TokenRepo, user.ID, user.Role, "")
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
 		return
@@ -108,41 +105,173 @@ func (c *UserController) Login(ctx *gin.Context) {
 
 	services.RedisClient.Del(services.Ctx, "users:all")
 
-	ctx.JSON(http.StatusOK, gin.H{"token": token})
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// Refresh godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access token and a new refresh token. Each refresh token can be used once; reusing one revokes every token derived from the same login.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param payload body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/refresh [post]
+func (c *UserController) Refresh(ctx *gin.Context) {
+	var p models.RefreshRequest
+	if err := ctx.ShouldBindJSON(&p); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rt, err := services.ConsumeRefreshToken(c.TokenRepo, p.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRefreshTokenReused):
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token reuse detected; please log in again"})
+		case errors.Is(err, services.ErrRefreshTokenInvalid):
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "token refresh failed"})
+		}
+		return
+	}
+
+	user, err := c.Repo.GetByID(rt.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		return
+	}
+
+	tokens, err := services.IssueTokenPair(c.TokenRepo, user.ID, user.Role, rt.FamilyID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description Revoke the current access token and, if given, the refresh token issued with it.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param payload body models.LogoutRequest false "Refresh token to revoke"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/logout [post]
+// @Security BearerAuth
+func (c *UserController) Logout(ctx *gin.Context) {
+	var p models.LogoutRequest
+	_ = ctx.ShouldBindJSON(&p)
+
+	claims := ctx.MustGet("claims").(*services.Claims)
+
+	if p.RefreshToken != "" {
+		if err := services.RevokeRefreshToken(c.TokenRepo, claims.UserID, p.RefreshToken); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "logout failed"})
+			return
+		}
+	}
+
+	if err := services.RevokeAccessToken(claims); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "logout failed"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// LogoutAll godoc
+// @Summary Log out everywhere
+// @Description Revoke every access and refresh token of the current user on all devices.
+// @Tags users
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/logout-all [post]
+// @Security BearerAuth
+func (c *UserController) LogoutAll(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*services.Claims)
+
+	if err := c.TokenRepo.RevokeAllForUser(claims.UserID, time.Now()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "logout failed"})
+		return
+	}
+	if err := services.RevokeUserAccessTokens(claims.UserID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "logout failed"})
+		return
+	}
+	if err := services.RevokeAccessToken(claims); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "logout failed"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "logged out from all devices"})
 }
 
 // GetAll godoc
 // @Summary List all users
-// @Description Returns all users (admin only)
+// @Description Returns a page of the users (admin only). Pages are numbered by default; send cursor (empty for the first page) to page with cursors instead: meta then carries next_cursor and prev_cursor, null at the ends of the list, to send back with the same filters and sort. meta.total is counted in page mode and, with total=true, in cursor mode.
 // @Tags users
 // @Produce json
 // @Param Authorization header string true "Bearer token"
-// @Success 200 {array} models.User
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 10, at most 100)"
+// @Param cursor query string false "Opaque cursor from meta.next_cursor or meta.prev_cursor; empty for the first page"
+// @Param total query bool false "Count the matching users (default: true for pages, false for cursors)"
+// @Param sort query string false "Sort by id, name, email or created_at with optional 'desc' (default: id)"
+// @Param role query string false "Filter by role"
+// @Param email query string false "Filter by email, ignoring case"
+// @Param created_after query string false "Created at or after this time (RFC3339)"
+// @Param created_before query string false "Created at or before this time (RFC3339)"
+// @Success 200 {object} map[string]interface{} "Paginated response: data + meta"
+// @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /users [get]
 // @Security BearerAuth
 func (c *UserController) GetAll(ctx *gin.Context) {
-	cached, _ := services.RedisClient.Get(services.Ctx, "users:all").Result()
-    if cached != "" {
-        ctx.Data(200, "application/json", []byte(cached))
-        return
-    }
+	// Only the first page with no query at all is cached
+	plain := ctx.Request.URL.RawQuery == ""
+	if plain {
+		cached, _ := services.RedisClient.Get(services.Ctx, "users:all").Result()
+		if cached != "" {
+			ctx.Data(200, "application/json", []byte(cached))
+			return
+		}
+	}
 
-	users, err := c.Repo.GetAll()
+	q, ok := listQuery(ctx, repository.UserList)
+	if !ok {
+		return
+	}
+	page, err := c.Repo.List(q)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch users"})
+		writeListError(ctx, err, "users")
 		return
 	}
 
-	for i := range users {
-		users[i].PasswordHash = ""
+	for i := range page.Items {
+		page.Items[i].PasswordHash = ""
 	}
 
-	jsonData, _ := json.Marshal(users)
-    services.RedisClient.Set(services.Ctx, "users:all", jsonData, 30*time.Second)
-	
-	ctx.JSON(http.StatusOK, users)
+	resp := listResponse(q, page)
+	if plain {
+		jsonData, _ := json.Marshal(resp)
+		services.RedisClient.Set(services.Ctx, "users:all", jsonData, 30*time.Second)
+	}
+
+	ctx.JSON(http.StatusOK, resp)
 }
 
 // GetByID godoc
@@ -0,0 +1,171 @@
+// Package ical reads and writes the parts of RFC 5545 iCalendar that
+// StudySync needs: components, properties with parameters, text escaping
+// and line folding.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Property is a single content line such as "DTSTART;TZID=UTC:20260101T090000".
+type Property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Component is a BEGIN/END block such as VCALENDAR, VEVENT or VALARM.
+type Component struct {
+	Name       string
+	Props      []Property
+	Components []*Component
+}
+
+func NewComponent(name string) *Component {
+	return &Component{Name: name}
+}
+
+// Add appends a property with a raw (already escaped) value.
+func (c *Component) Add(name, value string) *Component {
+	c.Props = append(c.Props, Property{Name: name, Value: value})
+	return c
+}
+
+// AddText appends a TEXT property, escaping the value.
+func (c *Component) AddText(name, value string) *Component {
+	return c.Add(name, EscapeText(value))
+}
+
+// AddTime appends a DATE-TIME property in UTC.
+func (c *Component) AddTime(name string, t time.Time) *Component {
+	return c.Add(name, FormatDateTime(t))
+}
+
+func (c *Component) AddComponent(child *Component) *Component {
+	c.Components = append(c.Components, child)
+	return c
+}
+
+// Get returns the first property with the given name.
+func (c *Component) Get(name string) (Property, bool) {
+	for _, p := range c.Props {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Property{}, false
+}
+
+// Text returns the unescaped value of the first property with the given name.
+func (c *Component) Text(name string) string {
+	p, ok := c.Get(name)
+	if !ok {
+		return ""
+	}
+	return UnescapeText(p.Value)
+}
+
+// Encode writes c with CRLF line endings and lines folded at 75 octets.
+func Encode(w io.Writer, c *Component) error {
+	bw := bufio.NewWriter(w)
+	encode(bw, c)
+	return bw.Flush()
+}
+
+func encode(w *bufio.Writer, c *Component) {
+	writeLine(w, "BEGIN:"+c.Name)
+	for _, p := range c.Props {
+		writeLine(w, p.String())
+	}
+	for _, child := range c.Components {
+		encode(w, child)
+	}
+	writeLine(w, "END:"+c.Name)
+}
+
+func (p Property) String() string {
+	var b strings.Builder
+	b.WriteString(p.Name)
+
+	keys := make([]string, 0, len(p.Params))
+	for k := range p.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := p.Params[k]
+		if strings.ContainsAny(v, ":;,") {
+			v = `"` + v + `"`
+		}
+		b.WriteString(";" + k + "=" + v)
+	}
+
+	b.WriteString(":" + p.Value)
+	return b.String()
+}
+
+// writeLine folds a content line so that no physical line exceeds 75
+// octets, without splitting a UTF-8 sequence.
+func writeLine(w *bufio.Writer, line string) {
+	const limit = 75
+	first := true
+	for len(line) > 0 {
+		max := limit
+		if !first {
+			max = limit - 1 // room for the leading space
+		}
+		if len(line) <= max {
+			if !first {
+				w.WriteByte(' ')
+			}
+			w.WriteString(line)
+			break
+		}
+		cut := max
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		if !first {
+			w.WriteByte(' ')
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n")
+		line = line[cut:]
+		first = false
+	}
+	w.WriteString("\r\n")
+}
+
+// EscapeText escapes a TEXT value as required by RFC 5545 section 3.3.11.
+func EscapeText(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+// UnescapeText reverses EscapeText.
+func UnescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// FormatDateTime formats t as a UTC DATE-TIME value.
+func FormatDateTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
@@ -0,0 +1,111 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		text, escaped string
+	}{
+		{"Essay", "Essay"},
+		{"Read ch. 1, 2; 3", `Read ch. 1\, 2\; 3`},
+		{`C:\notes`, `C:\\notes`},
+		{"line one\nline two\r\nline three", `line one\nline two\nline three`},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := EscapeText(tt.text); got != tt.escaped {
+			t.Errorf("EscapeText(%q) = %q, want %q", tt.text, got, tt.escaped)
+		}
+		want := strings.ReplaceAll(tt.text, "\r\n", "\n")
+		if got := UnescapeText(tt.escaped); got != want {
+			t.Errorf("UnescapeText(%q) = %q, want %q", tt.escaped, got, want)
+		}
+	}
+
+	if got := UnescapeText(`a\Nb\:c\`); got != "a\nb:c\\" {
+		t.Errorf("UnescapeText of \\N, an unknown escape and a trailing \\ = %q", got)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	event := NewComponent("VEVENT").
+		Add("UID", "task-7@studysync").
+		AddTime("DTSTART", time.Date(2030, 1, 7, 12, 0, 0, 0, time.FixedZone("", 3*3600))).
+		AddText("SUMMARY", "Essay, draft; v2")
+	event.Props = append(event.Props, Property{
+		Name:   "ATTENDEE",
+		Params: map[string]string{"ROLE": "REQ-PARTICIPANT", "CN": "Doe, Jane"},
+		Value:  "mailto:jane@example.com",
+	})
+	cal := NewComponent("VCALENDAR").Add("VERSION", "2.0").AddComponent(event)
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, cal); err != nil {
+		t.Fatal(err)
+	}
+	want := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:task-7@studysync\r\n" +
+		"DTSTART:20300107T090000Z\r\n" +
+		"SUMMARY:Essay\\, draft\\; v2\r\n" +
+		"ATTENDEE;CN=\"Doe, Jane\";ROLE=REQ-PARTICIPANT:mailto:jane@example.com\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Encode wrote\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		lines int
+	}{
+		{"short", "Essay", 1},
+		{"exactly 75 octets", strings.Repeat("a", 75-len("SUMMARY:")), 1},
+		{"76 octets", strings.Repeat("a", 76-len("SUMMARY:")), 2},
+		{"long", strings.Repeat("abcdefghij", 30), 5},
+		// multi-byte characters straddle every fold at some offset
+		{"utf-8", strings.Repeat("домашнее задание ✓ ", 12), 0},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Encode(&buf, NewComponent("VTODO").AddText("SUMMARY", tt.value)); err != nil {
+			t.Fatal(err)
+		}
+		out := strings.TrimSuffix(buf.String(), "\r\n")
+		lines := strings.Split(out, "\r\n")
+		folded := lines[1 : len(lines)-1]
+
+		if tt.lines > 0 && len(folded) != tt.lines {
+			t.Errorf("%s: folded into %d lines, want %d", tt.name, len(folded), tt.lines)
+		}
+		for i, line := range folded {
+			if len(line) > 75 {
+				t.Errorf("%s: line %d is %d octets", tt.name, i, len(line))
+			}
+			if i > 0 && !strings.HasPrefix(line, " ") {
+				t.Errorf("%s: continuation line %d does not start with a space", tt.name, i)
+			}
+			if !utf8.ValidString(line) {
+				t.Errorf("%s: line %d splits a UTF-8 sequence: %q", tt.name, i, line)
+			}
+		}
+
+		c, err := Parse(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := c.Text("SUMMARY"); got != tt.value {
+			t.Errorf("%s: unfolded to %q, want %q", tt.name, got, tt.value)
+		}
+	}
+}
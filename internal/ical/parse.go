@@ -0,0 +1,159 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Parse reads an iCalendar stream and returns its top-level component,
+// normally a VCALENDAR.
+func Parse(r io.Reader) (*Component, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var root *Component
+	var stack []*Component
+
+	for n, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		p, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("ical: line %d: %w", n+1, err)
+		}
+
+		switch strings.ToUpper(p.Name) {
+		case "BEGIN":
+			c := NewComponent(strings.ToUpper(p.Value))
+			if len(stack) > 0 {
+				stack[len(stack)-1].AddComponent(c)
+			} else if root == nil {
+				root = c
+			} else {
+				return nil, fmt.Errorf("ical: line %d: more than one top-level component", n+1)
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(p.Value) {
+				return nil, fmt.Errorf("ical: line %d: unexpected END:%s", n+1, p.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("ical: line %d: property outside of a component", n+1)
+			}
+			cur := stack[len(stack)-1]
+			cur.Props = append(cur.Props, p)
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("ical: no calendar data")
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("ical: missing END:%s", stack[len(stack)-1].Name)
+	}
+	return root, nil
+}
+
+// unfold joins continuation lines (starting with a space or tab) onto the
+// previous line.
+func unfold(r io.Reader) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, sc.Err()
+}
+
+// parseLine splits "NAME;PARAM=value;PARAM2=\"quoted:value\":VALUE".
+func parseLine(line string) (Property, error) {
+	p := Property{}
+
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return p, fmt.Errorf("malformed content line %q", line)
+	}
+	p.Name = strings.ToUpper(line[:i])
+	rest := line[i:]
+
+	for strings.HasPrefix(rest, ";") {
+		rest = rest[1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return p, fmt.Errorf("malformed parameter in %q", line)
+		}
+		key := strings.ToUpper(rest[:eq])
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return p, fmt.Errorf("unterminated quoted parameter in %q", line)
+			}
+			value = rest[1 : end+1]
+			rest = rest[end+2:]
+		} else {
+			end := strings.IndexAny(rest, ";:")
+			if end < 0 {
+				return p, fmt.Errorf("malformed parameter in %q", line)
+			}
+			value = rest[:end]
+			rest = rest[end:]
+		}
+
+		if p.Params == nil {
+			p.Params = map[string]string{}
+		}
+		p.Params[key] = value
+	}
+
+	if !strings.HasPrefix(rest, ":") {
+		return p, fmt.Errorf("missing value in %q", line)
+	}
+	p.Value = rest[1:]
+	return p, nil
+}
+
+// Time parses a DATE or DATE-TIME property. UTC values end in Z; values
+// with a TZID parameter are read in that zone; floating values and
+// unknown zones are read as UTC. The second result is true for all-day
+// DATE values.
+func (p Property) Time() (time.Time, bool, error) {
+	v := strings.TrimSpace(p.Value)
+
+	if p.Params["VALUE"] == "DATE" || len(v) == 8 {
+		t, err := time.Parse("20060102", v)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(v, "Z") {
+		t, err := time.Parse("20060102T150405Z", v)
+		return t, false, err
+	}
+
+	loc := time.UTC
+	if tzid := p.Params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", v, loc)
+	return t, false, err
+}
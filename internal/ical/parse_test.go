@@ -0,0 +1,114 @@
+package ical
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	data := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"\r\n" +
+		"begin:vevent\r\n" +
+		"UID:42@example.com\r\n" +
+		"SUMMARY:Read ch. 1\\, 2\\; and\r\n" +
+		"  the notes\r\n" +
+		"DESCRIPTION:folded with\n" +
+		"\ta tab and a bare LF\n" +
+		"ATTENDEE;cn=\"Doe, Jane\";ROLE=CHAIR:mailto:jane@example.com\r\n" +
+		"BEGIN:VALARM\r\n" +
+		"TRIGGER:-PT15M\r\n" +
+		"END:VALARM\r\n" +
+		"end:vevent\r\n" +
+		"END:VCALENDAR\r\n"
+
+	cal, err := Parse(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cal.Name != "VCALENDAR" || len(cal.Components) != 1 {
+		t.Fatalf("got %s with %d components", cal.Name, len(cal.Components))
+	}
+	event := cal.Components[0]
+	if event.Name != "VEVENT" {
+		t.Errorf("component name %q, want VEVENT", event.Name)
+	}
+	if got := event.Text("SUMMARY"); got != "Read ch. 1, 2; and the notes" {
+		t.Errorf("SUMMARY = %q", got)
+	}
+	if got := event.Text("DESCRIPTION"); got != "folded witha tab and a bare LF" {
+		t.Errorf("DESCRIPTION = %q", got)
+	}
+	attendee, _ := event.Get("ATTENDEE")
+	want := Property{
+		Name:   "ATTENDEE",
+		Params: map[string]string{"CN": "Doe, Jane", "ROLE": "CHAIR"},
+		Value:  "mailto:jane@example.com",
+	}
+	if !reflect.DeepEqual(attendee, want) {
+		t.Errorf("ATTENDEE = %+v, want %+v", attendee, want)
+	}
+	if len(event.Components) != 1 || event.Components[0].Text("TRIGGER") != "-PT15M" {
+		t.Errorf("VALARM not read: %+v", event.Components)
+	}
+	if _, ok := event.Get("LOCATION"); ok {
+		t.Error("Get found a property that is not there")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name, data, err string
+	}{
+		{"empty", "", "no calendar data"},
+		{"no name", "BEGIN:VCALENDAR\r\n:value\r\n", "line 2: malformed content line"},
+		{"no value", "BEGIN:VCALENDAR\r\nSUMMARY;LANGUAGE=en\r\n", "line 2: malformed parameter"},
+		{"unterminated parameter", "BEGIN:VCALENDAR\r\nSUMMARY;CN=\"Doe:x\r\n", "line 2: unterminated quoted parameter"},
+		{"property outside", "VERSION:2.0\r\n", "line 1: property outside of a component"},
+		{"mismatched end", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VCALENDAR\r\n", "line 3: unexpected END:VCALENDAR"},
+		{"missing end", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VEVENT\r\n", "missing END:VCALENDAR"},
+		{"two calendars", "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\nBEGIN:VCALENDAR\r\n", "line 3: more than one top-level component"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestPropertyTime(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	tests := []struct {
+		line   string
+		want   time.Time
+		allDay bool
+	}{
+		{"DTSTART:20300107T090000Z", time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC), false},
+		{"DTSTART:20300107T090000", time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC), false},
+		{"DTSTART;TZID=America/New_York:20300107T090000", time.Date(2030, 1, 7, 9, 0, 0, 0, ny), false},
+		{"DTSTART;TZID=Nowhere/Special:20300107T090000", time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC), false},
+		{"DTSTART;VALUE=DATE:20300107", time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC), true},
+		{"DTSTART:20300107", time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		p, err := parseLine(tt.line)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.line, err)
+		}
+		got, allDay, err := p.Time()
+		if err != nil || !got.Equal(tt.want) || allDay != tt.allDay {
+			t.Errorf("%s: Time() = %v, %v, %v, want %v, %v", tt.line, got, allDay, err, tt.want, tt.allDay)
+		}
+	}
+
+	p, _ := parseLine("DTSTART:next tuesday")
+	if _, _, err := p.Time(); err == nil {
+		t.Error("Time() read a value that is not a time")
+	}
+}
@@ -27,9 +27,20 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		revoked, err := services.IsAccessTokenRevoked(claims)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "token revocation check failed"})
+			return
+		}
+		if revoked {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+			return
+		}
+
 		// put user info into context
 		c.Set("user_id", claims.UserID)
 		c.Set("user_role", claims.Role)
+		c.Set("claims", claims)
 		c.Next()
 	}
 }
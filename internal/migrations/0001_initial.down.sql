@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS deadlines;
+DROP TABLE IF EXISTS tasks;
+DROP TABLE IF EXISTS subjects;
+DROP TABLE IF EXISTS users;
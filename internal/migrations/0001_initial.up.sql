@@ -0,0 +1,42 @@
+-- Baseline: the schema that GORM AutoMigrate created before migrations
+-- existed. IF NOT EXISTS lets databases created by AutoMigrate adopt the
+-- migrations in place; everything added since is in later migrations,
+-- which use IF NOT EXISTS for the same reason.
+
+CREATE TABLE IF NOT EXISTS users (
+    id            bigserial PRIMARY KEY,
+    name          text,
+    email         text,
+    password_hash text,
+    role          text,
+    created_at    timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS subjects (
+    id          bigserial PRIMARY KEY,
+    name        text,
+    description text,
+    created_at  timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS tasks (
+    id          bigserial PRIMARY KEY,
+    title       text,
+    description text,
+    status      text,
+    deadline    timestamptz,
+    subject_id  bigint,
+    created_at  timestamptz,
+    CONSTRAINT fk_tasks_subject FOREIGN KEY (subject_id) REFERENCES subjects (id)
+);
+
+CREATE TABLE IF NOT EXISTS deadlines (
+    id         bigserial PRIMARY KEY,
+    task_id    bigint,
+    user_id    bigint,
+    due_date   timestamptz,
+    created_at timestamptz,
+    CONSTRAINT fk_deadlines_task FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    CONSTRAINT fk_deadlines_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
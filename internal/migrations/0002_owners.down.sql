@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_tasks_user_id;
+DROP INDEX IF EXISTS idx_subjects_user_id;
+ALTER TABLE tasks DROP COLUMN IF EXISTS user_id;
+ALTER TABLE subjects DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,29 @@
+-- Subjects and tasks belong to a user. Rows from before owners existed are
+-- given the owner their data points to: a task the user of its deadlines,
+-- a subject the user of its tasks when they all have the same one, and a
+-- deadline the owner of its task. Rows left without an owner are only
+-- visible to admins; "migrate status" counts them.
+ALTER TABLE subjects ADD COLUMN IF NOT EXISTS user_id bigint;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS user_id bigint;
+CREATE INDEX IF NOT EXISTS idx_subjects_user_id ON subjects (user_id);
+CREATE INDEX IF NOT EXISTS idx_tasks_user_id ON tasks (user_id);
+
+UPDATE tasks SET user_id = owners.user_id
+  FROM (SELECT task_id, min(user_id) AS user_id
+          FROM deadlines
+         WHERE user_id IS NOT NULL
+         GROUP BY task_id
+        HAVING count(DISTINCT user_id) = 1) owners
+ WHERE tasks.id = owners.task_id AND tasks.user_id IS NULL;
+
+UPDATE subjects SET user_id = owners.user_id
+  FROM (SELECT subject_id, min(user_id) AS user_id
+          FROM tasks
+         WHERE user_id IS NOT NULL
+         GROUP BY subject_id
+        HAVING count(DISTINCT user_id) = 1) owners
+ WHERE subjects.id = owners.subject_id AND subjects.user_id IS NULL;
+
+UPDATE deadlines SET user_id = tasks.user_id
+  FROM tasks
+ WHERE deadlines.task_id = tasks.id AND deadlines.user_id IS NULL;
@@ -0,0 +1,20 @@
+-- The notification log: one row per reminder delivery, unique per deadline,
+-- channel and offset so that each reminder is sent once.
+CREATE TABLE IF NOT EXISTS notifications (
+    id              bigserial PRIMARY KEY,
+    deadline_id     bigint,
+    user_id         bigint,
+    channel         text,
+    offset_minutes  bigint,
+    status          text,
+    attempts        bigint,
+    last_error      text,
+    next_attempt_at timestamptz,
+    sent_at         timestamptz,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    CONSTRAINT fk_notifications_deadline FOREIGN KEY (deadline_id) REFERENCES deadlines (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_delivery ON notifications (deadline_id, channel, offset_minutes);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_status ON notifications (status);
@@ -0,0 +1,12 @@
+-- Reminder schedules: a user's defaults have no deadline, a deadline's own
+-- schedule overrides them.
+CREATE TABLE IF NOT EXISTS reminder_offsets (
+    id             bigserial PRIMARY KEY,
+    user_id        bigint,
+    deadline_id    bigint,
+    offset_minutes bigint,
+    created_at     timestamptz,
+    CONSTRAINT fk_reminder_offsets_deadline FOREIGN KEY (deadline_id) REFERENCES deadlines (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_reminder_offsets_user_id ON reminder_offsets (user_id);
+CREATE INDEX IF NOT EXISTS idx_reminder_offsets_deadline_id ON reminder_offsets (deadline_id);
@@ -0,0 +1,16 @@
+-- Rotating refresh tokens, stored hashed. Tokens rotated from one login
+-- share a family, which is revoked as a whole when a used token comes back.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint,
+    family_id  varchar(64),
+    token_hash varchar(64),
+    expires_at timestamptz,
+    used_at    timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens (expires_at);
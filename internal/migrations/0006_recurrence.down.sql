@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_task_occurrence;
+ALTER TABLE tasks DROP COLUMN IF EXISTS recurrence_generated_until;
+ALTER TABLE tasks DROP COLUMN IF EXISTS occurrence_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS series_id;
+ALTER TABLE tasks DROP COLUMN IF EXISTS recurrence_rule;
@@ -0,0 +1,7 @@
+-- Recurring tasks: the first task of a series holds the rule, and its
+-- occurrences point back to it, one per series and time.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurrence_rule text;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS series_id bigint;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS occurrence_at timestamptz;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurrence_generated_until timestamptz;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_task_occurrence ON tasks (series_id, occurrence_at);
@@ -0,0 +1,11 @@
+-- One iCalendar feed per user, reached through a revocable token that is
+-- stored hashed.
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint,
+    token_hash varchar(64),
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_feeds_user_id ON calendar_feeds (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_feeds_token_hash ON calendar_feeds (token_hash);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_task_external_uid;
+ALTER TABLE tasks DROP COLUMN IF EXISTS external_uid;
@@ -0,0 +1,2 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS external_uid text;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_task_external_uid ON tasks (user_id, external_uid);
@@ -0,0 +1,3 @@
+ALTER TABLE deadlines DROP COLUMN IF EXISTS version;
+ALTER TABLE tasks DROP COLUMN IF EXISTS version;
+ALTER TABLE subjects DROP COLUMN IF EXISTS version;
@@ -0,0 +1,3 @@
+ALTER TABLE subjects ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE deadlines ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS record_change ON deadlines;
+DROP TRIGGER IF EXISTS record_change ON tasks;
+DROP TRIGGER IF EXISTS record_change ON subjects;
+DROP FUNCTION IF EXISTS record_change();
+DROP TABLE IF EXISTS changes;
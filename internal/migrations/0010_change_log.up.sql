@@ -0,0 +1,68 @@
+-- The change log behind /sync. Every insert, update and delete of a task,
+-- subject or deadline appends a row; deletes are the tombstones.
+CREATE TABLE IF NOT EXISTS changes (
+    seq         bigserial PRIMARY KEY,
+    user_id     bigint NOT NULL,
+    resource    text NOT NULL,
+    resource_id bigint NOT NULL,
+    op          text NOT NULL,
+    fields      text NOT NULL DEFAULT '',
+    changed_at  timestamptz NOT NULL DEFAULT clock_timestamp()
+);
+CREATE INDEX IF NOT EXISTS idx_changes_user_seq ON changes (user_id, seq);
+
+-- record_change is the trigger that fills the change log. Its arguments
+-- name the columns that are not part of what clients sync; updates that
+-- only touch those are not logged. Updates list the columns they changed.
+--
+-- Sync tokens are sequence numbers, so a client must never see a change
+-- before an earlier one of the same user has committed. The per-user
+-- advisory lock, held until commit, makes each user's changes take their
+-- numbers in commit order. The lock key is an int; ids past 2^31 are
+-- masked into it, so a few users may share a lock, which only serialises
+-- them.
+CREATE OR REPLACE FUNCTION record_change() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+    owner   bigint;
+    row_id  bigint;
+    changed text := '';
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        owner := OLD.user_id;
+        row_id := OLD.id;
+    ELSE
+        owner := NEW.user_id;
+        row_id := NEW.id;
+    END IF;
+    IF owner IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'UPDATE' THEN
+        SELECT string_agg(n.key, ',' ORDER BY n.key) INTO changed
+          FROM jsonb_each(to_jsonb(NEW) - TG_ARGV) n
+         WHERE n.value IS DISTINCT FROM (to_jsonb(OLD) - TG_ARGV) -> n.key;
+        IF changed IS NULL THEN
+            RETURN NULL;
+        END IF;
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(7301, (owner & 2147483647)::int);
+    INSERT INTO changes (user_id, resource, resource_id, op, fields)
+    VALUES (owner, TG_TABLE_NAME, row_id, lower(TG_OP), changed);
+    RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS record_change ON subjects;
+CREATE TRIGGER record_change AFTER INSERT OR UPDATE OR DELETE ON subjects
+    FOR EACH ROW EXECUTE FUNCTION record_change('version');
+
+DROP TRIGGER IF EXISTS record_change ON tasks;
+CREATE TRIGGER record_change AFTER INSERT OR UPDATE OR DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION record_change('version', 'recurrence_generated_until');
+
+DROP TRIGGER IF EXISTS record_change ON deadlines;
+CREATE TRIGGER record_change AFTER INSERT OR UPDATE OR DELETE ON deadlines
+    FOR EACH ROW EXECUTE FUNCTION record_change('version');
@@ -0,0 +1,48 @@
+-- Rows in the trash have no place in the old schema; they are dropped.
+DELETE FROM deadlines WHERE deleted_at IS NOT NULL;
+DELETE FROM tasks WHERE deleted_at IS NOT NULL;
+DELETE FROM subjects WHERE deleted_at IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION record_change() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+    owner   bigint;
+    row_id  bigint;
+    changed text := '';
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        owner := OLD.user_id;
+        row_id := OLD.id;
+    ELSE
+        owner := NEW.user_id;
+        row_id := NEW.id;
+    END IF;
+    IF owner IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'UPDATE' THEN
+        SELECT string_agg(n.key, ',' ORDER BY n.key) INTO changed
+          FROM jsonb_each(to_jsonb(NEW) - TG_ARGV) n
+         WHERE n.value IS DISTINCT FROM (to_jsonb(OLD) - TG_ARGV) -> n.key;
+        IF changed IS NULL THEN
+            RETURN NULL;
+        END IF;
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(7301, (owner & 2147483647)::int);
+    INSERT INTO changes (user_id, resource, resource_id, op, fields)
+    VALUES (owner, TG_TABLE_NAME, row_id, lower(TG_OP), changed);
+    RETURN NULL;
+END
+$$;
+
+DROP INDEX IF EXISTS idx_task_external_uid;
+CREATE UNIQUE INDEX idx_task_external_uid ON tasks (user_id, external_uid);
+
+DROP INDEX IF EXISTS idx_deadlines_deleted_at;
+DROP INDEX IF EXISTS idx_tasks_deleted_at;
+DROP INDEX IF EXISTS idx_subjects_deleted_at;
+ALTER TABLE deadlines DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE subjects DROP COLUMN IF EXISTS deleted_at;
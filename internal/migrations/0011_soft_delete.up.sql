@@ -0,0 +1,62 @@
+-- Soft delete: deleted subjects, tasks and deadlines stay in the trash with
+-- deleted_at set until they are restored or purged. Rows deleted together
+-- share the same deleted_at, which is how a restore finds them again.
+ALTER TABLE subjects ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE deadlines ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_subjects_deleted_at ON subjects (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_tasks_deleted_at ON tasks (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_deadlines_deleted_at ON deadlines (deleted_at);
+
+-- A calendar entry imported again after its task was deleted gets a new
+-- task; only live tasks claim their UID.
+DROP INDEX IF EXISTS idx_task_external_uid;
+CREATE UNIQUE INDEX idx_task_external_uid ON tasks (user_id, external_uid) WHERE deleted_at IS NULL;
+
+-- To clients, moving a row to the trash is a delete and restoring it an
+-- insert. Changes to rows in the trash, and purging them, are not logged.
+CREATE OR REPLACE FUNCTION record_change() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+    owner   bigint;
+    row_id  bigint;
+    op      text := lower(TG_OP);
+    changed text := '';
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN NULL;
+        END IF;
+        owner := OLD.user_id;
+        row_id := OLD.id;
+    ELSE
+        owner := NEW.user_id;
+        row_id := NEW.id;
+    END IF;
+    IF owner IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'UPDATE' THEN
+        IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+            op := 'delete';
+        ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+            op := 'insert';
+        ELSIF NEW.deleted_at IS NOT NULL THEN
+            RETURN NULL;
+        ELSE
+            SELECT string_agg(n.key, ',' ORDER BY n.key) INTO changed
+              FROM jsonb_each(to_jsonb(NEW) - TG_ARGV) n
+             WHERE n.value IS DISTINCT FROM (to_jsonb(OLD) - TG_ARGV) -> n.key;
+            IF changed IS NULL THEN
+                RETURN NULL;
+            END IF;
+        END IF;
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(7301, (owner & 2147483647)::int);
+    INSERT INTO changes (user_id, resource, resource_id, op, fields)
+    VALUES (owner, TG_TABLE_NAME, row_id, op, changed);
+    RETURN NULL;
+END
+$$;
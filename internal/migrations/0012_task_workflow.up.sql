@@ -0,0 +1,9 @@
+-- Task statuses follow a workflow: the default one or a subject's own,
+-- stored with the subject. Tasks record when work on them started and
+-- when they were done; tasks finished before this are left without times.
+ALTER TABLE subjects ADD COLUMN IF NOT EXISTS workflow jsonb;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS started_at timestamptz;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS completed_at timestamptz;
+
+-- tasks created before status was defaulted start at the beginning
+UPDATE tasks SET status = 'todo' WHERE status IS NULL OR status = '';
@@ -0,0 +1,16 @@
+-- A task can wait for other tasks of the same user. The estimate is how
+-- long a task takes, for planning chains of dependent tasks.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS estimate_minutes integer NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS task_dependencies (
+    task_id    bigint NOT NULL,
+    blocker_id bigint NOT NULL,
+    user_id    bigint NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (task_id, blocker_id),
+    CONSTRAINT fk_task_dependencies_task FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    CONSTRAINT fk_task_dependencies_blocker FOREIGN KEY (blocker_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    CONSTRAINT chk_task_dependencies_self CHECK (task_id <> blocker_id)
+);
+CREATE INDEX IF NOT EXISTS idx_task_dependencies_blocker_id ON task_dependencies (blocker_id);
+CREATE INDEX IF NOT EXISTS idx_task_dependencies_user_id ON task_dependencies (user_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS checklist_items;
+ALTER TABLE tasks DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,20 @@
+-- Tasks can be broken into subtasks, nested to any depth, and checklist
+-- items. Both go when their task is purged from the trash.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS parent_id bigint;
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS fk_tasks_parent;
+ALTER TABLE tasks ADD CONSTRAINT fk_tasks_parent FOREIGN KEY (parent_id) REFERENCES tasks (id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_tasks_parent_id ON tasks (parent_id);
+
+CREATE TABLE IF NOT EXISTS checklist_items (
+    id         bigserial PRIMARY KEY,
+    task_id    bigint NOT NULL,
+    user_id    bigint NOT NULL,
+    title      text NOT NULL,
+    done       boolean NOT NULL DEFAULT false,
+    position   integer NOT NULL DEFAULT 0,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_checklist_items_task FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_checklist_items_task_id ON checklist_items (task_id);
+CREATE INDEX IF NOT EXISTS idx_checklist_items_user_id ON checklist_items (user_id);
@@ -0,0 +1,20 @@
+-- Users label tasks with their own tags. Deleting a task for good or a tag
+-- removes its links.
+CREATE TABLE IF NOT EXISTS tags (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint NOT NULL,
+    name       text NOT NULL,
+    color      text NOT NULL,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_tags_user_id ON tags (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags (user_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS task_tags (
+    task_id bigint NOT NULL,
+    tag_id  bigint NOT NULL,
+    PRIMARY KEY (task_id, tag_id),
+    CONSTRAINT fk_task_tags_task FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    CONSTRAINT fk_task_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_task_tags_tag_id ON task_tags (tag_id);
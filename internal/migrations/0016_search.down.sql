@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS record_change ON tasks;
+CREATE TRIGGER record_change AFTER INSERT OR UPDATE OR DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION record_change('version', 'recurrence_generated_until');
+
+DROP TRIGGER IF EXISTS record_change ON subjects;
+CREATE TRIGGER record_change AFTER INSERT OR UPDATE OR DELETE ON subjects
+    FOR EACH ROW EXECUTE FUNCTION record_change('version');
+
+DROP INDEX IF EXISTS idx_subjects_search_vector;
+ALTER TABLE subjects DROP COLUMN IF EXISTS search_vector;
+DROP INDEX IF EXISTS idx_tasks_search_vector;
+ALTER TABLE tasks DROP COLUMN IF EXISTS search_vector;
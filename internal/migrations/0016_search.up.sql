@@ -0,0 +1,25 @@
+-- Full-text search over tasks and subjects. Postgres keeps a stemmed
+-- search vector of each row's title or name (weight A) and description
+-- (weight B); deadlines are searched through their task.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS idx_tasks_search_vector ON tasks USING gin (search_vector);
+
+ALTER TABLE subjects ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS idx_subjects_search_vector ON subjects USING gin (search_vector);
+
+-- the vectors are derived, so clients do not sync them
+DROP TRIGGER IF EXISTS record_change ON subjects;
+CREATE TRIGGER record_change AFTER INSERT OR UPDATE OR DELETE ON subjects
+    FOR EACH ROW EXECUTE FUNCTION record_change('version', 'search_vector');
+
+DROP TRIGGER IF EXISTS record_change ON tasks;
+CREATE TRIGGER record_change AFTER INSERT OR UPDATE OR DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION record_change('version', 'recurrence_generated_until', 'search_vector');
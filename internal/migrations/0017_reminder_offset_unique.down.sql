@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_reminder_offsets_deadline_offset;
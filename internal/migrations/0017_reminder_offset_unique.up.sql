@@ -0,0 +1,8 @@
+-- A deadline has each offset once. Duplicates that slipped in before the
+-- index keep the oldest row.
+DELETE FROM reminder_offsets r
+ USING reminder_offsets older
+ WHERE r.deadline_id = older.deadline_id
+   AND r.offset_minutes = older.offset_minutes
+   AND r.id > older.id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reminder_offsets_deadline_offset ON reminder_offsets (deadline_id, offset_minutes);
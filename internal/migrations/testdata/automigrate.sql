@@ -0,0 +1,67 @@
+-- A database as GORM AutoMigrate left it before migrations existed: the
+-- four tables of the original models, with no schema_migrations table and
+-- no owners on subjects and tasks. CI loads it and runs "migrate up".
+
+CREATE TABLE users (
+    id            bigserial,
+    name          text,
+    email         text,
+    password_hash text,
+    role          text,
+    created_at    timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_users_email ON users (email);
+
+CREATE TABLE subjects (
+    id          bigserial,
+    name        text,
+    description text,
+    created_at  timestamptz,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE tasks (
+    id          bigserial,
+    title       text,
+    description text,
+    status      text,
+    deadline    timestamptz,
+    subject_id  bigint,
+    created_at  timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_tasks_subject FOREIGN KEY (subject_id) REFERENCES subjects (id)
+);
+
+CREATE TABLE deadlines (
+    id         bigserial,
+    task_id    bigint,
+    user_id    bigint,
+    due_date   timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_deadlines_task FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    CONSTRAINT fk_deadlines_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+INSERT INTO users (name, email, password_hash, role, created_at) VALUES
+    ('Alice', 'alice@example.com', 'x', 'user', now()),
+    ('Bob', 'bob@example.com', 'x', 'user', now()),
+    ('Admin', 'admin@example.com', 'x', 'admin', now());
+
+-- Calculus only has Alice's tasks; Group project has both users' tasks
+INSERT INTO subjects (name, description, created_at) VALUES
+    ('Calculus', '', now()),
+    ('Group project', '', now());
+
+-- the last task has no deadline, so nothing says whose it is
+INSERT INTO tasks (title, description, status, deadline, subject_id, created_at) VALUES
+    ('Problem set', '', 'todo', now() + interval '3 days', 1, now()),
+    ('Slides', '', 'in-progress', now() + interval '5 days', 2, now()),
+    ('Report', '', 'done', now() - interval '1 day', 2, now()),
+    ('Notes', '', 'todo', now() + interval '7 days', 1, now());
+
+INSERT INTO deadlines (task_id, user_id, due_date, created_at) VALUES
+    (1, 1, now() + interval '3 days', now()),
+    (2, 1, now() + interval '5 days', now()),
+    (3, 2, now() - interval '1 day', now());
@@ -0,0 +1,24 @@
+-- Run after "migrate up" on automigrate.sql: the rows are still there and
+-- got the owners their deadlines point to.
+DO $$
+DECLARE
+    got text;
+BEGIN
+    SELECT string_agg(title || ':' || coalesce(user_id::text, '-'), ' ' ORDER BY id) INTO got FROM tasks;
+    IF got IS DISTINCT FROM 'Problem set:1 Slides:1 Report:2 Notes:-' THEN
+        RAISE EXCEPTION 'task owners after migrating: %', got;
+    END IF;
+
+    SELECT string_agg(name || ':' || coalesce(user_id::text, '-'), ' ' ORDER BY id) INTO got FROM subjects;
+    IF got IS DISTINCT FROM 'Calculus:1 Group project:-' THEN
+        RAISE EXCEPTION 'subject owners after migrating: %', got;
+    END IF;
+
+    -- the change log trigger runs
+    UPDATE subjects SET name = 'Calculus I' WHERE id = 1;
+    SELECT op || ' ' || fields INTO got FROM changes WHERE resource = 'subjects' AND resource_id = 1 ORDER BY seq DESC LIMIT 1;
+    IF got IS DISTINCT FROM 'update name' THEN
+        RAISE EXCEPTION 'change logged for a subject rename: %', got;
+    END IF;
+END
+$$;
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// CalendarFeed is a user's subscribable iCalendar feed. Calendar clients
+// cannot send a Bearer header, so the feed is addressed by a secret token
+// in its URL; only the token's SHA-256 hash is stored.
+type CalendarFeed struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex"`
+	TokenHash string    `json:"-" gorm:"uniqueIndex;size:64"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
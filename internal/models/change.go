@@ -0,0 +1,41 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Resources of the change log, the sync protocol and the trash bin; they
+// are the table names.
+const (
+	ResourceSubjects  = "subjects"
+	ResourceTasks     = "tasks"
+	ResourceDeadlines = "deadlines"
+)
+
+// Change ops, as written by the record_change trigger.
+const (
+	ChangeInsert = "insert"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// Change is an entry of the change log that /sync reads. Resource is the
+// table name; Fields lists the columns an update changed, comma-separated.
+type Change struct {
+	Seq        uint64    `json:"seq" gorm:"primaryKey"`
+	UserID     uint      `json:"user_id"`
+	Resource   string    `json:"resource"`
+	ResourceID uint      `json:"resource_id"`
+	Op         string    `json:"op"`
+	Fields     string    `json:"fields"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// FieldList returns the columns an update changed.
+func (c Change) FieldList() []string {
+	if c.Fields == "" {
+		return nil
+	}
+	return strings.Split(c.Fields, ",")
+}
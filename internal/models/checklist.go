@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// ChecklistItem is a step of a task too small to be a subtask. Items are
+// listed by position, then in the order they were added.
+type ChecklistItem struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TaskID    uint      `json:"task_id" gorm:"index" example:"7"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	Title     string    `json:"title" gorm:"not null" example:"Write the introduction"`
+	Done      bool      `json:"done" gorm:"not null;default:false"`
+	Position  int       `json:"position" gorm:"not null;default:0" example:"0"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChecklistItemPatch is the writable part of a checklist item.
+type ChecklistItemPatch struct {
+	Title    string `json:"title" binding:"required,max=200" example:"Write the introduction"`
+	Done     bool   `json:"done" example:"true"`
+	Position int    `json:"position" binding:"min=0" example:"0"`
+}
+
+func NewChecklistItemPatch(i ChecklistItem) ChecklistItemPatch {
+	return ChecklistItemPatch{Title: i.Title, Done: i.Done, Position: i.Position}
+}
+
+func (p ChecklistItemPatch) Changes(i ChecklistItem) map[string]interface{} {
+	c := map[string]interface{}{}
+	if p.Title != i.Title {
+		c["title"] = p.Title
+	}
+	if p.Done != i.Done {
+		c["done"] = p.Done
+	}
+	if p.Position != i.Position {
+		c["position"] = p.Position
+	}
+	return c
+}
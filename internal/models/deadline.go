@@ -1,13 +1,19 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Deadline struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	TaskID    uint      `json:"task_id"`
-	Task      Task      `json:"task" gorm:"constraint:OnDelete:CASCADE"`
-	UserID    uint      `json:"user_id"`
-	User      User      `json:"user"`
-	DueDate   time.Time `json:"due_date"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	TaskID    uint           `json:"task_id"`
+	Task      Task           `json:"task" gorm:"constraint:OnDelete:CASCADE"`
+	UserID    uint           `json:"user_id"`
+	User      User           `json:"user"`
+	DueDate   time.Time      `json:"due_date"`
+	CreatedAt time.Time      `json:"created_at"`
+	Version   uint           `json:"version" gorm:"not null;default:1"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
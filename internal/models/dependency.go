@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// TaskDependency records that a task cannot be done before its blocker.
+// Both tasks belong to the same user.
+type TaskDependency struct {
+	TaskID    uint      `json:"task_id" gorm:"primaryKey;autoIncrement:false" example:"7"`
+	BlockerID uint      `json:"blocker_id" gorm:"primaryKey;autoIncrement:false;index" example:"5"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DependencyRequest struct {
+	BlockerID uint `json:"blocker_id" binding:"required" example:"5"`
+}
+
+// GraphNode is a task in the dependency graph. The schedule fields are set
+// for open tasks: the earliest they can be finished when every task takes
+// its estimate and work starts now, and how much time that leaves before
+// the deadline.
+type GraphNode struct {
+	ID              uint       `json:"id" example:"7"`
+	Title           string     `json:"title" example:"Final essay"`
+	Status          string     `json:"status" example:"todo"`
+	Deadline        time.Time  `json:"deadline"`
+	EstimateMinutes int        `json:"estimate_minutes" example:"240"`
+	Open            bool       `json:"open"`
+	EarliestFinish  *time.Time `json:"earliest_finish,omitempty"`
+	SlackMinutes    *int       `json:"slack_minutes,omitempty" example:"-90"`
+	Late            bool       `json:"late"`
+	Critical        bool       `json:"critical"`
+}
+
+// DependencyGraph is a user's tasks that block or are blocked by others.
+// CriticalPath is the chain of open tasks, first blocker first, that leaves
+// the least time before a deadline; Late is set when some chain cannot
+// finish in time.
+type DependencyGraph struct {
+	Nodes        []GraphNode      `json:"nodes"`
+	Edges        []TaskDependency `json:"edges"`
+	CriticalPath []uint           `json:"critical_path" example:"5,6,7"`
+	Late         bool             `json:"late"`
+}
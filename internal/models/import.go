@@ -0,0 +1,29 @@
+package models
+
+// Import actions reported for each calendar entry.
+const (
+	ImportCreate = "create"
+	ImportUpdate = "update"
+	ImportSkip   = "skip"
+)
+
+// ImportItem describes what an import did, or would do in a dry run, with
+// one VEVENT or VTODO.
+type ImportItem struct {
+	UID     string `json:"uid" example:"assignment-42@lms.example.edu"`
+	Kind    string `json:"kind" example:"VEVENT"`
+	Title   string `json:"title" example:"Homework 3"`
+	Subject string `json:"subject,omitempty" example:"Linear Algebra"`
+	Action  string `json:"action" example:"create"`
+	Reason  string `json:"reason,omitempty" example:"unchanged"`
+	TaskID  uint   `json:"task_id,omitempty" example:"12"`
+}
+
+type ImportResult struct {
+	DryRun          bool         `json:"dry_run"`
+	Created         int          `json:"created"`
+	Updated         int          `json:"updated"`
+	Skipped         int          `json:"skipped"`
+	SubjectsCreated []string     `json:"subjects_created"`
+	Items           []ImportItem `json:"items"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+
+	ChannelEmail = "email"
+)
+
+// Notification records one delivery of a reminder for a deadline over a
+// channel at a given offset before the due date. The unique index makes
+// every (deadline, channel, offset) delivered at most once.
+type Notification struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	DeadlineID    uint       `json:"deadline_id" gorm:"uniqueIndex:idx_notification_delivery"`
+	Deadline      Deadline   `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	UserID        uint       `json:"user_id" gorm:"index"`
+	Channel       string     `json:"channel" gorm:"uniqueIndex:idx_notification_delivery" example:"email"`
+	OffsetMinutes int        `json:"offset_minutes" gorm:"uniqueIndex:idx_notification_delivery" example:"15"`
+	Status        string     `json:"status" gorm:"index" example:"sent"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
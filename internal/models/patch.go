@@ -0,0 +1,98 @@
+package models
+
+import (
+	"reflect"
+	"time"
+)
+
+// TaskPatch is the writable part of a task. PATCH and PUT requests are
+// applied to it, so ownership, ids, timestamps and series bookkeeping can
+// never be changed through the API.
+type TaskPatch struct {
+	Title           string    `json:"title" binding:"required,max=200" example:"Finish Go backend"`
+	Description     string    `json:"description" binding:"max=5000" example:"Implement CRUD with JWT"`
+	Status          string    `json:"status" binding:"max=40" example:"in-progress"`
+	Deadline        time.Time `json:"deadline" example:"2025-12-01T12:00:00Z"`
+	EstimateMinutes int       `json:"estimate_minutes" binding:"min=0,max=525600" example:"120"`
+	SubjectID       uint      `json:"subject_id" binding:"required" example:"1"`
+	RecurrenceRule  string    `json:"recurrence_rule" binding:"max=200" example:"FREQ=WEEKLY;BYDAY=MO"`
+}
+
+// NewTaskPatch returns the current writable fields of t. The status is
+// checked against the workflow of the task's subject when it changes.
+func NewTaskPatch(t Task) TaskPatch {
+	return TaskPatch{
+		Title:           t.Title,
+		Description:     t.Description,
+		Status:          t.Status,
+		Deadline:        t.Deadline,
+		EstimateMinutes: t.EstimateMinutes,
+		SubjectID:       t.SubjectID,
+		RecurrenceRule:  t.RecurrenceRule,
+	}
+}
+
+// Changes returns the columns that differ between the patched fields and t.
+func (p TaskPatch) Changes(t Task) map[string]interface{} {
+	c := map[string]interface{}{}
+	if p.Title != t.Title {
+		c["title"] = p.Title
+	}
+	if p.Description != t.Description {
+		c["description"] = p.Description
+	}
+	if p.Status != t.Status {
+		c["status"] = p.Status
+	}
+	if !p.Deadline.Equal(t.Deadline) {
+		c["deadline"] = p.Deadline
+	}
+	if p.EstimateMinutes != t.EstimateMinutes {
+		c["estimate_minutes"] = p.EstimateMinutes
+	}
+	if p.SubjectID != t.SubjectID {
+		c["subject_id"] = p.SubjectID
+	}
+	if p.RecurrenceRule != t.RecurrenceRule {
+		c["recurrence_rule"] = p.RecurrenceRule
+	}
+	return c
+}
+
+// SubjectPatch is the writable part of a subject.
+type SubjectPatch struct {
+	Name        string    `json:"name" binding:"required,max=100" example:"Linear Algebra"`
+	Description string    `json:"description" binding:"max=5000" example:"MATH 201, spring term"`
+	Workflow    *Workflow `json:"workflow"`
+}
+
+func NewSubjectPatch(s Subject) SubjectPatch {
+	return SubjectPatch{Name: s.Name, Description: s.Description, Workflow: s.Workflow}
+}
+
+// Validate checks the custom workflow, if any.
+func (p SubjectPatch) Validate() error {
+	if p.Workflow == nil {
+		return nil
+	}
+	return p.Workflow.Validate()
+}
+
+func (p SubjectPatch) Changes(s Subject) map[string]interface{} {
+	c := map[string]interface{}{}
+	if p.Name != s.Name {
+		c["name"] = p.Name
+	}
+	if p.Description != s.Description {
+		c["description"] = p.Description
+	}
+	if !reflect.DeepEqual(p.Workflow, s.Workflow) {
+		if p.Workflow == nil {
+			// a typed nil would leave the column alone
+			c["workflow"] = nil
+		} else {
+			c["workflow"] = p.Workflow
+		}
+	}
+	return c
+}
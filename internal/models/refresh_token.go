@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// RefreshToken is the server-side record of an issued refresh token. Only
+// a SHA-256 hash of the token is stored. Tokens created by rotating one
+// another share a FamilyID, so the whole chain can be revoked at once
+// when a used token is presented again.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"index"`
+	FamilyID  string     `gorm:"index;size:64"`
+	TokenHash string     `gorm:"uniqueIndex;size:64"`
+	ExpiresAt time.Time  `gorm:"index"`
+	UsedAt    *time.Time // set when rotated into a new token
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenResponse struct {
+	Token        string `json:"token"` // same as AccessToken, kept for older clients
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int64  `json:"expires_in" example:"900"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// DefaultReminderOffsets is used for users who never set preferences:
+// a single reminder 15 minutes before the deadline.
+var DefaultReminderOffsets = []int{15}
+
+const MaxReminderOffsetMinutes = 60 * 24 * 30 // 30 days
+
+// ReminderOffset says "remind me OffsetMinutes before the due date".
+// Rows without a DeadlineID are the user's default schedule; rows with one
+// override the defaults for that deadline only.
+type ReminderOffset struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	UserID        uint      `json:"user_id" gorm:"index"`
+	DeadlineID    *uint     `json:"deadline_id,omitempty" gorm:"index;uniqueIndex:idx_reminder_offsets_deadline_offset"`
+	Deadline      *Deadline `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	OffsetMinutes int       `json:"offset_minutes" gorm:"uniqueIndex:idx_reminder_offsets_deadline_offset" example:"1440"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ReminderOffsetRequest struct {
+	OffsetMinutes int `json:"offset_minutes" binding:"required,min=1,max=43200" example:"60"`
+}
+
+type ReminderScheduleRequest struct {
+	OffsetsMinutes []int `json:"offsets_minutes" binding:"max=10,dive,min=1,max=43200" example:"4320,1440,60"`
+}
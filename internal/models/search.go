@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SearchHit is a subject, task or deadline found by a full-text search.
+// Deadlines are found by the text of their task. Snippet is the matching
+// text with the words that matched between <mark> and </mark>.
+type SearchHit struct {
+	Type    string     `json:"type" example:"tasks"`
+	ID      uint       `json:"id" example:"3"`
+	Title   string     `json:"title" example:"Essay on Kant"`
+	Snippet string     `json:"snippet" example:"Compare <mark>Kant</mark> and Hume"`
+	Rank    float64    `json:"rank" example:"0.61"`
+	TaskID  *uint      `json:"task_id,omitempty" example:"3"`
+	DueDate *time.Time `json:"due_date,omitempty"`
+}
@@ -1,10 +1,20 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Subject struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	UserID      uint           `json:"user_id" gorm:"index"`
+	CreatedAt   time.Time      `json:"created_at"`
+	Version     uint           `json:"version" gorm:"not null;default:1"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Workflow replaces the default task workflow for the subject's tasks.
+	Workflow *Workflow `json:"workflow,omitempty" gorm:"type:jsonb"`
 }
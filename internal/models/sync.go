@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Ops a client can send for a resource.
+const (
+	SyncCreate = "create"
+	SyncUpdate = "update"
+	SyncDelete = "delete"
+)
+
+// SyncRequest is a client's offline change set together with the token
+// returned by its previous sync. Without a token the server sends a full
+// copy of the user's data.
+type SyncRequest struct {
+	Token   string       `json:"token" example:"1042"`
+	Changes []SyncChange `json:"changes"`
+}
+
+// SyncChange is one offline edit. Fields holds the writable fields that
+// were set, as for PATCH. A create names the new row with ClientID, which
+// later changes in the same request may use in place of a subject_id or
+// task_id.
+type SyncChange struct {
+	Resource  string                     `json:"resource" example:"tasks"`
+	Op        string                     `json:"op" example:"update"`
+	ID        uint                       `json:"id,omitempty" example:"12"`
+	ClientID  string                     `json:"client_id,omitempty" example:"local-7"`
+	Fields    map[string]json.RawMessage `json:"fields,omitempty" swaggertype:"object"`
+	ChangedAt time.Time                  `json:"changed_at" example:"2025-11-03T08:15:00Z"`
+}
+
+// SyncCreated maps the ClientID of a created row to its id.
+type SyncCreated struct {
+	Resource string `json:"resource"`
+	ClientID string `json:"client_id"`
+	ID       uint   `json:"id"`
+}
+
+// SyncConflict reports a field that both the client and the server changed
+// since the client's last sync; the later change won. Field is empty when
+// the conflict is about the whole row: an update of a row the server
+// deleted, or a delete of a row the server changed afterwards.
+type SyncConflict struct {
+	Resource    string      `json:"resource"`
+	ID          uint        `json:"id"`
+	Field       string      `json:"field,omitempty"`
+	ClientValue interface{} `json:"client_value,omitempty"`
+	ServerValue interface{} `json:"server_value,omitempty"`
+	Winner      string      `json:"winner" example:"server"`
+}
+
+// SyncRejected is a change the server could not apply.
+type SyncRejected struct {
+	Resource string `json:"resource"`
+	ID       uint   `json:"id,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	Error    string `json:"error"`
+}
+
+// Tombstone marks a row that was deleted since the client's last sync.
+type Tombstone struct {
+	Resource  string    `json:"resource"`
+	ID        uint      `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// SyncResponse carries what changed on the server since the client's token,
+// the outcome of the client's changes and the token for the next sync.
+// With Full set the lists are a complete copy and the client should drop
+// anything else it holds.
+type SyncResponse struct {
+	Token     string         `json:"token" example:"1057"`
+	Full      bool           `json:"full"`
+	Subjects  []Subject      `json:"subjects"`
+	Tasks     []Task         `json:"tasks"`
+	Deadlines []Deadline     `json:"deadlines"`
+	Deleted   []Tombstone    `json:"deleted"`
+	Created   []SyncCreated  `json:"created"`
+	Conflicts []SyncConflict `json:"conflicts"`
+	Rejected  []SyncRejected `json:"rejected"`
+}
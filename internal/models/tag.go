@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// DefaultTagColor is the colour of tags created without one.
+const DefaultTagColor = "#9e9e9e"
+
+// Tag is a user's label for grouping tasks across subjects. Names are
+// unique per user, ignoring case.
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	Name      string    `json:"name" gorm:"not null" binding:"required,max=50,excludes=," example:"exam"`
+	Color     string    `json:"color" gorm:"not null" binding:"omitempty,hexcolor" example:"#e53935"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TagPatch is the writable part of a tag.
+type TagPatch struct {
+	Name  string `json:"name" binding:"required,max=50,excludes=," example:"exam"`
+	Color string `json:"color" binding:"required,hexcolor" example:"#e53935"`
+}
+
+func NewTagPatch(t Tag) TagPatch {
+	return TagPatch{Name: t.Name, Color: t.Color}
+}
+
+func (p TagPatch) Changes(t Tag) map[string]interface{} {
+	c := map[string]interface{}{}
+	if p.Name != t.Name {
+		c["name"] = p.Name
+	}
+	if p.Color != t.Color {
+		c["color"] = p.Color
+	}
+	return c
+}
+
+// TaskTagsRequest tags or untags a task in bulk.
+type TaskTagsRequest struct {
+	TagIDs []uint `json:"tag_ids" binding:"required,min=1,max=50" example:"1,2"`
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Task struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
@@ -8,7 +12,46 @@ type Task struct {
 	Description string    `json:"description" example:"Implement CRUD with JWT"`
 	Status      string    `json:"status" example:"in-progress"`
 	Deadline    time.Time `json:"deadline" example:"2025-12-01T12:00:00Z"`
-	SubjectID   uint      `json:"subject_id" example:"1"`
-	Subject     Subject   `json:"subject" gorm:"foreignKey:SubjectID"` // <- add this
-	CreatedAt   time.Time `json:"created_at"`
+
+	// EstimateMinutes is how long the student expects the task to take;
+	// the dependency graph plans with it.
+	EstimateMinutes int `json:"estimate_minutes" gorm:"not null;default:0" example:"120"`
+
+	// ParentID makes the task a subtask of another task of the same user.
+	// Progress is how much of a task with subtasks or checklist items is
+	// done, in percent; it is worked out when the task is read.
+	ParentID *uint `json:"parent_id,omitempty" gorm:"index" example:"3"`
+	Progress *int  `json:"progress,omitempty" gorm:"-" example:"50"`
+
+	SubjectID uint      `json:"subject_id" example:"1"`
+	Subject   Subject   `json:"subject" gorm:"foreignKey:SubjectID"` // <- add this
+	Tags      []Tag     `json:"tags" gorm:"many2many:task_tags"`
+	UserID    uint      `json:"user_id" gorm:"index;uniqueIndex:idx_task_external_uid"`
+	CreatedAt time.Time `json:"created_at"`
+	Version   uint      `json:"version" gorm:"not null;default:1"`
+
+	// StartedAt is when work on the task started and CompletedAt when it
+	// was done; both follow the kind of the task's status.
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	// DeletedAt is set while the task is in the trash.
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// ExternalUID is the iCalendar UID of an imported task, used to match
+	// re-imports of the same calendar.
+	ExternalUID *string `json:"external_uid,omitempty" gorm:"uniqueIndex:idx_task_external_uid,where:deleted_at IS NULL"`
+
+	// Recurring tasks: the first task of a series carries the RRULE and
+	// acts as the template, with Deadline as the series start. Generated
+	// occurrences point back to it through SeriesID.
+	RecurrenceRule           string     `json:"recurrence_rule,omitempty" example:"FREQ=WEEKLY;BYDAY=MO"`
+	SeriesID                 *uint      `json:"series_id,omitempty" gorm:"uniqueIndex:idx_task_occurrence"`
+	OccurrenceAt             *time.Time `json:"occurrence_at,omitempty" gorm:"uniqueIndex:idx_task_occurrence"`
+	RecurrenceGeneratedUntil *time.Time `json:"-"`
+}
+
+// IsSeriesMaster reports whether the task defines a recurring series.
+func (t Task) IsSeriesMaster() bool {
+	return t.RecurrenceRule != ""
 }
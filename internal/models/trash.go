@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// TrashItem is a deleted subject, task or deadline that can still be
+// restored. Rows deleted together with their subject or task are restored
+// with it and are not listed on their own.
+type TrashItem struct {
+	Type      string    `json:"type" example:"subjects"`
+	ID        uint      `json:"id" example:"3"`
+	Title     string    `json:"title" example:"Linear Algebra"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
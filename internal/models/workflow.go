@@ -0,0 +1,220 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Kinds of workflow state. A state's kind decides what entering it does to
+// a task's started_at and completed_at, and how calendars show the task.
+const (
+	KindTodo      = "todo"
+	KindActive    = "active"
+	KindBlocked   = "blocked"
+	KindDone      = "done"
+	KindCancelled = "cancelled"
+)
+
+var workflowKinds = []string{KindTodo, KindActive, KindBlocked, KindDone, KindCancelled}
+
+// Limits on custom workflows.
+const (
+	maxWorkflowStates = 20
+	maxStateName      = 40
+)
+
+type WorkflowState struct {
+	Name string `json:"name" example:"submitted"`
+	Kind string `json:"kind" example:"active"`
+}
+
+type WorkflowTransition struct {
+	From string `json:"from" example:"draft"`
+	To   string `json:"to" example:"submitted"`
+}
+
+// Workflow is the set of statuses a task can have and the moves allowed
+// between them. New tasks start in the first state.
+type Workflow struct {
+	States      []WorkflowState      `json:"states"`
+	Transitions []WorkflowTransition `json:"transitions"`
+}
+
+// DefaultWorkflow applies to the tasks of subjects without a workflow of
+// their own.
+var DefaultWorkflow = Workflow{
+	States: []WorkflowState{
+		{"todo", KindTodo},
+		{"in-progress", KindActive},
+		{"blocked", KindBlocked},
+		{"done", KindDone},
+		{"cancelled", KindCancelled},
+	},
+	Transitions: []WorkflowTransition{
+		{"todo", "in-progress"},
+		{"todo", "blocked"},
+		{"todo", "done"},
+		{"todo", "cancelled"},
+		{"in-progress", "todo"},
+		{"in-progress", "blocked"},
+		{"in-progress", "done"},
+		{"in-progress", "cancelled"},
+		{"blocked", "todo"},
+		{"blocked", "in-progress"},
+		{"blocked", "cancelled"},
+		{"done", "todo"},
+		{"done", "in-progress"},
+		{"cancelled", "todo"},
+	},
+}
+
+// TaskWorkflow returns the workflow of the subject's tasks.
+func (s Subject) TaskWorkflow() Workflow {
+	if s.Workflow == nil {
+		return DefaultWorkflow
+	}
+	return *s.Workflow
+}
+
+// IsOpen reports whether the task still needs work: it is neither done nor
+// cancelled. The task's subject must be loaded.
+func (t Task) IsOpen() bool {
+	if t.CompletedAt != nil {
+		return false
+	}
+	state, ok := t.Subject.TaskWorkflow().State(t.Status)
+	if !ok {
+		return t.Status != "done"
+	}
+	return state.Kind != KindDone && state.Kind != KindCancelled
+}
+
+// IsCancelled reports whether the task's status is of the cancelled kind.
+// The task's subject must be loaded.
+func (t Task) IsCancelled() bool {
+	state, ok := t.Subject.TaskWorkflow().State(t.Status)
+	return ok && state.Kind == KindCancelled
+}
+
+// Initial returns the status new tasks start with.
+func (w Workflow) Initial() string {
+	if len(w.States) == 0 {
+		return ""
+	}
+	return w.States[0].Name
+}
+
+// State returns the state called name.
+func (w Workflow) State(name string) (WorkflowState, bool) {
+	for _, s := range w.States {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return WorkflowState{}, false
+}
+
+// FirstOfKind returns the first state of the given kind.
+func (w Workflow) FirstOfKind(kind string) (WorkflowState, bool) {
+	for _, s := range w.States {
+		if s.Kind == kind {
+			return s, true
+		}
+	}
+	return WorkflowState{}, false
+}
+
+// Allows reports whether a task can move from one status to another. A task
+// whose status is not in the workflow, for example after it moved to a
+// subject with a different workflow, can move to any state.
+func (w Workflow) Allows(from, to string) bool {
+	if _, ok := w.State(to); !ok {
+		return false
+	}
+	if _, ok := w.State(from); !ok {
+		return true
+	}
+	for _, t := range w.Transitions {
+		if t.From == from && t.To == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the statuses a task can move to from the given one.
+func (w Workflow) Next(from string) []string {
+	next := []string{}
+	for _, s := range w.States {
+		if s.Name != from && w.Allows(from, s.Name) {
+			next = append(next, s.Name)
+		}
+	}
+	return next
+}
+
+// Validate checks that the workflow is well formed: named states of known
+// kinds, each named once, and transitions between them.
+func (w Workflow) Validate() error {
+	if len(w.States) == 0 {
+		return errors.New("workflow: at least one state is required")
+	}
+	if len(w.States) > maxWorkflowStates {
+		return fmt.Errorf("workflow: at most %d states are allowed", maxWorkflowStates)
+	}
+	seen := map[string]bool{}
+	for _, s := range w.States {
+		name := strings.TrimSpace(s.Name)
+		if name == "" || name != s.Name || len(name) > maxStateName {
+			return fmt.Errorf("workflow: state name %q must be 1 to %d characters without surrounding spaces", s.Name, maxStateName)
+		}
+		if seen[name] {
+			return fmt.Errorf("workflow: state %q is listed twice", name)
+		}
+		seen[name] = true
+		if !slices.Contains(workflowKinds, s.Kind) {
+			return fmt.Errorf("workflow: state %q has kind %q, want one of %s", name, s.Kind, strings.Join(workflowKinds, ", "))
+		}
+	}
+	for _, t := range w.Transitions {
+		if !seen[t.From] || !seen[t.To] {
+			return fmt.Errorf("workflow: transition %q -> %q names an unknown state", t.From, t.To)
+		}
+		if t.From == t.To {
+			return fmt.Errorf("workflow: transition %q -> %q goes nowhere", t.From, t.To)
+		}
+	}
+	return nil
+}
+
+// Value stores a workflow as JSON.
+func (w Workflow) Value() (driver.Value, error) {
+	b, err := json.Marshal(w)
+	return string(b), err
+}
+
+func (w *Workflow) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, w)
+	case string:
+		return json.Unmarshal([]byte(v), w)
+	}
+	return fmt.Errorf("workflow: cannot scan %T", src)
+}
+
+// TransitionRequest moves a task to another status.
+type TransitionRequest struct {
+	To string `json:"to" binding:"required,max=40" example:"done"`
+}
+
+// TaskTransitions is a task's status and where it can go from there.
+type TaskTransitions struct {
+	Status   string   `json:"status" example:"in-progress"`
+	Next     []string `json:"next" example:"todo,blocked,done,cancelled"`
+	Workflow Workflow `json:"workflow"`
+}
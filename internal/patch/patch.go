@@ -0,0 +1,346 @@
+// Package patch applies JSON Merge Patch (RFC 7396) and JSON Patch
+// (RFC 6902) documents to the JSON form of a resource, and decodes the
+// result into a typed DTO that only has the writable fields.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+var (
+	// ErrUnsupportedMediaType is returned for bodies that are neither kind
+	// of patch.
+	ErrUnsupportedMediaType = errors.New("patch: use application/merge-patch+json or application/json-patch+json")
+	// ErrTestFailed is returned when a JSON Patch "test" operation fails.
+	ErrTestFailed = errors.New("patch: test operation failed")
+)
+
+// Apply patches doc with body according to the request content type. Plain
+// application/json is treated as a merge patch, which is what clients that
+// send a partial object expect.
+func Apply(contentType string, doc, body []byte) ([]byte, error) {
+	switch contentType {
+	case MergePatchType, "application/json", "":
+		return MergePatch(doc, body)
+	case JSONPatchType:
+		return JSONPatch(doc, body)
+	}
+	return nil, ErrUnsupportedMediaType
+}
+
+// MergePatch applies an RFC 7396 merge patch: members of the patch replace
+// those of doc, null removes a member, and nested objects merge.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("patch: invalid merge patch: %w", err)
+	}
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = merge(t[k], v)
+	}
+	return t
+}
+
+// Operation is one step of an RFC 6902 JSON Patch.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"` // "null" for JSON null, empty when left out
+}
+
+// JSONPatch applies an RFC 6902 patch. The operations apply in order and
+// the patch fails as a whole if any of them does.
+func JSONPatch(doc, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("patch: invalid JSON patch: %w", err)
+	}
+
+	for i, op := range ops {
+		var err error
+		if target, err = apply(target, op); err != nil {
+			if errors.Is(err, ErrTestFailed) {
+				return nil, fmt.Errorf("%w: operation %d (%s)", ErrTestFailed, i, op.Path)
+			}
+			return nil, fmt.Errorf("patch: operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func apply(doc interface{}, op Operation) (interface{}, error) {
+	value := func() (interface{}, error) {
+		if len(op.Value) == 0 {
+			return nil, errors.New("value is required")
+		}
+		var v interface{}
+		err := json.Unmarshal(op.Value, &v)
+		return v, err
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, v)
+	case "remove":
+		doc, _, err := remove(doc, op.Path)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if doc, _, err = remove(doc, op.Path); err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, v)
+	case "move":
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		doc, v, err := remove(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, v)
+	case "copy":
+		v, err := get(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, clone(v))
+	case "test":
+		want, err := value()
+		if err != nil {
+			return nil, err
+		}
+		got, err := get(doc, op.Path)
+		if err != nil {
+			return nil, ErrTestFailed
+		}
+		if !equal(got, want) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", op.Op)
+}
+
+// pointer splits an RFC 6901 JSON pointer into unescaped reference tokens.
+func pointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func index(token string, n int, appending bool) (int, error) {
+	if appending && token == "-" {
+		return n, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	limit := n - 1
+	if appending {
+		limit = n
+	}
+	if i > limit {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+	return i, nil
+}
+
+func get(doc interface{}, path string) (interface{}, error) {
+	tokens, err := pointer(path)
+	if err != nil {
+		return nil, err
+	}
+	cur := doc
+	for _, t := range tokens {
+		switch c := cur.(type) {
+		case map[string]interface{}:
+			v, ok := c[t]
+			if !ok {
+				return nil, fmt.Errorf("path %q does not exist", path)
+			}
+			cur = v
+		case []interface{}:
+			i, err := index(t, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			cur = c[i]
+		default:
+			return nil, fmt.Errorf("path %q does not exist", path)
+		}
+	}
+	return cur, nil
+}
+
+// parent returns the container that the last token of path refers into.
+func parent(doc interface{}, path string) (interface{}, string, error) {
+	tokens, err := pointer(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(tokens) == 0 {
+		return nil, "", nil
+	}
+	// reference tokens never contain a raw "/", so the last one starts
+	// after the last slash
+	p, err := get(doc, path[:strings.LastIndex(path, "/")])
+	return p, tokens[len(tokens)-1], err
+}
+
+// add inserts v at path and returns the new document; arrays are values in
+// Go, so the container is written back up the tree.
+func add(doc interface{}, path string, v interface{}) (interface{}, error) {
+	if path == "" {
+		return v, nil
+	}
+	p, key, err := parent(doc, path)
+	if err != nil {
+		return nil, err
+	}
+	switch c := p.(type) {
+	case map[string]interface{}:
+		c[key] = v
+		return doc, nil
+	case []interface{}:
+		i, err := index(key, len(c), true)
+		if err != nil {
+			return nil, err
+		}
+		c = append(c, nil)
+		copy(c[i+1:], c[i:])
+		c[i] = v
+		return replaceAt(doc, path[:strings.LastIndex(path, "/")], c)
+	}
+	return nil, fmt.Errorf("path %q does not exist", path)
+}
+
+func remove(doc interface{}, path string) (interface{}, interface{}, error) {
+	if path == "" {
+		return nil, doc, nil
+	}
+	p, key, err := parent(doc, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch c := p.(type) {
+	case map[string]interface{}:
+		v, ok := c[key]
+		if !ok {
+			return nil, nil, fmt.Errorf("path %q does not exist", path)
+		}
+		delete(c, key)
+		return doc, v, nil
+	case []interface{}:
+		i, err := index(key, len(c), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		v := c[i]
+		c = append(c[:i:i], c[i+1:]...)
+		doc, err = replaceAt(doc, path[:strings.LastIndex(path, "/")], c)
+		return doc, v, err
+	}
+	return nil, nil, fmt.Errorf("path %q does not exist", path)
+}
+
+// replaceAt stores v at an existing path.
+func replaceAt(doc interface{}, path string, v interface{}) (interface{}, error) {
+	if path == "" {
+		return v, nil
+	}
+	p, key, err := parent(doc, path)
+	if err != nil {
+		return nil, err
+	}
+	switch c := p.(type) {
+	case map[string]interface{}:
+		c[key] = v
+		return doc, nil
+	case []interface{}:
+		i, err := index(key, len(c), false)
+		if err != nil {
+			return nil, err
+		}
+		c[i] = v
+		return doc, nil
+	}
+	return nil, fmt.Errorf("path %q does not exist", path)
+}
+
+func clone(v interface{}) interface{} {
+	b, _ := json.Marshal(v)
+	var out interface{}
+	json.Unmarshal(b, &out)
+	return out
+}
+
+func equal(a, b interface{}) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return bytes.Equal(x, y)
+}
+
+// Decode unmarshals a patched document into dst, rejecting members that
+// dst does not declare, so only whitelisted fields can change.
+func Decode(doc []byte, dst interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return fmt.Errorf("field %s cannot be changed", name)
+		}
+		return err
+	}
+	return nil
+}
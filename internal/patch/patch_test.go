@@ -0,0 +1,206 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func sameJSON(t *testing.T, got []byte, want string) bool {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("result %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("want %s: %v", want, err)
+	}
+	return reflect.DeepEqual(g, w)
+}
+
+// The examples of RFC 6902 appendix A, and null values.
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name, doc, patch string
+		want             string // "" when the patch fails
+	}{
+		{"A.1 adding an object member",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux"}]`,
+			`{"baz":"qux","foo":"bar"}`},
+		{"A.2 adding an array element",
+			`{"foo":["bar","baz"]}`,
+			`[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			`{"foo":["bar","qux","baz"]}`},
+		{"A.3 removing an object member",
+			`{"baz":"qux","foo":"bar"}`,
+			`[{"op":"remove","path":"/baz"}]`,
+			`{"foo":"bar"}`},
+		{"A.4 removing an array element",
+			`{"foo":["bar","qux","baz"]}`,
+			`[{"op":"remove","path":"/foo/1"}]`,
+			`{"foo":["bar","baz"]}`},
+		{"A.5 replacing a value",
+			`{"baz":"qux","foo":"bar"}`,
+			`[{"op":"replace","path":"/baz","value":"boo"}]`,
+			`{"baz":"boo","foo":"bar"}`},
+		{"A.6 moving a value",
+			`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"A.7 moving an array element",
+			`{"foo":["all","grass","cows","eat"]}`,
+			`[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			`{"foo":["all","cows","eat","grass"]}`},
+		{"A.8 testing a value: success",
+			`{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`},
+		{"A.9 testing a value: error",
+			`{"baz":"qux"}`,
+			`[{"op":"test","path":"/baz","value":"bar"}]`,
+			``},
+		{"A.10 adding a nested member object",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+			`{"foo":"bar","child":{"grandchild":{}}}`},
+		{"A.11 ignoring unrecognized elements",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux","xyz":123}]`,
+			`{"foo":"bar","baz":"qux"}`},
+		{"A.12 adding to a nonexistent target",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			``},
+		// encoding/json keeps the last of the duplicate members, so this
+		// fails as a remove of a member that is not there
+		{"A.13 invalid JSON patch document",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux","op":"remove"}]`,
+			``},
+		{"A.14 ~ escape ordering",
+			`{"/":9,"~1":10}`,
+			`[{"op":"test","path":"/~01","value":10}]`,
+			`{"/":9,"~1":10}`},
+		{"A.15 comparing strings and numbers",
+			`{"/":9,"~1":10}`,
+			`[{"op":"test","path":"/~01","value":"10"}]`,
+			``},
+		{"A.16 adding an array value",
+			`{"foo":["bar"]}`,
+			`[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			`{"foo":["bar",["abc","def"]]}`},
+
+		{"replacing with null",
+			`{"parent_id":3,"title":"Essay"}`,
+			`[{"op":"replace","path":"/parent_id","value":null}]`,
+			`{"parent_id":null,"title":"Essay"}`},
+		{"adding null",
+			`{"title":"Essay"}`,
+			`[{"op":"add","path":"/deadline","value":null}]`,
+			`{"deadline":null,"title":"Essay"}`},
+		{"testing for null",
+			`{"parent_id":null}`,
+			`[{"op":"test","path":"/parent_id","value":null}]`,
+			`{"parent_id":null}`},
+		{"replacing without a value",
+			`{"title":"Essay"}`,
+			`[{"op":"replace","path":"/title"}]`,
+			``},
+		{"moving into a child",
+			`{"a":{"b":{}}}`,
+			`[{"op":"move","from":"/a","path":"/a/b/c"}]`,
+			``},
+		{"index with a leading zero",
+			`{"foo":["a","b"]}`,
+			`[{"op":"remove","path":"/foo/01"}]`,
+			``},
+		{"failing as a whole",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":1},{"op":"remove","path":"/nothing"}]`,
+			``},
+	}
+	for _, tt := range tests {
+		got, err := JSONPatch([]byte(tt.doc), []byte(tt.patch))
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: got %s, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !sameJSON(t, got, tt.want) {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestJSONPatchTestFailed(t *testing.T) {
+	_, err := JSONPatch([]byte(`{"baz":"qux"}`), []byte(`[{"op":"test","path":"/baz","value":"bar"}]`))
+	if !errors.Is(err, ErrTestFailed) {
+		t.Errorf("got %v, want ErrTestFailed", err)
+	}
+}
+
+// The examples of RFC 7396 appendix A.
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("MergePatch(%s, %s): %v", tt.doc, tt.patch, err)
+			continue
+		}
+		if !sameJSON(t, got, tt.want) {
+			t.Errorf("MergePatch(%s, %s) = %s, want %s", tt.doc, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	doc := []byte(`{"title":"Essay"}`)
+	if got, err := Apply("application/json", doc, []byte(`{"title":"Draft"}`)); err != nil || !sameJSON(t, got, `{"title":"Draft"}`) {
+		t.Errorf("Apply of plain JSON = %s, %v, want a merge patch", got, err)
+	}
+	if got, err := Apply(JSONPatchType, doc, []byte(`[{"op":"replace","path":"/title","value":"Draft"}]`)); err != nil || !sameJSON(t, got, `{"title":"Draft"}`) {
+		t.Errorf("Apply of a JSON patch = %s, %v", got, err)
+	}
+	if _, err := Apply("text/plain", doc, []byte(`title=Draft`)); !errors.Is(err, ErrUnsupportedMediaType) {
+		t.Errorf("Apply of text/plain: got %v, want ErrUnsupportedMediaType", err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var dst struct {
+		Title string `json:"title"`
+	}
+	if err := Decode([]byte(`{"title":"Essay"}`), &dst); err != nil || dst.Title != "Essay" {
+		t.Errorf("Decode = %+v, %v", dst, err)
+	}
+	err := Decode([]byte(`{"title":"Essay","user_id":5}`), &dst)
+	if err == nil || err.Error() != `field "user_id" cannot be changed` {
+		t.Errorf("Decode of a field that is not writable: got %v", err)
+	}
+}
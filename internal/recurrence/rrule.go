@@ -0,0 +1,310 @@
+// Package recurrence implements the subset of RFC 5545 recurrence rules
+// StudySync supports: FREQ, INTERVAL, BYDAY, COUNT and UNTIL.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxIterations bounds the expansion of rules that never match, such as
+// a yearly rule starting on 29 February with an UNTIL in the same decade.
+const maxIterations = 100000
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Rule is a parsed recurrence rule. The zero Interval means 1; a zero
+// Count and a zero Until mean the rule has no end.
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []time.Weekday
+	Count    int
+	Until    time.Time
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10".
+// An optional "RRULE:" prefix is accepted.
+func Parse(s string) (Rule, error) {
+	var r Rule
+
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return r, errors.New("rrule: empty rule")
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return r, fmt.Errorf("rrule: malformed part %q", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			switch f := Frequency(strings.ToUpper(value)); f {
+			case Daily, Weekly, Monthly, Yearly:
+				r.Freq = f
+			default:
+				return r, fmt.Errorf("rrule: unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("rrule: INTERVAL must be a positive integer")
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("rrule: COUNT must be a positive integer")
+			}
+			r.Count = n
+		case "UNTIL":
+			t, err := parseUntil(value)
+			if err != nil {
+				return r, err
+			}
+			r.Until = t
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := weekdays[strings.ToUpper(d)]
+				if !ok {
+					return r, fmt.Errorf("rrule: unsupported BYDAY value %q", d)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		default:
+			return r, fmt.Errorf("rrule: unsupported part %q", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return r, errors.New("rrule: FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return r, errors.New("rrule: COUNT and UNTIL are mutually exclusive")
+	}
+	if len(r.ByDay) > 0 && r.Freq != Daily && r.Freq != Weekly {
+		return r, errors.New("rrule: BYDAY is only supported with FREQ=DAILY or FREQ=WEEKLY")
+	}
+	if r.Interval == 0 {
+		r.Interval = 1
+	}
+	r.ByDay = normalizeDays(r.ByDay)
+
+	return r, nil
+}
+
+func parseUntil(v string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			if layout == "20060102" {
+				// a date-only UNTIL includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("rrule: invalid UNTIL %q", v)
+}
+
+// String formats the rule back into RRULE syntax.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, d := range r.ByDay {
+			days = append(days, strings.ToUpper(d.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Iterate calls fn for every occurrence of the rule starting at dtstart, in
+// order, until fn returns false or the rule ends. dtstart is the first
+// occurrence when it matches the rule, as in RFC 5545.
+func (r Rule) Iterate(dtstart time.Time, fn func(time.Time) bool) {
+	interval := max(r.Interval, 1)
+	emitted := 0
+
+	emit := func(t time.Time) bool {
+		if t.Before(dtstart) {
+			return true
+		}
+		if !r.Until.IsZero() && t.After(r.Until) {
+			return false
+		}
+		if r.Count > 0 && emitted >= r.Count {
+			return false
+		}
+		emitted++
+		return fn(t)
+	}
+
+	for k := 0; k < maxIterations; k++ {
+		switch r.Freq {
+		case Daily:
+			t := dtstart.AddDate(0, 0, k*interval)
+			if len(r.ByDay) > 0 && !containsDay(r.ByDay, t.Weekday()) {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+
+		case Weekly:
+			days := r.ByDay
+			if len(days) == 0 {
+				days = []time.Weekday{dtstart.Weekday()}
+			}
+			weekStart := dtstart.AddDate(0, 0, -mondayOffset(dtstart.Weekday())+7*k*interval)
+			for _, d := range days {
+				if !emit(weekStart.AddDate(0, 0, mondayOffset(d))) {
+					return
+				}
+			}
+
+		case Monthly, Yearly:
+			months := k * interval
+			if r.Freq == Yearly {
+				months *= 12
+			}
+			// months that lack the start day (31st, 29 February) are skipped
+			y, m, _ := dtstart.Date()
+			first := time.Date(y, m+time.Month(months), 1,
+				dtstart.Hour(), dtstart.Minute(), dtstart.Second(), dtstart.Nanosecond(), dtstart.Location())
+			t := first.AddDate(0, 0, dtstart.Day()-1)
+			if t.Month() != first.Month() {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+
+		default:
+			return
+		}
+	}
+}
+
+// Between returns the occurrences in the half-open range (after, before].
+func (r Rule) Between(dtstart, after, before time.Time) []time.Time {
+	var out []time.Time
+	r.Iterate(dtstart, func(t time.Time) bool {
+		if t.After(before) {
+			return false
+		}
+		if t.After(after) {
+			out = append(out, t)
+		}
+		return true
+	})
+	return out
+}
+
+// Next returns the first occurrence strictly after the given time.
+func (r Rule) Next(dtstart, after time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	r.Iterate(dtstart, func(t time.Time) bool {
+		if t.After(after) {
+			next, found = t, true
+			return false
+		}
+		return true
+	})
+	return next, found
+}
+
+// CountBefore returns how many occurrences fall strictly before t.
+func (r Rule) CountBefore(dtstart, t time.Time) int {
+	n := 0
+	r.Iterate(dtstart, func(o time.Time) bool {
+		if !o.Before(t) {
+			return false
+		}
+		n++
+		return true
+	})
+	return n
+}
+
+// Truncate returns a copy of the rule that ends before t, keeping
+// COUNT-based rules COUNT-based.
+func (r Rule) Truncate(dtstart, t time.Time) Rule {
+	out := r
+	if r.Count > 0 {
+		out.Count = r.CountBefore(dtstart, t)
+		return out
+	}
+	out.Until = t.Add(-time.Second).UTC()
+	return out
+}
+
+// Remainder returns the rule for the occurrences from t onwards, to be used
+// with t as the new dtstart.
+func (r Rule) Remainder(dtstart, t time.Time) Rule {
+	out := r
+	if r.Count > 0 {
+		out.Count = max(r.Count-r.CountBefore(dtstart, t), 1)
+	}
+	return out
+}
+
+func containsDay(days []time.Weekday, d time.Weekday) bool {
+	for _, x := range days {
+		if x == d {
+			return true
+		}
+	}
+	return false
+}
+
+// mondayOffset is the position of d in a week that starts on Monday.
+func mondayOffset(d time.Weekday) int {
+	return (int(d) + 6) % 7
+}
+
+func normalizeDays(days []time.Weekday) []time.Weekday {
+	seen := map[time.Weekday]bool{}
+	out := days[:0]
+	for _, d := range days {
+		if !seen[d] {
+			seen[d] = true
+			out = append(out, d)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return mondayOffset(out[i]) < mondayOffset(out[j]) })
+	return out
+}
@@ -0,0 +1,183 @@
+package recurrence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d, h int) time.Time {
+	return time.Date(y, m, d, h, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		rule   string
+		want   Rule
+		String string
+	}{
+		{"FREQ=DAILY", Rule{Freq: Daily, Interval: 1}, "FREQ=DAILY"},
+		{"RRULE:freq=weekly;interval=2;byday=we,MO,mo;count=10",
+			Rule{Freq: Weekly, Interval: 2, ByDay: []time.Weekday{time.Monday, time.Wednesday}, Count: 10},
+			"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10"},
+		{"FREQ=WEEKLY;BYDAY=SU,MO", Rule{Freq: Weekly, Interval: 1, ByDay: []time.Weekday{time.Monday, time.Sunday}}, "FREQ=WEEKLY;BYDAY=MO,SU"},
+		{"FREQ=MONTHLY;UNTIL=20300131T090000Z", Rule{Freq: Monthly, Interval: 1, Until: date(2030, 1, 31, 9)}, "FREQ=MONTHLY;UNTIL=20300131T090000Z"},
+		// a date-only UNTIL includes the whole day
+		{"FREQ=YEARLY;UNTIL=20300131", Rule{Freq: Yearly, Interval: 1, Until: time.Date(2030, 1, 31, 23, 59, 59, 0, time.UTC)}, "FREQ=YEARLY;UNTIL=20300131T235959Z"},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.rule)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.rule, err)
+			continue
+		}
+		if !reflect.DeepEqual(r, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.rule, r, tt.want)
+		}
+		if s := r.String(); s != tt.String {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.rule, s, tt.String)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"RRULE:",
+		"FREQ",
+		"FREQ=",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;INTERVAL=x",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;COUNT=2;UNTIL=20300101",
+		"FREQ=DAILY;BYDAY=XX",
+		"FREQ=MONTHLY;BYDAY=MO",
+		"FREQ=DAILY;BYSETPOS=1",
+	} {
+		if r, err := Parse(rule); err == nil {
+			t.Errorf("Parse(%q) = %+v, want an error", rule, r)
+		}
+	}
+}
+
+func TestIterate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		want  []time.Time
+	}{
+		{"daily every other day", "FREQ=DAILY;INTERVAL=2;COUNT=3", date(2030, 1, 1, 9),
+			[]time.Time{date(2030, 1, 1, 9), date(2030, 1, 3, 9), date(2030, 1, 5, 9)}},
+		{"daily on weekends", "FREQ=DAILY;BYDAY=SA,SU;COUNT=3", date(2030, 1, 1, 9),
+			[]time.Time{date(2030, 1, 5, 9), date(2030, 1, 6, 9), date(2030, 1, 12, 9)}},
+		// the start is a Tuesday, which the rule does not match
+		{"weekly by day", "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4", date(2030, 1, 1, 9),
+			[]time.Time{date(2030, 1, 2, 9), date(2030, 1, 7, 9), date(2030, 1, 9, 9), date(2030, 1, 14, 9)}},
+		{"fortnightly", "FREQ=WEEKLY;INTERVAL=2;COUNT=3", date(2030, 1, 1, 9),
+			[]time.Time{date(2030, 1, 1, 9), date(2030, 1, 15, 9), date(2030, 1, 29, 9)}},
+		{"monthly on the 31st", "FREQ=MONTHLY;COUNT=3", date(2030, 1, 31, 9),
+			[]time.Time{date(2030, 1, 31, 9), date(2030, 3, 31, 9), date(2030, 5, 31, 9)}},
+		{"yearly on 29 February", "FREQ=YEARLY;COUNT=2", date(2028, 2, 29, 9),
+			[]time.Time{date(2028, 2, 29, 9), date(2032, 2, 29, 9)}},
+		{"until a date", "FREQ=DAILY;UNTIL=20300103", date(2030, 1, 1, 9),
+			[]time.Time{date(2030, 1, 1, 9), date(2030, 1, 2, 9), date(2030, 1, 3, 9)}},
+		{"never matching", "FREQ=YEARLY;UNTIL=20310101", date(2028, 2, 29, 9),
+			[]time.Time{date(2028, 2, 29, 9)}},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []time.Time
+		r.Iterate(tt.start, func(o time.Time) bool {
+			got = append(got, o)
+			return len(got) < 10
+		})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIterateKeepsLocalTime(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	r, _ := Parse("FREQ=DAILY;COUNT=3")
+	var got []time.Time
+	// clocks go forward on 10 March 2030
+	r.Iterate(time.Date(2030, 3, 9, 9, 0, 0, 0, loc), func(o time.Time) bool {
+		got = append(got, o)
+		return true
+	})
+	for _, o := range got {
+		if o.Hour() != 9 {
+			t.Errorf("occurrence %v is not at 9:00", o)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	r, _ := Parse("FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4")
+	start := date(2030, 1, 1, 9)
+	tests := []struct {
+		after time.Time
+		want  time.Time
+		ok    bool
+	}{
+		{date(2029, 12, 1, 0), date(2030, 1, 2, 9), true},
+		{date(2030, 1, 2, 9), date(2030, 1, 7, 9), true},
+		{date(2030, 1, 7, 8), date(2030, 1, 7, 9), true},
+		{date(2030, 1, 14, 9), time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := r.Next(start, tt.after)
+		if !got.Equal(tt.want) || ok != tt.ok {
+			t.Errorf("Next(%v) = %v, %v, want %v, %v", tt.after, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	never, _ := Parse("FREQ=YEARLY;UNTIL=20310101")
+	if got, ok := never.Next(date(2028, 2, 29, 9), date(2028, 3, 1, 0)); ok {
+		t.Errorf("Next of a rule with no more occurrences = %v", got)
+	}
+}
+
+func TestBetween(t *testing.T) {
+	r, _ := Parse("FREQ=DAILY")
+	got := r.Between(date(2030, 1, 1, 9), date(2030, 1, 2, 9), date(2030, 1, 4, 9))
+	want := []time.Time{date(2030, 1, 3, 9), date(2030, 1, 4, 9)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Between = %v, want %v", got, want)
+	}
+}
+
+func TestTruncateAndRemainder(t *testing.T) {
+	start, split := date(2030, 1, 1, 9), date(2030, 1, 3, 9)
+
+	counted, _ := Parse("FREQ=DAILY;COUNT=5")
+	if n := counted.Truncate(start, split).Count; n != 2 {
+		t.Errorf("Truncate of COUNT=5 has COUNT=%d, want 2", n)
+	}
+	if n := counted.Remainder(start, split).Count; n != 3 {
+		t.Errorf("Remainder of COUNT=5 has COUNT=%d, want 3", n)
+	}
+
+	open, _ := Parse("FREQ=DAILY")
+	head := open.Truncate(start, split)
+	if want := time.Date(2030, 1, 3, 8, 59, 59, 0, time.UTC); !head.Until.Equal(want) {
+		t.Errorf("Truncate has UNTIL %v, want %v", head.Until, want)
+	}
+	if got := head.Between(start, start.Add(-time.Second), date(2031, 1, 1, 0)); len(got) != 2 {
+		t.Errorf("Truncate leaves %d occurrences, want 2", len(got))
+	}
+	if tail := open.Remainder(start, split); !reflect.DeepEqual(tail, open) {
+		t.Errorf("Remainder of an open rule = %+v, want it unchanged", tail)
+	}
+}
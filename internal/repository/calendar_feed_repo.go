@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CalendarFeedRepository struct {
+	db *gorm.DB
+}
+
+func NewCalendarFeedRepository(db *gorm.DB) *CalendarFeedRepository {
+	return &CalendarFeedRepository{db}
+}
+
+func (r *CalendarFeedRepository) GetByUser(userID uint) (models.CalendarFeed, error) {
+	var f models.CalendarFeed
+	err := r.db.Where("user_id = ?", userID).First(&f).Error
+	return f, err
+}
+
+func (r *CalendarFeedRepository) GetByTokenHash(hash string) (models.CalendarFeed, error) {
+	var f models.CalendarFeed
+	err := r.db.Where("token_hash = ?", hash).First(&f).Error
+	return f, err
+}
+
+// Upsert creates the user's feed or replaces its token, which revokes the
+// previous URL.
+func (r *CalendarFeedRepository) Upsert(f *models.CalendarFeed) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"token_hash", "updated_at"}),
+	}).Create(f).Error
+}
+
+func (r *CalendarFeedRepository) DeleteByUser(userID uint) error {
+	return affected(r.db.Where("user_id = ?", userID).Delete(&models.CalendarFeed{}))
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"gorm.io/gorm"
+)
+
+// ChangeRepository reads the change log that the record_change trigger
+// writes for tasks, subjects and deadlines.
+type ChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewChangeRepository(db *gorm.DB) *ChangeRepository {
+	return &ChangeRepository{db}
+}
+
+// Latest returns the sequence number of the user's most recent change, or
+// zero if there is none.
+func (r *ChangeRepository) Latest(userID uint) (uint64, error) {
+	var seq uint64
+	err := r.db.Model(&models.Change{}).Where("user_id = ?", userID).
+		Select("COALESCE(MAX(seq), 0)").Scan(&seq).Error
+	return seq, err
+}
+
+// Since returns the user's changes after the sequence number after, up to
+// and including through, in order.
+func (r *ChangeRepository) Since(userID uint, after, through uint64) ([]models.Change, error) {
+	var changes []models.Change
+	err := r.db.Where("user_id = ? AND seq > ? AND seq <= ?", userID, after, through).
+		Order("seq").Find(&changes).Error
+	return changes, err
+}
+
+// Horizon returns the oldest and newest sequence numbers still in the log,
+// or zeros when it is empty.
+func (r *ChangeRepository) Horizon() (oldest, newest uint64, err error) {
+	row := r.db.Model(&models.Change{}).Select("COALESCE(MIN(seq), 0), COALESCE(MAX(seq), 0)").Row()
+	err = row.Scan(&oldest, &newest)
+	return oldest, newest, err
+}
+
+// Purge deletes changes made before t. The newest change is always kept,
+// so that Horizon can tell tokens that predate the purge from new ones.
+func (r *ChangeRepository) Purge(before time.Time) (int64, error) {
+	res := r.db.Where("changed_at < ? AND seq < (SELECT MAX(seq) FROM changes)", before).Delete(&models.Change{})
+	return res.RowsAffected, res.Error
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/kadyrbayev2005/studysync/internal/models"
 	"gorm.io/gorm"
 )
@@ -17,18 +19,32 @@ func (r *DeadlineRepository) Create(d *models.Deadline) error {
 	return r.db.Create(d).Error
 }
 
-func (r *DeadlineRepository) GetAll() ([]models.Deadline, error) {
+func (r *DeadlineRepository) GetAll(scope Scope) ([]models.Deadline, error) {
 	var ds []models.Deadline
-	err := r.db.Preload("Task").Find(&ds).Error
+	err := r.db.Scopes(scope.owned("user_id")).Preload("Task").Find(&ds).Error
 	return ds, err
 }
 
-func (r *DeadlineRepository) GetByID(id uint) (models.Deadline, error) {
+// List reads a page of the deadlines in scope with their task (see
+// DeadlineList).
+func (r *DeadlineRepository) List(scope Scope, q *ListQuery) (Page[models.Deadline], error) {
+	return list[models.Deadline](r.db.Model(&models.Deadline{}).Scopes(scope.owned("user_id")).Preload("Task"), DeadlineList, q)
+}
+
+func (r *DeadlineRepository) GetByID(scope Scope, id uint) (models.Deadline, error) {
 	var d models.Deadline
-	err := r.db.Preload("Task").First(&d, id).Error
+	err := r.db.Scopes(scope.owned("user_id")).Preload("Task").First(&d, id).Error
 	return d, err
 }
 
+// GetDueAfter returns the deadlines due after t, with their tasks.
+func (r *DeadlineRepository) GetDueAfter(scope Scope, t time.Time) ([]models.Deadline, error) {
+	var ds []models.Deadline
+	err := r.db.Scopes(scope.owned("user_id")).Preload("Task.Subject").
+		Where("due_date > ?", t).Order("due_date").Find(&ds).Error
+	return ds, err
+}
+
 func (r *DeadlineRepository) GetDueBefore(t string) ([]models.Deadline, error) {
 	// not used directly; keep as example
 	var ds []models.Deadline
@@ -36,6 +52,21 @@ func (r *DeadlineRepository) GetDueBefore(t string) ([]models.Deadline, error) {
 	return ds, err
 }
 
-func (r *DeadlineRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Deadline{}, id).Error
+// Delete moves d to the trash as long as it is still at the version it was
+// read at.
+func (r *DeadlineRepository) Delete(scope Scope, d models.Deadline) error {
+	tx := r.db.Model(&models.Deadline{}).Scopes(scope.owned("user_id"), atVersion(d.Version)).
+		Where("id = ?", d.ID).Updates(trashed(time.Now()))
+	return guarded(tx, &models.Deadline{}, scope, d.ID)
+}
+
+// SetForTask moves the deadline rows of a task to due, creating one if the
+// task has none yet.
+func (r *DeadlineRepository) SetForTask(task models.Task, due time.Time) error {
+	res := r.db.Model(&models.Deadline{}).Where("task_id = ?", task.ID).
+		Updates(bumped(map[string]interface{}{"due_date": due}))
+	if res.Error != nil || res.RowsAffected > 0 {
+		return res.Error
+	}
+	return r.Create(&models.Deadline{TaskID: task.ID, UserID: task.UserID, DueDate: due})
 }
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A dependency makes a task wait for its blocker. Dependencies on or of
+// tasks in the trash stay in place but are left out until the task is
+// restored; purging the task deletes them.
+
+// ErrDependencyCycle is returned when a dependency would make a task wait,
+// directly or through other tasks, for itself.
+var ErrDependencyCycle = errors.New("repository: the dependency would create a cycle")
+
+type DependencyRepository struct {
+	db *gorm.DB
+}
+
+func NewDependencyRepository(db *gorm.DB) *DependencyRepository {
+	return &DependencyRepository{db}
+}
+
+// liveDependencies limits a query on task_dependencies to the ones between
+// tasks that are not in the trash.
+func liveDependencies(db *gorm.DB) *gorm.DB {
+	return db.Joins("JOIN tasks t ON t.id = task_dependencies.task_id AND t.deleted_at IS NULL").
+		Joins("JOIN tasks b ON b.id = task_dependencies.blocker_id AND b.deleted_at IS NULL")
+}
+
+// Add makes the task wait for the blocker. Both must be tasks of the same
+// user in scope; adding a dependency twice is a no-op.
+func (r *DependencyRepository) Add(scope Scope, taskID, blockerID uint) (models.TaskDependency, error) {
+	var dep models.TaskDependency
+	if taskID == blockerID {
+		return dep, ErrDependencyCycle
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var tasks []models.Task
+		if err := tx.Scopes(scope.owned("user_id")).Where("id IN ?", []uint{taskID, blockerID}).Find(&tasks).Error; err != nil {
+			return err
+		}
+		if len(tasks) != 2 || tasks[0].UserID != tasks[1].UserID {
+			return gorm.ErrRecordNotFound
+		}
+		userID := tasks[0].UserID
+
+		// one dependency write per user at a time, so that two requests
+		// cannot close a cycle between them
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(7302, ?::int)", userID).Error; err != nil {
+			return err
+		}
+		var edges []models.TaskDependency
+		if err := tx.Scopes(liveDependencies).Where("task_dependencies.user_id = ?", userID).Find(&edges).Error; err != nil {
+			return err
+		}
+		if DependsOn(edges, blockerID, taskID) {
+			return ErrDependencyCycle
+		}
+
+		dep = models.TaskDependency{TaskID: taskID, BlockerID: blockerID, UserID: userID}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&dep).Error; err != nil {
+			return err
+		}
+		return tx.Where("task_id = ? AND blocker_id = ?", taskID, blockerID).First(&dep).Error
+	})
+	return dep, err
+}
+
+// Remove deletes a dependency.
+func (r *DependencyRepository) Remove(scope Scope, taskID, blockerID uint) error {
+	return affected(r.db.Scopes(scope.owned("user_id")).Where("task_id = ? AND blocker_id = ?", taskID, blockerID).
+		Delete(&models.TaskDependency{}))
+}
+
+// Blockers returns the tasks the task waits for, by deadline, with their
+// subject.
+func (r *DependencyRepository) Blockers(scope Scope, taskID uint) ([]models.Task, error) {
+	var n int64
+	if err := r.db.Model(&models.Task{}).Scopes(scope.owned("user_id")).Where("id = ?", taskID).Count(&n).Error; err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var tasks []models.Task
+	blockers := r.db.Model(&models.TaskDependency{}).Where("task_id = ?", taskID).Select("blocker_id")
+	err := r.db.Scopes(withTags).Preload("Subject").Where("id IN (?)", blockers).Order("deadline, id").Find(&tasks).Error
+	return tasks, err
+}
+
+// Graph returns the dependencies in scope and the tasks they link, with
+// their subject.
+func (r *DependencyRepository) Graph(scope Scope) ([]models.Task, []models.TaskDependency, error) {
+	var edges []models.TaskDependency
+	err := r.db.Scopes(liveDependencies, scope.owned("task_dependencies.user_id")).
+		Order("task_dependencies.task_id, task_dependencies.blocker_id").Find(&edges).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]uint, 0, 2*len(edges))
+	for _, e := range edges {
+		ids = append(ids, e.TaskID, e.BlockerID)
+	}
+	tasks := []models.Task{}
+	if len(ids) > 0 {
+		if err := r.db.Preload("Subject").Where("id IN ?", ids).Order("id").Find(&tasks).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	return tasks, edges, nil
+}
+
+// DependsOn reports whether taskID waits for blockerID, directly or through
+// other tasks.
+func DependsOn(edges []models.TaskDependency, taskID, blockerID uint) bool {
+	blockers := map[uint][]uint{}
+	for _, e := range edges {
+		blockers[e.TaskID] = append(blockers[e.TaskID], e.BlockerID)
+	}
+	seen := map[uint]bool{taskID: true}
+	queue := []uint{taskID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, b := range blockers[id] {
+			if b == blockerID {
+				return true
+			}
+			if !seen[b] {
+				seen[b] = true
+				queue = append(queue, b)
+			}
+		}
+	}
+	return false
+}
@@ -1,14 +1,38 @@
 package repository
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 type TaskFilter struct {
 	Page           int
 	Limit          int
 	Status         string
 	SubjectID      *uint
-	Search         string
+	Search         string // full text, see search_repo.go; ranks when Sort is empty
 	Sort           string // e.g. "created_at desc"
 	DeadlineBefore *time.Time
 	DeadlineAfter  *time.Time
+	TopLevel       bool   // leave out subtasks
+	Where          *Where // a filter expression, see where.go
+	SkipTotal      bool   // do not count the matches; the total is -1
+
+	// tag names, ignoring case: tasks with any, all or none of them
+	TagsAny  []string
+	TagsAll  []string
+	TagsNone []string
+}
+
+// SearchFilter is a full-text search. Types are the resources to search,
+// all of them when empty.
+type SearchFilter struct {
+	Query string
+	Types []string
+	Limit int
+}
+
+// Wants reports whether the search covers resource.
+func (f *SearchFilter) Wants(resource string) bool {
+	return len(f.Types) == 0 || slices.Contains(f.Types, resource)
 }
@@ -0,0 +1,370 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Lists are read through a ListQuery checked against the ListSpec of what
+// is listed: the sorts it allows, the fields it can be filtered on and the
+// times it can be limited to a range of. Pages are numbered, or read from
+// a cursor: the sort key and id of the row at the edge of a page, so pages
+// do not shift when rows are added or removed before them and deep pages
+// cost no more than the first. Ties in the sort key are broken by id, in
+// the same direction as the sort.
+
+var (
+	// ErrInvalidQuery is returned for a list query that names a sort,
+	// filter or range its list does not have.
+	ErrInvalidQuery = errors.New("repository: invalid list query")
+
+	// ErrInvalidCursor is returned for a cursor that cannot be read or was
+	// made for another sort.
+	ErrInvalidCursor = errors.New("repository: invalid cursor")
+)
+
+// KeyKind is the type of a column lists sort or filter by.
+type KeyKind int
+
+const (
+	KeyText KeyKind = iota
+	KeyNumber
+	KeyTime
+)
+
+// ListKey is a column a list can be sorted or filtered by.
+type ListKey struct {
+	Column string
+	Kind   KeyKind
+}
+
+// ListSpec is what a list can be sorted and filtered by, by the names
+// clients use. Every sort also has a descending form, "<sort> desc".
+// Ranges are filtered with <range>_after and <range>_before.
+type ListSpec struct {
+	Sorts   map[string]ListKey
+	Default string
+	Fields  map[string]ListKey
+	Ranges  map[string]ListKey
+}
+
+var (
+	TaskList = ListSpec{
+		Sorts: map[string]ListKey{
+			"created_at": {"created_at", KeyTime},
+			"deadline":   {"deadline", KeyTime},
+			"title":      {"title", KeyText},
+		},
+		Default: "created_at desc",
+	}
+	SubjectList = ListSpec{
+		Sorts: map[string]ListKey{
+			"id":         {"id", KeyNumber},
+			"name":       {"name", KeyText},
+			"created_at": {"created_at", KeyTime},
+		},
+		Default: "id",
+		Fields:  map[string]ListKey{"name": {"name", KeyText}},
+		Ranges:  map[string]ListKey{"created": {"created_at", KeyTime}},
+	}
+	DeadlineList = ListSpec{
+		Sorts: map[string]ListKey{
+			"id":         {"id", KeyNumber},
+			"due_date":   {"due_date", KeyTime},
+			"created_at": {"created_at", KeyTime},
+		},
+		Default: "id",
+		Fields:  map[string]ListKey{"task_id": {"task_id", KeyNumber}},
+		Ranges: map[string]ListKey{
+			"due":     {"due_date", KeyTime},
+			"created": {"created_at", KeyTime},
+		},
+	}
+	UserList = ListSpec{
+		Sorts: map[string]ListKey{
+			"id":         {"id", KeyNumber},
+			"name":       {"name", KeyText},
+			"email":      {"email", KeyText},
+			"created_at": {"created_at", KeyTime},
+		},
+		Default: "id",
+		Fields: map[string]ListKey{
+			"role":  {"role", KeyText},
+			"email": {"email", KeyText},
+		},
+		Ranges: map[string]ListKey{"created": {"created_at", KeyTime}},
+	}
+)
+
+// TimeRange limits a time column. Both ends are inclusive and optional.
+type TimeRange struct {
+	After  *time.Time
+	Before *time.Time
+}
+
+// ListQuery asks for a page of a list. Filters hold values parsed with
+// ListSpec.Parse; text filters match ignoring case.
+type ListQuery struct {
+	Page      int
+	Limit     int
+	Sort      string
+	Cursors   bool    // page with cursors; a nil Cursor reads the first page
+	Cursor    *Cursor // the page after it, or before it
+	Filters   map[string]interface{}
+	Ranges    map[string]TimeRange
+	SkipTotal bool // do not count the matches; the total is -1
+}
+
+// Page is a page of a list. In cursor mode Next and Prev lead to the
+// neighbouring pages and are nil at the ends of the list.
+type Page[T any] struct {
+	Items []T
+	Next  *Cursor
+	Prev  *Cursor
+	Total int64
+}
+
+// Cursor is a position in a list in the order of Sort: the sort key and
+// id of a row. The page it leads to holds the rows after it, or before it
+// when Before is set. Only the field for the kind of the sort key is set.
+type Cursor struct {
+	Sort   string    `json:"s"`
+	At     time.Time `json:"t,omitzero"`
+	Text   string    `json:"k,omitempty"`
+	Number uint      `json:"n,omitempty"`
+	ID     uint      `json:"i"`
+	Before bool      `json:"b,omitempty"`
+}
+
+// Sortable reports whether the list can be sorted by sort.
+func (s ListSpec) Sortable(sort string) bool {
+	_, ok := s.Sorts[strings.TrimSuffix(sort, " desc")]
+	return ok
+}
+
+// SortOrDefault is sort when the list can be sorted by it and the default
+// sort otherwise.
+func (s ListSpec) SortOrDefault(sort string) string {
+	if sort = strings.TrimSpace(sort); s.Sortable(sort) {
+		return sort
+	}
+	return s.Default
+}
+
+// Key returns the column of a sort the list has and whether it is
+// descending.
+func (s ListSpec) Key(sort string) (key ListKey, desc bool) {
+	name, desc := strings.CutSuffix(sort, " desc")
+	return s.Sorts[name], desc
+}
+
+// Parse reads the value of a filter on field from a query string.
+func (s ListSpec) Parse(field, raw string) (interface{}, error) {
+	key, ok := s.Fields[field]
+	if !ok {
+		return nil, ErrInvalidQuery
+	}
+	return parseKey(key.Kind, raw)
+}
+
+func parseKey(kind KeyKind, raw string) (interface{}, error) {
+	switch kind {
+	case KeyNumber:
+		n, err := strconv.ParseUint(raw, 10, 0)
+		return uint(n), err
+	case KeyTime:
+		return time.Parse(time.RFC3339Nano, raw)
+	}
+	return raw, nil
+}
+
+// Check normalises the query for the list: the default sort, the first
+// page and 10 rows per page unless set, and at most 100.
+func (s ListSpec) Check(q *ListQuery) error {
+	if q.Sort = strings.TrimSpace(q.Sort); q.Sort == "" {
+		q.Sort = s.Default
+	}
+	if !s.Sortable(q.Sort) {
+		return fmt.Errorf("%w: cannot sort by %q", ErrInvalidQuery, q.Sort)
+	}
+	for name := range q.Filters {
+		if _, ok := s.Fields[name]; !ok {
+			return fmt.Errorf("%w: cannot filter by %q", ErrInvalidQuery, name)
+		}
+	}
+	for name := range q.Ranges {
+		if _, ok := s.Ranges[name]; !ok {
+			return fmt.Errorf("%w: no range %q", ErrInvalidQuery, name)
+		}
+	}
+	if q.Cursor != nil && q.Cursor.Sort != q.Sort {
+		return ErrInvalidCursor
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.Limit <= 0 {
+		q.Limit = 10
+	}
+	if q.Limit > 100 {
+		q.Limit = 100
+	}
+	return nil
+}
+
+// Encode returns the opaque form of the cursor handed to clients.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor reads a cursor made by Encode for sort.
+func (s ListSpec) DecodeCursor(raw, sort string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort != sort || c.ID == 0 || !s.Sortable(sort) {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// CursorKey returns the sort key of a cursor for the list.
+func (s ListSpec) CursorKey(c *Cursor) interface{} {
+	key, _ := s.Key(c.Sort)
+	switch key.Kind {
+	case KeyTime:
+		return c.At
+	case KeyNumber:
+		return c.Number
+	}
+	return c.Text
+}
+
+var listSchemas sync.Map
+
+// ColumnValue returns the value of column in a model value.
+func ColumnValue(row interface{}, column string) interface{} {
+	sch, err := schema.Parse(row, &listSchemas, schema.NamingStrategy{})
+	if err != nil {
+		panic(err)
+	}
+	value, _ := sch.LookUpField(column).ValueOf(context.Background(), reflect.ValueOf(row))
+	return value
+}
+
+// CursorAt returns the cursor at row in sort.
+func (s ListSpec) CursorAt(sort string, row interface{}, before bool) *Cursor {
+	key, _ := s.Key(sort)
+	c := &Cursor{Sort: sort, ID: ColumnValue(row, "id").(uint), Before: before}
+	switch v := ColumnValue(row, key.Column).(type) {
+	case time.Time:
+		c.At = v
+	case uint:
+		c.Number = v
+	case string:
+		c.Text = v
+	}
+	return c
+}
+
+// CursorPage makes a cursor page of the rows read from q.Cursor: up to one
+// more than q.Limit, in the order they were read.
+func CursorPage[T any](rows []T, spec ListSpec, q *ListQuery, total int64) Page[T] {
+	more := len(rows) > q.Limit
+	if more {
+		rows = rows[:q.Limit]
+	}
+	backwards := q.Cursor != nil && q.Cursor.Before
+	if backwards {
+		slices.Reverse(rows)
+	}
+
+	page := Page[T]{Items: rows, Total: total}
+	if len(rows) == 0 {
+		return page
+	}
+	first, last := rows[0], rows[len(rows)-1]
+	if more || backwards {
+		page.Next = spec.CursorAt(q.Sort, last, false)
+	}
+	if (more && backwards) || (q.Cursor != nil && !backwards) {
+		page.Prev = spec.CursorAt(q.Sort, first, true)
+	}
+	return page
+}
+
+// list reads the page q asks for of the rows tx selects.
+func list[T any](tx *gorm.DB, spec ListSpec, q *ListQuery) (Page[T], error) {
+	if err := spec.Check(q); err != nil {
+		return Page[T]{}, err
+	}
+	for name, value := range q.Filters {
+		key := spec.Fields[name]
+		if key.Kind == KeyText {
+			tx = tx.Where("LOWER("+key.Column+") = LOWER(?)", value)
+		} else {
+			tx = tx.Where(key.Column+" = ?", value)
+		}
+	}
+	for name, r := range q.Ranges {
+		column := spec.Ranges[name].Column
+		if r.After != nil {
+			tx = tx.Where(column+" >= ?", *r.After)
+		}
+		if r.Before != nil {
+			tx = tx.Where(column+" <= ?", *r.Before)
+		}
+	}
+
+	total := int64(-1)
+	if !q.SkipTotal {
+		// a count has nothing to preload into; drop the preloads from a
+		// statement of its own, not the one that reads the page
+		counted := tx.Session(&gorm.Session{Initialized: true})
+		counted.Statement.Preloads = nil
+		if err := counted.Count(&total).Error; err != nil {
+			return Page[T]{}, err
+		}
+	}
+
+	// a page before the cursor is read backwards from it
+	key, desc := spec.Key(q.Sort)
+	backwards := q.Cursor != nil && q.Cursor.Before
+	op, dir := ">", "ASC"
+	if desc != backwards {
+		op, dir = "<", "DESC"
+	}
+	if key.Column == "id" {
+		tx = tx.Order("id " + dir)
+	} else {
+		tx = tx.Order(key.Column + " " + dir + ", id " + dir)
+	}
+
+	rows := []T{}
+	if !q.Cursors {
+		err := tx.Limit(q.Limit).Offset((q.Page - 1) * q.Limit).Find(&rows).Error
+		return Page[T]{Items: rows, Total: total}, err
+	}
+	if q.Cursor != nil {
+		tx = tx.Where("("+key.Column+", id) "+op+" (?, ?)", spec.CursorKey(q.Cursor), q.Cursor.ID)
+	}
+	if err := tx.Limit(q.Limit + 1).Find(&rows).Error; err != nil {
+		return Page[T]{}, err
+	}
+	return CursorPage(rows, spec, q, total), nil
+}
@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"gorm.io/gorm/schema"
+)
+
+type ChangeRepository struct {
+	s *Store
+}
+
+func (r *ChangeRepository) Latest(userID uint) (uint64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var seq uint64
+	for _, c := range r.s.changes {
+		if c.UserID == userID {
+			seq = c.Seq
+		}
+	}
+	return seq, nil
+}
+
+func (r *ChangeRepository) Since(userID uint, after, through uint64) ([]models.Change, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	changes := []models.Change{}
+	for _, c := range r.s.changes {
+		if c.UserID == userID && c.Seq > after && c.Seq <= through {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+func (r *ChangeRepository) Horizon() (oldest, newest uint64, err error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if n := len(r.s.changes); n > 0 {
+		return r.s.changes[0].Seq, r.s.changes[n-1].Seq, nil
+	}
+	return 0, 0, nil
+}
+
+func (r *ChangeRepository) Purge(before time.Time) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	kept := r.s.changes[:0]
+	for i, c := range r.s.changes {
+		if c.ChangedAt.Before(before) && i < len(r.s.changes)-1 {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	purged := int64(len(r.s.changes) - len(kept))
+	r.s.changes = kept
+	return purged, nil
+}
+
+// untracked are the columns, per table, that the record_change trigger is
+// told to ignore. The models have no search_vector field; it is listed to
+// keep the two in step.
+var untracked = map[string][]string{
+	"subjects":  {"version", "search_vector"},
+	"tasks":     {"version", "recurrence_generated_until", "search_vector"},
+	"deadlines": {"version"},
+}
+
+// track logs a row going from before to after like the record_change
+// trigger does. before is nil for inserts and after is nil for deletes;
+// both are model values.
+func (s *Store) track(table string, before, after interface{}) {
+	row := after
+	op := models.ChangeUpdate
+	switch {
+	case before == nil:
+		op = models.ChangeInsert
+	case after == nil:
+		op, row = models.ChangeDelete, before
+	}
+
+	v := reflect.ValueOf(row)
+	sch, err := schema.Parse(row, &schemas, schema.NamingStrategy{})
+	if err != nil {
+		panic(err)
+	}
+	field := func(v reflect.Value, name string) interface{} {
+		value, _ := sch.LookUpField(name).ValueOf(context.Background(), v)
+		return value
+	}
+
+	var changed []string
+	if op == models.ChangeUpdate {
+		old := reflect.ValueOf(before)
+		for _, f := range sch.Fields {
+			if f.DBName == "" || slices.Contains(untracked[table], f.DBName) {
+				continue
+			}
+			if !same(field(old, f.DBName), field(v, f.DBName)) {
+				changed = append(changed, f.DBName)
+			}
+		}
+		if len(changed) == 0 {
+			return
+		}
+		sort.Strings(changed)
+	}
+
+	s.seq++
+	s.changes = append(s.changes, models.Change{
+		Seq:        s.seq,
+		UserID:     field(v, "user_id").(uint),
+		Resource:   table,
+		ResourceID: field(v, "id").(uint),
+		Op:         op,
+		Fields:     strings.Join(changed, ","),
+		ChangedAt:  time.Now(),
+	})
+}
+
+// same compares column values the way Postgres would: times by instant
+// and pointers by what they point to.
+func same(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() == reflect.Pointer && vb.Kind() == reflect.Pointer {
+		if va.IsNil() || vb.IsNil() {
+			return va.IsNil() == vb.IsNil()
+		}
+		return same(va.Elem().Interface(), vb.Elem().Interface())
+	}
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+	return reflect.DeepEqual(a, b)
+}
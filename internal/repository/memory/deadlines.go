@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+type DeadlineRepository struct {
+	s *Store
+}
+
+func (r *DeadlineRepository) Create(d *models.Deadline) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.createDeadline(d)
+	return nil
+}
+
+func (r *DeadlineRepository) GetAll(scope repository.Scope) ([]models.Deadline, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	ds := r.s.findDeadlines(func(d models.Deadline) bool { return scope.Allows(d.UserID) }, false)
+	sort.Slice(ds, func(i, j int) bool { return ds[i].ID < ds[j].ID })
+	return ds, nil
+}
+
+func (r *DeadlineRepository) List(scope repository.Scope, q *repository.ListQuery) (repository.Page[models.Deadline], error) {
+	ds, _ := r.GetAll(scope)
+	return list(ds, repository.DeadlineList, q)
+}
+
+func (r *DeadlineRepository) GetByID(scope repository.Scope, id uint) (models.Deadline, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	d, ok := r.s.deadlines[id]
+	if !ok || !scope.Allows(d.UserID) {
+		return models.Deadline{}, gorm.ErrRecordNotFound
+	}
+	d.Task = r.s.loadTask(d.TaskID, false)
+	return d, nil
+}
+
+func (r *DeadlineRepository) GetDueAfter(scope repository.Scope, t time.Time) ([]models.Deadline, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	ds := r.s.findDeadlines(func(d models.Deadline) bool {
+		return scope.Allows(d.UserID) && d.DueDate.After(t)
+	}, true)
+	sort.SliceStable(ds, func(i, j int) bool { return ds[i].DueDate.Before(ds[j].DueDate) })
+	return ds, nil
+}
+
+func (r *DeadlineRepository) SetForTask(task models.Task, due time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.setDeadline(task, due)
+	return nil
+}
+
+func (s *Store) setDeadline(task models.Task, due time.Time) {
+	found := false
+	for id, d := range s.deadlines {
+		if d.TaskID == task.ID {
+			old := d
+			d.DueDate = due
+			d.Version++
+			s.deadlines[id] = d
+			s.track("deadlines", old, d)
+			found = true
+		}
+	}
+	if !found {
+		s.createDeadline(&models.Deadline{TaskID: task.ID, UserID: task.UserID, DueDate: due})
+	}
+}
+
+func (r *DeadlineRepository) Delete(scope repository.Scope, deadline models.Deadline) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	d, ok := r.s.deadlines[deadline.ID]
+	if err := claim(ok, scope, d.UserID, d.Version, deadline.Version); err != nil {
+		return err
+	}
+	delete(r.s.deadlines, deadline.ID)
+	r.s.trashDeadline(d, time.Now())
+	return nil
+}
+
+// trashDeadline moves a deadline that has been taken out of the live
+// table to the trash at.
+func (s *Store) trashDeadline(d models.Deadline, at time.Time) {
+	s.track("deadlines", d, nil)
+	d.DeletedAt = gorm.DeletedAt{Time: at, Valid: true}
+	d.Version++
+	s.trashedDeadlines[d.ID] = d
+}
+
+func (s *Store) createDeadline(d *models.Deadline) {
+	d.ID = s.id("deadlines")
+	d.CreatedAt = createdAt(d.CreatedAt)
+	d.Version = version(d.Version)
+	stored := *d
+	stored.Task = models.Task{}
+	stored.User = models.User{}
+	s.deadlines[d.ID] = stored
+	s.track("deadlines", nil, stored)
+}
+
+// findDeadlines returns the matching deadlines with their task preloaded,
+// and the task's subject too when withSubject is set.
+func (s *Store) findDeadlines(match func(models.Deadline) bool, withSubject bool) []models.Deadline {
+	ds := []models.Deadline{}
+	for _, d := range s.deadlines {
+		if match(d) {
+			d.Task = s.loadTask(d.TaskID, withSubject)
+			ds = append(ds, d)
+		}
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i].ID < ds[j].ID })
+	return ds
+}
+
+func (s *Store) loadTask(id uint, withSubject bool) models.Task {
+	t, ok := s.tasks[id]
+	if !ok {
+		return models.Task{}
+	}
+	t = cloneTask(t)
+	if withSubject {
+		t.Subject = s.subjects[t.SubjectID]
+		t.Tags = s.tagsOf(id)
+	}
+	return t
+}
@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+type DependencyRepository struct {
+	s *Store
+}
+
+// depKey is the primary key of task_dependencies.
+type depKey struct {
+	task, blocker uint
+}
+
+func (r *DependencyRepository) Add(scope repository.Scope, taskID, blockerID uint) (models.TaskDependency, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if taskID == blockerID {
+		return models.TaskDependency{}, repository.ErrDependencyCycle
+	}
+	t, ok := r.s.tasks[taskID]
+	b, found := r.s.tasks[blockerID]
+	if !ok || !found || !scope.Allows(t.UserID) || !scope.Allows(b.UserID) || t.UserID != b.UserID {
+		return models.TaskDependency{}, gorm.ErrRecordNotFound
+	}
+	if repository.DependsOn(r.s.liveDependencies(repository.Scope{UserID: t.UserID}), blockerID, taskID) {
+		return models.TaskDependency{}, repository.ErrDependencyCycle
+	}
+
+	key := depKey{taskID, blockerID}
+	if dep, ok := r.s.dependencies[key]; ok {
+		return dep, nil
+	}
+	dep := models.TaskDependency{TaskID: taskID, BlockerID: blockerID, UserID: t.UserID, CreatedAt: time.Now()}
+	r.s.dependencies[key] = dep
+	return dep, nil
+}
+
+func (r *DependencyRepository) Remove(scope repository.Scope, taskID, blockerID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	key := depKey{taskID, blockerID}
+	dep, ok := r.s.dependencies[key]
+	if !ok || !scope.Allows(dep.UserID) {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.s.dependencies, key)
+	return nil
+}
+
+func (r *DependencyRepository) Blockers(scope repository.Scope, taskID uint) ([]models.Task, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tasks[taskID]
+	if !ok || !scope.Allows(t.UserID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	tasks := r.s.findTasks(func(b models.Task) bool {
+		_, ok := r.s.dependencies[depKey{taskID, b.ID}]
+		return ok
+	})
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].Deadline.Before(tasks[j].Deadline) })
+	return tasks, nil
+}
+
+func (r *DependencyRepository) Graph(scope repository.Scope) ([]models.Task, []models.TaskDependency, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	edges := r.s.liveDependencies(scope)
+	linked := map[uint]bool{}
+	for _, e := range edges {
+		linked[e.TaskID], linked[e.BlockerID] = true, true
+	}
+	return r.s.findTasks(func(t models.Task) bool { return linked[t.ID] }), edges, nil
+}
+
+// liveDependencies returns the dependencies in scope between tasks that are
+// not in the trash, by task and blocker.
+func (s *Store) liveDependencies(scope repository.Scope) []models.TaskDependency {
+	edges := []models.TaskDependency{}
+	for key, dep := range s.dependencies {
+		_, taskLive := s.tasks[key.task]
+		_, blockerLive := s.tasks[key.blocker]
+		if taskLive && blockerLive && scope.Allows(dep.UserID) {
+			edges = append(edges, dep)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].TaskID != edges[j].TaskID {
+			return edges[i].TaskID < edges[j].TaskID
+		}
+		return edges[i].BlockerID < edges[j].BlockerID
+	})
+	return edges
+}
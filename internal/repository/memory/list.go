@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+)
+
+// list reads the page q asks for of rows like the gorm repositories do;
+// rows are every row in scope, in any order.
+func list[T any](rows []T, spec repository.ListSpec, q *repository.ListQuery) (repository.Page[T], error) {
+	if err := spec.Check(q); err != nil {
+		return repository.Page[T]{}, err
+	}
+	rows = slices.DeleteFunc(rows, func(row T) bool { return !listed(row, spec, q) })
+
+	total := int64(len(rows))
+	if q.SkipTotal {
+		total = -1
+	}
+
+	key, desc := spec.Key(q.Sort)
+	before := func(a, b T) bool {
+		if c := compareKeys(repository.ColumnValue(a, key.Column), repository.ColumnValue(b, key.Column)); c != 0 {
+			return (c < 0) != desc
+		}
+		ida, idb := repository.ColumnValue(a, "id").(uint), repository.ColumnValue(b, "id").(uint)
+		if desc {
+			return ida > idb
+		}
+		return ida < idb
+	}
+
+	if !q.Cursors {
+		sort.Slice(rows, func(i, j int) bool { return before(rows[i], rows[j]) })
+		offset := (q.Page - 1) * q.Limit
+		if offset >= len(rows) {
+			return repository.Page[T]{Items: []T{}, Total: total}, nil
+		}
+		return repository.Page[T]{Items: rows[offset:min(offset+q.Limit, len(rows))], Total: total}, nil
+	}
+
+	// a page before the cursor is read backwards from it
+	backwards := q.Cursor != nil && q.Cursor.Before
+	sort.Slice(rows, func(i, j int) bool {
+		if backwards {
+			return before(rows[j], rows[i])
+		}
+		return before(rows[i], rows[j])
+	})
+	if c := q.Cursor; c != nil {
+		rows = slices.DeleteFunc(rows, func(row T) bool {
+			cmp := compareKeys(repository.ColumnValue(row, key.Column), spec.CursorKey(c))
+			if cmp == 0 {
+				cmp = compareKeys(repository.ColumnValue(row, "id"), c.ID)
+			}
+			// keep the rows that come after the cursor in reading order
+			return cmp == 0 || (cmp < 0) != (desc != backwards)
+		})
+	}
+	return repository.CursorPage(rows[:min(len(rows), q.Limit+1)], spec, q, total), nil
+}
+
+// listed reports whether the row matches the filters and ranges of q.
+func listed(row interface{}, spec repository.ListSpec, q *repository.ListQuery) bool {
+	for name, value := range q.Filters {
+		key := spec.Fields[name]
+		v := repository.ColumnValue(row, key.Column)
+		if key.Kind == repository.KeyText {
+			if !strings.EqualFold(v.(string), value.(string)) {
+				return false
+			}
+		} else if compareKeys(v, value) != 0 {
+			return false
+		}
+	}
+	for name, r := range q.Ranges {
+		v := repository.ColumnValue(row, spec.Ranges[name].Column).(time.Time)
+		if (r.After != nil && v.Before(*r.After)) || (r.Before != nil && v.After(*r.Before)) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareKeys orders two values of a sort key: times, strings or
+// numbers.
+func compareKeys(a, b interface{}) int {
+	switch a := a.(type) {
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	x, y := number(a), number(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func number(v interface{}) int64 {
+	rv := reflect.ValueOf(v)
+	if rv.CanInt() {
+		return rv.Int()
+	}
+	return int64(rv.Uint())
+}
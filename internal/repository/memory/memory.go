@@ -0,0 +1,190 @@
+// Package memory implements the repository Store interfaces in memory, so
+// controllers and services can be tested without Postgres. It follows the
+// gorm repositories' semantics, including scoping, soft deletes, the unique
+// indexes and the change log, and is checked against them by the
+// conformance suite in package repotest.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Store holds the tables shared by the repositories it hands out, so that
+// tasks can preload their subject and deadlines their task, and the change
+// log that the record_change trigger keeps in Postgres.
+type Store struct {
+	mu sync.Mutex
+
+	users     map[uint]models.User
+	subjects  map[uint]models.Subject
+	tasks     map[uint]models.Task
+	deadlines map[uint]models.Deadline
+
+	// the trash: soft-deleted rows, out of reach of everything but
+	// TrashRepository like gorm's deleted_at scope
+	trashedSubjects  map[uint]models.Subject
+	trashedTasks     map[uint]models.Task
+	trashedDeadlines map[uint]models.Deadline
+
+	dependencies map[depKey]models.TaskDependency
+	checklist    map[uint]models.ChecklistItem
+	tags         map[uint]models.Tag
+	taskTags     map[taskTagKey]bool
+
+	changes []models.Change
+	seq     uint64
+
+	nextID map[string]uint
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:     map[uint]models.User{},
+		subjects:  map[uint]models.Subject{},
+		tasks:     map[uint]models.Task{},
+		deadlines: map[uint]models.Deadline{},
+
+		trashedSubjects:  map[uint]models.Subject{},
+		trashedTasks:     map[uint]models.Task{},
+		trashedDeadlines: map[uint]models.Deadline{},
+
+		dependencies: map[depKey]models.TaskDependency{},
+		checklist:    map[uint]models.ChecklistItem{},
+		tags:         map[uint]models.Tag{},
+		taskTags:     map[taskTagKey]bool{},
+
+		nextID: map[string]uint{},
+	}
+}
+
+func (s *Store) Users() *UserRepository              { return &UserRepository{s} }
+func (s *Store) Subjects() *SubjectRepository        { return &SubjectRepository{s} }
+func (s *Store) Tasks() *TaskRepository              { return &TaskRepository{s} }
+func (s *Store) Deadlines() *DeadlineRepository      { return &DeadlineRepository{s} }
+func (s *Store) Changes() *ChangeRepository          { return &ChangeRepository{s} }
+func (s *Store) Trash() *TrashRepository             { return &TrashRepository{s} }
+func (s *Store) Dependencies() *DependencyRepository { return &DependencyRepository{s} }
+func (s *Store) Subtasks() *SubtaskRepository        { return &SubtaskRepository{s} }
+func (s *Store) Tags() *TagRepository                { return &TagRepository{s} }
+func (s *Store) Search() *SearchRepository           { return &SearchRepository{s} }
+
+func (s *Store) id(table string) uint {
+	s.nextID[table]++
+	return s.nextID[table]
+}
+
+// createdAt mimics gorm filling in a zero CreatedAt on insert.
+func createdAt(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now()
+	}
+	return t
+}
+
+// version mimics the column default for rows created without one.
+func version(v uint) uint {
+	if v == 0 {
+		return 1
+	}
+	return v
+}
+
+// claim checks a version-guarded write like the gorm repositories do: a
+// zero version is unconditional.
+func claim(found bool, scope repository.Scope, ownerID, current, expected uint) error {
+	switch {
+	case !found || !scope.Allows(ownerID):
+		return gorm.ErrRecordNotFound
+	case expected != 0 && current != expected:
+		return repository.ErrConflict
+	}
+	return nil
+}
+
+var schemas sync.Map
+
+// apply sets the columns in data on the struct pointed to by dst, using
+// gorm's own schema so column names and value conversions (JSON numbers,
+// RFC 3339 strings) match what an Updates call accepts.
+func apply(dst interface{}, data map[string]interface{}) error {
+	sch, err := schema.Parse(dst, &schemas, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(dst).Elem()
+	for column, value := range data {
+		f := sch.LookUpField(column)
+		if f == nil || f.DBName == "" {
+			return fmt.Errorf("memory: %s has no column %q", sch.Table, column)
+		}
+		if err := f.Set(context.Background(), v, value); err != nil {
+			return fmt.Errorf("memory: set %s.%s: %w", sch.Table, column, err)
+		}
+	}
+	return nil
+}
+
+func cloneUint(p *uint) *uint {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+func cloneTime(p *time.Time) *time.Time {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+func cloneString(p *string) *string {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// cloneTask copies the pointer fields too, so callers never share state
+// with the store.
+func cloneTask(t models.Task) models.Task {
+	t.ParentID = cloneUint(t.ParentID)
+	t.SeriesID = cloneUint(t.SeriesID)
+	t.OccurrenceAt = cloneTime(t.OccurrenceAt)
+	t.RecurrenceGeneratedUntil = cloneTime(t.RecurrenceGeneratedUntil)
+	t.StartedAt = cloneTime(t.StartedAt)
+	t.CompletedAt = cloneTime(t.CompletedAt)
+	if t.Progress != nil {
+		p := *t.Progress
+		t.Progress = &p
+	}
+	t.ExternalUID = cloneString(t.ExternalUID)
+	t.Tags = slices.Clone(t.Tags)
+	return t
+}
+
+var (
+	_ repository.UserStore       = (*UserRepository)(nil)
+	_ repository.SubjectStore    = (*SubjectRepository)(nil)
+	_ repository.TaskStore       = (*TaskRepository)(nil)
+	_ repository.DeadlineStore   = (*DeadlineRepository)(nil)
+	_ repository.ChangeStore     = (*ChangeRepository)(nil)
+	_ repository.TrashStore      = (*TrashRepository)(nil)
+	_ repository.DependencyStore = (*DependencyRepository)(nil)
+	_ repository.SubtaskStore    = (*SubtaskRepository)(nil)
+	_ repository.TagStore        = (*TagRepository)(nil)
+	_ repository.SearchStore     = (*SearchRepository)(nil)
+)
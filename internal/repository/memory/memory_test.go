@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/kadyrbayev2005/studysync/internal/repository/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Stores {
+		s := NewStore()
+		return repotest.Stores{Users: s.Users(), Subjects: s.Subjects(), Tasks: s.Tasks(), Deadlines: s.Deadlines(), Changes: s.Changes(), Trash: s.Trash(), Deps: s.Dependencies(), Subtasks: s.Subtasks(), Tags: s.Tags(), Search: s.Search()}
+	})
+}
@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+)
+
+type SearchRepository struct {
+	s *Store
+}
+
+func (r *SearchRepository) Search(scope repository.Scope, filter *repository.SearchFilter) ([]models.SearchHit, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	hits := []models.SearchHit{}
+	terms := repository.SearchTerms(filter.Query)
+	if len(terms) == 0 {
+		return hits, nil
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+
+	if filter.Wants(models.ResourceSubjects) {
+		for _, s := range r.s.subjects {
+			if rank, ok := searchRank(terms, s.Name, s.Description); ok && scope.Allows(s.UserID) {
+				hits = append(hits, models.SearchHit{
+					Type: models.ResourceSubjects, ID: s.ID, Title: s.Name, Rank: rank,
+					Snippet: searchSnippet(terms, s.Name, s.Description),
+				})
+			}
+		}
+	}
+	if filter.Wants(models.ResourceTasks) {
+		for _, t := range r.s.tasks {
+			if rank, ok := searchRank(terms, t.Title, t.Description); ok && scope.Allows(t.UserID) {
+				hits = append(hits, models.SearchHit{
+					Type: models.ResourceTasks, ID: t.ID, Title: t.Title, Rank: rank,
+					Snippet: searchSnippet(terms, t.Title, t.Description),
+				})
+			}
+		}
+	}
+	if filter.Wants(models.ResourceDeadlines) {
+		for _, d := range r.s.deadlines {
+			t, live := r.s.tasks[d.TaskID]
+			if !live || !scope.Allows(d.UserID) {
+				continue
+			}
+			if rank, ok := searchRank(terms, t.Title, t.Description); ok {
+				taskID, due := d.TaskID, d.DueDate
+				hits = append(hits, models.SearchHit{
+					Type: models.ResourceDeadlines, ID: d.ID, Title: t.Title, Rank: rank,
+					Snippet: searchSnippet(terms, t.Title, t.Description), TaskID: &taskID, DueDate: &due,
+				})
+			}
+		}
+	}
+	return repository.RankHits(hits, filter.Limit), nil
+}
+
+func searched(terms []string, t models.Task) bool {
+	_, ok := searchRank(terms, t.Title, t.Description)
+	return ok
+}
+
+// searchRank stands in for ts_rank: every term must match a word of the
+// title or body, and words of the title count for more. ok is false when
+// the text does not match.
+func searchRank(terms []string, title, body string) (rank float64, ok bool) {
+	for _, term := range terms {
+		n := matches(term, title)*1.0 + matches(term, body)*0.4
+		if n == 0 {
+			return 0, false
+		}
+		rank += n
+	}
+	return rank, true
+}
+
+func matches(term, text string) float64 {
+	var n float64
+	for _, w := range repository.SearchTerms(text) {
+		if wordMatches(term, w) {
+			n++
+		}
+	}
+	return n
+}
+
+// wordMatches is a rough stand-in for matching a word against a prefix
+// query with the english stemmer: the term, or its stem, starts the word.
+func wordMatches(term, word string) bool {
+	return strings.HasPrefix(word, term) || strings.HasPrefix(stem(word), stem(term))
+}
+
+// stem strips the most common english endings.
+func stem(w string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if strings.HasSuffix(w, suffix) && len(w)-len(suffix) >= 3 && !strings.HasSuffix(w, "ss") {
+			return strings.TrimSuffix(w, suffix)
+		}
+	}
+	return w
+}
+
+// searchSnippet marks the matching words of the body, or of the title when
+// the body does not match, like ts_headline.
+func searchSnippet(terms []string, title, body string) string {
+	text := title
+	for _, term := range terms {
+		if matches(term, body) > 0 {
+			text = body
+			break
+		}
+	}
+
+	var b strings.Builder
+	word := []rune{}
+	flush := func() {
+		w := string(word)
+		for _, term := range terms {
+			if w != "" && wordMatches(term, strings.ToLower(w)) {
+				w = "<mark>" + w + "</mark>"
+				break
+			}
+		}
+		b.WriteString(w)
+		word = word[:0]
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word = append(word, r)
+			continue
+		}
+		flush()
+		b.WriteRune(r)
+	}
+	flush()
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+type SubjectRepository struct {
+	s *Store
+}
+
+func (r *SubjectRepository) Create(subject *models.Subject) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	subject.ID = r.s.id("subjects")
+	subject.CreatedAt = createdAt(subject.CreatedAt)
+	subject.Version = version(subject.Version)
+	r.s.subjects[subject.ID] = *subject
+	r.s.track("subjects", nil, *subject)
+	return nil
+}
+
+func (r *SubjectRepository) GetAll(scope repository.Scope) ([]models.Subject, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	subjects := []models.Subject{}
+	for _, s := range r.s.subjects {
+		if scope.Allows(s.UserID) {
+			subjects = append(subjects, s)
+		}
+	}
+	sort.Slice(subjects, func(i, j int) bool { return subjects[i].ID < subjects[j].ID })
+	return subjects, nil
+}
+
+func (r *SubjectRepository) List(scope repository.Scope, q *repository.ListQuery) (repository.Page[models.Subject], error) {
+	subjects, _ := r.GetAll(scope)
+	return list(subjects, repository.SubjectList, q)
+}
+
+func (r *SubjectRepository) GetByID(scope repository.Scope, id uint) (models.Subject, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	s, ok := r.s.subjects[id]
+	if !ok || !scope.Allows(s.UserID) {
+		return models.Subject{}, gorm.ErrRecordNotFound
+	}
+	return s, nil
+}
+
+func (r *SubjectRepository) GetByName(scope repository.Scope, name string) (models.Subject, error) {
+	subjects, _ := r.GetAll(scope)
+	for _, s := range subjects {
+		if strings.EqualFold(s.Name, name) {
+			return s, nil
+		}
+	}
+	return models.Subject{}, gorm.ErrRecordNotFound
+}
+
+func (r *SubjectRepository) Update(scope repository.Scope, subject models.Subject, data map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	s, ok := r.s.subjects[subject.ID]
+	if err := claim(ok, scope, s.UserID, s.Version, subject.Version); err != nil {
+		return err
+	}
+	if err := apply(&s, data); err != nil {
+		return err
+	}
+	old := r.s.subjects[subject.ID]
+	s.Version++
+	r.s.subjects[subject.ID] = s
+	r.s.track("subjects", old, s)
+	return nil
+}
+
+// Delete moves the subject to the trash together with its tasks and their
+// deadlines.
+func (r *SubjectRepository) Delete(scope repository.Scope, subject models.Subject) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	id := subject.ID
+	s, ok := r.s.subjects[id]
+	if err := claim(ok, scope, s.UserID, s.Version, subject.Version); err != nil {
+		return err
+	}
+	at := time.Now()
+	delete(r.s.subjects, id)
+	r.s.track("subjects", s, nil)
+	s.DeletedAt = gorm.DeletedAt{Time: at, Valid: true}
+	s.Version++
+	r.s.trashedSubjects[id] = s
+	for tid, t := range r.s.tasks {
+		if t.SubjectID == id {
+			r.s.trashTask(all, tid, at)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+type SubtaskRepository struct {
+	s *Store
+}
+
+// liveTask returns the task if it is in scope and not in the trash.
+func (s *Store) liveTask(scope repository.Scope, id uint) (models.Task, bool) {
+	t, ok := s.tasks[id]
+	return t, ok && scope.Allows(t.UserID)
+}
+
+func (r *SubtaskRepository) Subtasks(scope repository.Scope, parentID uint) ([]models.Task, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.liveTask(scope, parentID); !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.s.findTasks(func(t models.Task) bool {
+		return scope.Allows(t.UserID) && t.ParentID != nil && *t.ParentID == parentID
+	}), nil
+}
+
+func (r *SubtaskRepository) Descendants(scope repository.Scope, ids []uint) ([]models.Task, []models.ChecklistItem, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	tasks := []models.Task{}
+	all := slices.Clone(ids)
+	for level := ids; len(level) > 0; {
+		subtasks := r.s.findTasks(func(t models.Task) bool {
+			return scope.Allows(t.UserID) && t.ParentID != nil && slices.Contains(level, *t.ParentID)
+		})
+		level = make([]uint, len(subtasks))
+		for i, t := range subtasks {
+			level[i] = t.ID
+		}
+		tasks, all = append(tasks, subtasks...), append(all, level...)
+	}
+
+	items := r.s.findItems(func(i models.ChecklistItem) bool {
+		return scope.Allows(i.UserID) && slices.Contains(all, i.TaskID)
+	})
+	sort.SliceStable(items, func(i, j int) bool { return items[i].TaskID < items[j].TaskID })
+	return tasks, items, nil
+}
+
+func (r *SubtaskRepository) Checklist(scope repository.Scope, taskID uint) ([]models.ChecklistItem, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.liveTask(scope, taskID); !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.s.findItems(func(i models.ChecklistItem) bool { return i.TaskID == taskID }), nil
+}
+
+func (r *SubtaskRepository) GetItem(scope repository.Scope, taskID, id uint) (models.ChecklistItem, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	item, ok := r.s.checklist[id]
+	if _, live := r.s.liveTask(scope, taskID); !live || !ok || item.TaskID != taskID {
+		return models.ChecklistItem{}, gorm.ErrRecordNotFound
+	}
+	return item, nil
+}
+
+func (r *SubtaskRepository) AddItem(scope repository.Scope, item *models.ChecklistItem) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	task, ok := r.s.liveTask(scope, item.TaskID)
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	item.ID = r.s.id("checklist_items")
+	item.UserID = task.UserID
+	item.CreatedAt = createdAt(item.CreatedAt)
+	item.UpdatedAt = item.CreatedAt
+	r.s.checklist[item.ID] = *item
+	return nil
+}
+
+func (r *SubtaskRepository) UpdateItem(scope repository.Scope, item models.ChecklistItem, data map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.checklist[item.ID]
+	if _, live := r.s.liveTask(scope, item.TaskID); !live || !ok || stored.TaskID != item.TaskID {
+		return gorm.ErrRecordNotFound
+	}
+	if err := apply(&stored, data); err != nil {
+		return err
+	}
+	stored.UpdatedAt = time.Now()
+	r.s.checklist[item.ID] = stored
+	return nil
+}
+
+func (r *SubtaskRepository) DeleteItem(scope repository.Scope, item models.ChecklistItem) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.checklist[item.ID]
+	if _, live := r.s.liveTask(scope, item.TaskID); !live || !ok || stored.TaskID != item.TaskID {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.s.checklist, item.ID)
+	return nil
+}
+
+// findItems returns the matching checklist items by position, then id.
+func (s *Store) findItems(match func(models.ChecklistItem) bool) []models.ChecklistItem {
+	items := []models.ChecklistItem{}
+	for _, i := range s.checklist {
+		if match(i) {
+			items = append(items, i)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Position != items[j].Position {
+			return items[i].Position < items[j].Position
+		}
+		return items[i].ID < items[j].ID
+	})
+	return items
+}
@@ -0,0 +1,204 @@
+package memory
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+type TagRepository struct {
+	s *Store
+}
+
+// taskTagKey is a row of the task_tags join table.
+type taskTagKey struct {
+	task, tag uint
+}
+
+func (r *TagRepository) Create(tag *models.Tag) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.s.tagNameTaken(tag.UserID, 0, tag.Name) {
+		return repository.ErrTagExists
+	}
+	tag.ID = r.s.id("tags")
+	tag.CreatedAt = createdAt(tag.CreatedAt)
+	r.s.tags[tag.ID] = *tag
+	return nil
+}
+
+func (r *TagRepository) GetAll(scope repository.Scope) ([]models.Tag, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.findTags(func(t models.Tag) bool { return scope.Allows(t.UserID) }), nil
+}
+
+func (r *TagRepository) GetByID(scope repository.Scope, id uint) (models.Tag, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	tag, ok := r.s.tags[id]
+	if !ok || !scope.Allows(tag.UserID) {
+		return models.Tag{}, gorm.ErrRecordNotFound
+	}
+	return tag, nil
+}
+
+func (r *TagRepository) Update(scope repository.Scope, id uint, data map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	tag, ok := r.s.tags[id]
+	if !ok || !scope.Allows(tag.UserID) {
+		return gorm.ErrRecordNotFound
+	}
+	if name, ok := data["name"].(string); ok && r.s.tagNameTaken(tag.UserID, id, name) {
+		return repository.ErrTagExists
+	}
+	if err := apply(&tag, data); err != nil {
+		return err
+	}
+	r.s.tags[id] = tag
+	r.s.bumpTagged(id)
+	return nil
+}
+
+func (r *TagRepository) Delete(scope repository.Scope, id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	tag, ok := r.s.tags[id]
+	if !ok || !scope.Allows(tag.UserID) {
+		return gorm.ErrRecordNotFound
+	}
+	r.s.bumpTagged(id)
+	for key := range r.s.taskTags {
+		if key.tag == id {
+			delete(r.s.taskTags, key)
+		}
+	}
+	delete(r.s.tags, id)
+	return nil
+}
+
+func (r *TagRepository) Tag(scope repository.Scope, task models.Task, tagIDs []uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if err := r.s.taggable(scope, task, tagIDs); err != nil {
+		return err
+	}
+	changed := false
+	for _, id := range tagIDs {
+		key := taskTagKey{task.ID, id}
+		if !r.s.taskTags[key] {
+			r.s.taskTags[key] = true
+			changed = true
+		}
+	}
+	if changed {
+		r.s.bumpTask(task.ID)
+	}
+	return nil
+}
+
+func (r *TagRepository) Untag(scope repository.Scope, task models.Task, tagIDs []uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if err := r.s.taggable(scope, task, tagIDs); err != nil {
+		return err
+	}
+	changed := false
+	for _, id := range tagIDs {
+		key := taskTagKey{task.ID, id}
+		if r.s.taskTags[key] {
+			delete(r.s.taskTags, key)
+			changed = true
+		}
+	}
+	if changed {
+		r.s.bumpTask(task.ID)
+	}
+	return nil
+}
+
+// taggable checks that task is still at the version it was read at and
+// that the tags belong to its owner.
+func (s *Store) taggable(scope repository.Scope, task models.Task, tagIDs []uint) error {
+	if err := s.claimTask(scope, task); err != nil {
+		return err
+	}
+	owner := s.tasks[task.ID].UserID
+	for _, id := range tagIDs {
+		if tag, ok := s.tags[id]; !ok || tag.UserID != owner {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	return nil
+}
+
+func (s *Store) tagNameTaken(userID, id uint, name string) bool {
+	for _, t := range s.tags {
+		if t.UserID == userID && t.ID != id && strings.EqualFold(t.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// bumpTagged moves on the version of the live tasks tagged with tagID.
+func (s *Store) bumpTagged(tagID uint) {
+	for key := range s.taskTags {
+		if key.tag == tagID {
+			s.bumpTask(key.task)
+		}
+	}
+}
+
+func (s *Store) bumpTask(id uint) {
+	if t, ok := s.tasks[id]; ok {
+		t.Version++
+		s.putTask(t)
+	}
+}
+
+// tagsOf returns the tags of a task by name.
+func (s *Store) tagsOf(taskID uint) []models.Tag {
+	return s.findTags(func(t models.Tag) bool { return s.taskTags[taskTagKey{taskID, t.ID}] })
+}
+
+// taggedWith counts the tags of the task with one of the names, which are
+// lower case.
+func (s *Store) taggedWith(taskID uint, names []string) int {
+	n := 0
+	for _, t := range s.tagsOf(taskID) {
+		if slices.Contains(names, strings.ToLower(t.Name)) {
+			n++
+		}
+	}
+	return n
+}
+
+// findTags returns the matching tags by name.
+func (s *Store) findTags(match func(models.Tag) bool) []models.Tag {
+	tags := []models.Tag{}
+	for _, t := range s.tags {
+		if match(t) {
+			tags = append(tags, t)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if a, b := strings.ToLower(tags[i].Name), strings.ToLower(tags[j].Name); a != b {
+			return a < b
+		}
+		return tags[i].ID < tags[j].ID
+	})
+	return tags
+}
@@ -0,0 +1,319 @@
+package memory
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/recurrence"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+// all is the scope of the bookkeeping writes that the gorm repository
+// makes without a scope.
+var all = repository.Scope{Admin: true}
+
+func (r *TaskRepository) CreateSeries(task *models.Task) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.atomic(func() error {
+		if err := r.s.createTask(task); err != nil {
+			return err
+		}
+		r.s.createDeadline(&models.Deadline{TaskID: task.ID, UserID: task.UserID, DueDate: task.Deadline})
+		return nil
+	})
+}
+
+func (r *TaskRepository) GetSeriesMasters() ([]models.Task, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.findTasks(func(t models.Task) bool { return t.IsSeriesMaster() }), nil
+}
+
+func (r *TaskRepository) CreateOccurrence(master models.Task, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.createOccurrence(master, at)
+}
+
+func (r *TaskRepository) SetGeneratedUntil(id uint, until time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if t, ok := r.s.tasks[id]; ok {
+		t.RecurrenceGeneratedUntil = &until
+		r.s.putTask(t)
+	}
+	return nil
+}
+
+func (r *TaskRepository) UpdateOccurrence(scope repository.Scope, task models.Task, data map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.atomic(func() error {
+		if err := r.s.claimTask(scope, task); err != nil {
+			return err
+		}
+		series := task.IsSeriesMaster() || task.SeriesID != nil
+		if task.IsSeriesMaster() {
+			if err := r.s.detachMaster(task); err != nil {
+				return err
+			}
+		}
+		if err := r.s.updateTask(scope, task.ID, data); err != nil {
+			return err
+		}
+		if _, ok := data["deadline"]; ok && series {
+			r.s.moveDeadline(task.ID)
+		}
+		return nil
+	})
+}
+
+func (r *TaskRepository) UpdateFollowing(scope repository.Scope, task models.Task, data map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.atomic(func() error {
+		if err := r.s.claimTask(scope, task); err != nil {
+			return err
+		}
+		if task.SeriesID != nil {
+			var err error
+			if task, err = r.s.splitAt(task); err != nil {
+				return err
+			}
+		}
+		series := task.IsSeriesMaster()
+		if err := r.s.updateTask(scope, task.ID, data); err != nil {
+			return err
+		}
+		_, startChanged := data["deadline"]
+		if startChanged && series {
+			r.s.moveDeadline(task.ID)
+		}
+		if !series {
+			return nil
+		}
+
+		_, ruleChanged := data["recurrence_rule"]
+		if ruleChanged || startChanged {
+			now, initial := time.Now(), task.Subject.TaskWorkflow().Initial()
+			for id, t := range r.s.tasks {
+				if t.SeriesID != nil && *t.SeriesID == task.ID && t.Status == initial &&
+					t.OccurrenceAt != nil && t.OccurrenceAt.After(now) {
+					r.s.purgeTask(id)
+				}
+			}
+			master := r.s.tasks[task.ID]
+			master.RecurrenceGeneratedUntil = nil
+			r.s.putTask(master)
+		}
+
+		shared := map[string]interface{}{}
+		for _, f := range repository.SeriesFields {
+			if v, ok := data[f]; ok {
+				shared[f] = v
+			}
+		}
+		if len(shared) == 0 {
+			return nil
+		}
+		for id, t := range r.s.tasks {
+			if t.SeriesID != nil && *t.SeriesID == task.ID {
+				if err := r.s.updateTask(all, id, shared); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (r *TaskRepository) DeleteFollowing(scope repository.Scope, task models.Task) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.atomic(func() error {
+		if err := r.s.claimTask(scope, task); err != nil {
+			return err
+		}
+		if task.SeriesID != nil {
+			var err error
+			if task, err = r.s.splitAt(task); err != nil {
+				return err
+			}
+		}
+		at := time.Now()
+		for id, t := range r.s.tasks {
+			if t.SeriesID != nil && *t.SeriesID == task.ID {
+				r.s.trashTask(all, id, at)
+			}
+		}
+		return r.s.trashTask(scope, task.ID, at)
+	})
+}
+
+func (r *TaskRepository) DeleteOccurrence(scope repository.Scope, task models.Task) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.atomic(func() error {
+		if err := r.s.claimTask(scope, task); err != nil {
+			return err
+		}
+		if task.IsSeriesMaster() {
+			if err := r.s.detachMaster(task); err != nil {
+				return err
+			}
+		}
+		return r.s.trashTask(scope, task.ID, time.Now())
+	})
+}
+
+// atomic runs fn like a transaction: when it fails, every table is put
+// back the way it was.
+func (s *Store) atomic(fn func() error) error {
+	users, subjects := maps.Clone(s.users), maps.Clone(s.subjects)
+	tasks, deadlines := maps.Clone(s.tasks), maps.Clone(s.deadlines)
+	trashedSubjects, trashedTasks, trashedDeadlines := maps.Clone(s.trashedSubjects), maps.Clone(s.trashedTasks), maps.Clone(s.trashedDeadlines)
+	dependencies, checklist, nextID := maps.Clone(s.dependencies), maps.Clone(s.checklist), maps.Clone(s.nextID)
+	tags, taskTags := maps.Clone(s.tags), maps.Clone(s.taskTags)
+	changes, seq := slices.Clone(s.changes), s.seq
+
+	err := fn()
+	if err != nil {
+		s.users, s.subjects, s.tasks, s.deadlines, s.nextID = users, subjects, tasks, deadlines, nextID
+		s.trashedSubjects, s.trashedTasks, s.trashedDeadlines = trashedSubjects, trashedTasks, trashedDeadlines
+		s.dependencies, s.checklist = dependencies, checklist
+		s.tags, s.taskTags = tags, taskTags
+		s.changes, s.seq = changes, seq
+	}
+	return err
+}
+
+// moveDeadline moves the deadline rows of a series task to its deadline.
+func (s *Store) moveDeadline(id uint) {
+	if t, ok := s.tasks[id]; ok {
+		s.setDeadline(t, t.Deadline)
+	}
+}
+
+func (s *Store) createOccurrence(master models.Task, at time.Time) error {
+	seriesID := master.ID
+	if s.occurrenceExists(&seriesID, &at) {
+		return nil
+	}
+	task := models.Task{
+		Title:           master.Title,
+		Description:     master.Description,
+		Status:          master.Subject.TaskWorkflow().Initial(),
+		EstimateMinutes: master.EstimateMinutes,
+		Deadline:        at,
+		SubjectID:       master.SubjectID,
+		UserID:          master.UserID,
+		SeriesID:        &seriesID,
+		OccurrenceAt:    &at,
+	}
+	if err := s.createTask(&task); err != nil {
+		return err
+	}
+	s.createDeadline(&models.Deadline{TaskID: task.ID, UserID: task.UserID, DueDate: at})
+	return nil
+}
+
+// firstOccurrence returns the earliest generated occurrence of a series.
+func (s *Store) firstOccurrence(seriesID uint) (models.Task, bool) {
+	var first models.Task
+	found := false
+	for _, t := range s.tasks {
+		if t.SeriesID == nil || *t.SeriesID != seriesID || t.OccurrenceAt == nil {
+			continue
+		}
+		if !found || t.OccurrenceAt.Before(*first.OccurrenceAt) {
+			first, found = cloneTask(t), true
+		}
+	}
+	return first, found
+}
+
+func (s *Store) setRule(id uint, rule string) {
+	if t, ok := s.tasks[id]; ok {
+		t.RecurrenceRule = rule
+		t.Version++
+		s.putTask(t)
+	}
+}
+
+func (s *Store) detachMaster(master models.Task) error {
+	rule, err := recurrence.Parse(master.RecurrenceRule)
+	if err != nil {
+		return err
+	}
+
+	next, ok := s.firstOccurrence(master.ID)
+	if !ok {
+		at, more := rule.Next(master.Deadline, master.Deadline)
+		if !more {
+			s.setRule(master.ID, "")
+			return nil
+		}
+		if err := s.createOccurrence(master, at); err != nil {
+			return err
+		}
+		next, _ = s.firstOccurrence(master.ID)
+	}
+
+	s.promote(master, rule, next)
+	s.setRule(master.ID, "")
+	return nil
+}
+
+func (s *Store) splitAt(occurrence models.Task) (models.Task, error) {
+	master, ok := s.tasks[*occurrence.SeriesID]
+	if !ok {
+		return occurrence, gorm.ErrRecordNotFound
+	}
+	rule, err := recurrence.Parse(master.RecurrenceRule)
+	if err != nil {
+		return occurrence, err
+	}
+
+	promoted := s.promote(master, rule, occurrence)
+	s.setRule(master.ID, rule.Truncate(master.Deadline, *occurrence.OccurrenceAt).String())
+	return promoted, nil
+}
+
+func (s *Store) promote(master models.Task, rule recurrence.Rule, occurrence models.Task) models.Task {
+	at := *occurrence.OccurrenceAt
+	remainder := rule.Remainder(master.Deadline, at)
+
+	for _, t := range s.tasks {
+		if t.SeriesID != nil && *t.SeriesID == master.ID && t.OccurrenceAt != nil && t.OccurrenceAt.After(at) {
+			newID := occurrence.ID
+			t.SeriesID = &newID
+			t.Version++
+			s.putTask(t)
+		}
+	}
+
+	occurrence.RecurrenceRule = remainder.String()
+	occurrence.SeriesID = nil
+	occurrence.RecurrenceGeneratedUntil = cloneTime(master.RecurrenceGeneratedUntil)
+
+	if t, ok := s.tasks[occurrence.ID]; ok {
+		t.RecurrenceRule = occurrence.RecurrenceRule
+		t.SeriesID = nil
+		t.RecurrenceGeneratedUntil = cloneTime(master.RecurrenceGeneratedUntil)
+		t.Version++
+		s.putTask(t)
+	}
+	return occurrence
+}
@@ -0,0 +1,329 @@
+package memory
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+type TaskRepository struct {
+	s *Store
+}
+
+func (r *TaskRepository) Create(task *models.Task) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.createTask(task)
+}
+
+func (r *TaskRepository) GetAll(scope repository.Scope) ([]models.Task, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.findTasks(func(t models.Task) bool { return scope.Allows(t.UserID) }), nil
+}
+
+func (r *TaskRepository) GetOpenOrDueAfter(scope repository.Scope, t time.Time) ([]models.Task, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	tasks := r.s.findTasks(func(task models.Task) bool {
+		return scope.Allows(task.UserID) && ((task.CompletedAt == nil && task.Status != "done") || task.Deadline.After(t))
+	})
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].Deadline.Before(tasks[j].Deadline) })
+	return tasks, nil
+}
+
+func (r *TaskRepository) GetByID(scope repository.Scope, id uint) (models.Task, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tasks[id]
+	if !ok || !scope.Allows(t.UserID) {
+		return models.Task{}, gorm.ErrRecordNotFound
+	}
+	return r.s.loadTask(id, true), nil
+}
+
+func (r *TaskRepository) GetByExternalUID(scope repository.Scope, uid string) (models.Task, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	tasks := r.s.findTasks(func(t models.Task) bool {
+		return scope.Allows(t.UserID) && t.ExternalUID != nil && *t.ExternalUID == uid
+	})
+	if len(tasks) == 0 {
+		return models.Task{}, gorm.ErrRecordNotFound
+	}
+	return tasks[0], nil
+}
+
+func (r *TaskRepository) Update(scope repository.Scope, id uint, data map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.updateTask(scope, id, data)
+}
+
+func (r *TaskRepository) Delete(scope repository.Scope, id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.trashTask(scope, id, time.Now())
+}
+
+// taskSorts mirrors the sort whitelist of the gorm repository.
+var taskSorts = map[string]func(a, b models.Task) bool{
+	"created_at":      func(a, b models.Task) bool { return a.CreatedAt.Before(b.CreatedAt) },
+	"created_at desc": func(a, b models.Task) bool { return a.CreatedAt.After(b.CreatedAt) },
+	"deadline":        func(a, b models.Task) bool { return a.Deadline.Before(b.Deadline) },
+	"deadline desc":   func(a, b models.Task) bool { return a.Deadline.After(b.Deadline) },
+	"title":           func(a, b models.Task) bool { return a.Title < b.Title },
+	"title desc":      func(a, b models.Task) bool { return a.Title > b.Title },
+}
+
+// filtered returns the tasks that match the filter, by id.
+func (s *Store) filtered(scope repository.Scope, filter *repository.TaskFilter) []models.Task {
+	status := strings.TrimSpace(filter.Status)
+	terms := repository.SearchTerms(filter.Search)
+	tagsAny, tagsAll, tagsNone := repository.TagNames(filter.TagsAny), repository.TagNames(filter.TagsAll), repository.TagNames(filter.TagsNone)
+
+	return s.findTasks(func(t models.Task) bool {
+		switch {
+		case !scope.Allows(t.UserID):
+			return false
+		case status != "" && t.Status != filter.Status:
+			return false
+		case filter.SubjectID != nil && t.SubjectID != *filter.SubjectID:
+			return false
+		case len(terms) > 0 && !searched(terms, t):
+			return false
+		case filter.DeadlineBefore != nil && t.Deadline.After(*filter.DeadlineBefore):
+			return false
+		case filter.DeadlineAfter != nil && t.Deadline.Before(*filter.DeadlineAfter):
+			return false
+		case filter.TopLevel && t.ParentID != nil:
+			return false
+		case len(tagsAny) > 0 && s.taggedWith(t.ID, tagsAny) == 0:
+			return false
+		case len(tagsAll) > 0 && s.taggedWith(t.ID, tagsAll) < len(tagsAll):
+			return false
+		case len(tagsNone) > 0 && s.taggedWith(t.ID, tagsNone) > 0:
+			return false
+		case filter.Where != nil && !s.where(*filter.Where, t):
+			return false
+		}
+		return true
+	})
+}
+
+func (r *TaskRepository) GetTasks(scope repository.Scope, filter *repository.TaskFilter) ([]models.Task, int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	tasks := r.s.filtered(scope, filter)
+	terms := repository.SearchTerms(filter.Search)
+	total := int64(len(tasks))
+	if filter.SkipTotal {
+		total = -1
+	}
+
+	less, ok := taskSorts[strings.TrimSpace(filter.Sort)]
+	if !ok {
+		less = taskSorts["created_at desc"]
+	}
+	sort.SliceStable(tasks, func(i, j int) bool { return less(tasks[i], tasks[j]) })
+	if strings.TrimSpace(filter.Sort) == "" && len(terms) > 0 {
+		// best matches first
+		sort.SliceStable(tasks, func(i, j int) bool {
+			a, _ := searchRank(terms, tasks[i].Title, tasks[i].Description)
+			b, _ := searchRank(terms, tasks[j].Title, tasks[j].Description)
+			return a > b
+		})
+	}
+
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	if offset >= len(tasks) {
+		return []models.Task{}, total, nil
+	}
+	end := min(offset+filter.Limit, len(tasks))
+	return tasks[offset:end], total, nil
+}
+
+func (r *TaskRepository) GetTaskPage(scope repository.Scope, filter *repository.TaskFilter, cursor *repository.Cursor) (repository.Page[models.Task], error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	q := &repository.ListQuery{
+		Limit:     filter.Limit,
+		Sort:      repository.TaskList.SortOrDefault(filter.Sort),
+		Cursors:   true,
+		Cursor:    cursor,
+		SkipTotal: filter.SkipTotal,
+	}
+	page, err := list(r.s.filtered(scope, filter), repository.TaskList, q)
+	filter.Limit = q.Limit
+	return page, err
+}
+
+func (s *Store) createTask(task *models.Task) error {
+	if task.ExternalUID != nil {
+		for _, t := range s.tasks {
+			if t.UserID == task.UserID && t.ExternalUID != nil && *t.ExternalUID == *task.ExternalUID {
+				return errors.New("memory: duplicate key value violates unique constraint \"idx_task_external_uid\"")
+			}
+		}
+	}
+	if s.occurrenceExists(task.SeriesID, task.OccurrenceAt) {
+		return errors.New("memory: duplicate key value violates unique constraint \"idx_task_occurrence\"")
+	}
+
+	task.ID = s.id("tasks")
+	task.CreatedAt = createdAt(task.CreatedAt)
+	task.Version = version(task.Version)
+	stored := cloneTask(*task)
+	stored.Subject, stored.Tags = models.Subject{}, nil
+	s.putTask(stored)
+	return nil
+}
+
+func (s *Store) occurrenceExists(seriesID *uint, at *time.Time) bool {
+	if seriesID == nil || at == nil {
+		return false
+	}
+	// the unique index covers the trash too
+	for _, tasks := range []map[uint]models.Task{s.tasks, s.trashedTasks} {
+		for _, t := range tasks {
+			if t.SeriesID != nil && *t.SeriesID == *seriesID && t.OccurrenceAt != nil && t.OccurrenceAt.Equal(*at) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Store) updateTask(scope repository.Scope, id uint, data map[string]interface{}) error {
+	t, ok := s.tasks[id]
+	if !ok || !scope.Allows(t.UserID) {
+		return gorm.ErrRecordNotFound
+	}
+	t = cloneTask(t)
+	if err := apply(&t, data); err != nil {
+		return err
+	}
+	t.Version++
+	s.putTask(t)
+	return nil
+}
+
+// claimTask checks that task is still at the version it was read at.
+func (s *Store) claimTask(scope repository.Scope, task models.Task) error {
+	t, ok := s.tasks[task.ID]
+	return claim(ok, scope, t.UserID, t.Version, task.Version)
+}
+
+// trashTask moves the task, its subtasks and its deadlines to the trash at.
+func (s *Store) trashTask(scope repository.Scope, id uint, at time.Time) error {
+	t, ok := s.tasks[id]
+	if !ok || !scope.Allows(t.UserID) {
+		return gorm.ErrRecordNotFound
+	}
+	for sid, sub := range s.tasks {
+		if sub.ParentID != nil && *sub.ParentID == id {
+			s.trashTask(all, sid, at)
+		}
+	}
+	for did, d := range s.deadlines {
+		if d.TaskID == id {
+			s.trashDeadline(d, at)
+			delete(s.deadlines, did)
+		}
+	}
+	delete(s.tasks, id)
+	s.track("tasks", t, nil)
+	t.DeletedAt = gorm.DeletedAt{Time: at, Valid: true}
+	t.Version++
+	s.trashedTasks[id] = t
+	return nil
+}
+
+// purgeTask deletes a task for good, from the trash too, and like ON
+// DELETE CASCADE its subtasks, checklist and deadlines.
+func (s *Store) purgeTask(id uint) {
+	for _, tasks := range []map[uint]models.Task{s.tasks, s.trashedTasks} {
+		for sid, sub := range tasks {
+			if sub.ParentID != nil && *sub.ParentID == id {
+				s.purgeTask(sid)
+			}
+		}
+	}
+	for iid, item := range s.checklist {
+		if item.TaskID == id {
+			delete(s.checklist, iid)
+		}
+	}
+	for key := range s.taskTags {
+		if key.task == id {
+			delete(s.taskTags, key)
+		}
+	}
+	for _, deadlines := range []map[uint]models.Deadline{s.deadlines, s.trashedDeadlines} {
+		for did, d := range deadlines {
+			if d.TaskID == id {
+				delete(deadlines, did)
+				if !d.DeletedAt.Valid {
+					s.track("deadlines", d, nil)
+				}
+			}
+		}
+	}
+	if t, ok := s.tasks[id]; ok {
+		delete(s.tasks, id)
+		s.track("tasks", t, nil)
+	}
+	delete(s.trashedTasks, id)
+	for key := range s.dependencies {
+		if key.task == id || key.blocker == id {
+			delete(s.dependencies, key)
+		}
+	}
+}
+
+// putTask stores t, logging the change.
+func (s *Store) putTask(t models.Task) {
+	if old, ok := s.tasks[t.ID]; ok {
+		s.tasks[t.ID] = t
+		s.track("tasks", old, t)
+		return
+	}
+	s.tasks[t.ID] = t
+	s.track("tasks", nil, t)
+}
+
+// findTasks returns the matching tasks by id, with their subject preloaded.
+func (s *Store) findTasks(match func(models.Task) bool) []models.Task {
+	tasks := []models.Task{}
+	for id, t := range s.tasks {
+		if match(t) {
+			tasks = append(tasks, s.loadTask(id, true))
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks
+}
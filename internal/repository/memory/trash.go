@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"slices"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+type TrashRepository struct {
+	s *Store
+}
+
+func (r *TrashRepository) List(scope repository.Scope) ([]models.TrashItem, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var subjects []models.Subject
+	var tasks []models.Task
+	var deadlines []models.Deadline
+	for _, s := range r.s.trashedSubjects {
+		if scope.Allows(s.UserID) {
+			subjects = append(subjects, s)
+		}
+	}
+	for _, t := range r.s.trashedTasks {
+		if scope.Allows(t.UserID) {
+			tasks = append(tasks, cloneTask(t))
+		}
+	}
+	for _, d := range r.s.trashedDeadlines {
+		if scope.Allows(d.UserID) {
+			d.Task = r.s.tasks[d.TaskID]
+			if t, ok := r.s.trashedTasks[d.TaskID]; ok {
+				d.Task = t
+			}
+			deadlines = append(deadlines, d)
+		}
+	}
+	return repository.TrashItems(subjects, tasks, deadlines), nil
+}
+
+func (r *TrashRepository) Restore(scope repository.Scope, resource string, id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	switch resource {
+	case models.ResourceSubjects:
+		s, ok := r.s.trashedSubjects[id]
+		if !ok || !scope.Allows(s.UserID) {
+			return gorm.ErrRecordNotFound
+		}
+		at := s.DeletedAt.Time
+		var ids []uint
+		for tid, t := range r.s.trashedTasks {
+			if t.SubjectID == id && t.DeletedAt.Time.Equal(at) {
+				ids = append(ids, tid)
+			}
+		}
+		r.s.restoreTasks(s.UserID, ids, at)
+		delete(r.s.trashedSubjects, id)
+		s.DeletedAt = gorm.DeletedAt{}
+		s.Version++
+		r.s.subjects[id] = s
+		r.s.track("subjects", nil, s)
+		return nil
+
+	case models.ResourceTasks:
+		t, ok := r.s.trashedTasks[id]
+		if !ok || !scope.Allows(t.UserID) {
+			return gorm.ErrRecordNotFound
+		}
+		if _, ok := r.s.subjects[t.SubjectID]; !ok {
+			return repository.ErrParentDeleted
+		}
+		for _, parent := range []*uint{t.SeriesID, t.ParentID} {
+			if parent == nil {
+				continue
+			}
+			if _, ok := r.s.tasks[*parent]; !ok {
+				return repository.ErrParentDeleted
+			}
+		}
+		at := t.DeletedAt.Time
+		ids := []uint{id}
+		for oid, o := range r.s.trashedTasks {
+			if o.SeriesID != nil && *o.SeriesID == id && o.DeletedAt.Time.Equal(at) {
+				ids = append(ids, oid)
+			}
+		}
+		r.s.restoreTasks(t.UserID, ids, at)
+		return nil
+
+	case models.ResourceDeadlines:
+		d, ok := r.s.trashedDeadlines[id]
+		if !ok || !scope.Allows(d.UserID) {
+			return gorm.ErrRecordNotFound
+		}
+		if _, ok := r.s.tasks[d.TaskID]; !ok {
+			return repository.ErrParentDeleted
+		}
+		r.s.restoreDeadline(d)
+		return nil
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *TrashRepository) Purge(before time.Time) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var n int64
+	for id, d := range r.s.trashedDeadlines {
+		if d.DeletedAt.Time.Before(before) {
+			delete(r.s.trashedDeadlines, id)
+			n++
+		}
+	}
+	// collected first: purging a task takes its subtasks with it
+	var tasks []uint
+	for id, t := range r.s.trashedTasks {
+		if t.DeletedAt.Time.Before(before) {
+			tasks = append(tasks, id)
+		}
+	}
+	for _, id := range tasks {
+		r.s.purgeTask(id)
+	}
+	n += int64(len(tasks))
+	for id, s := range r.s.trashedSubjects {
+		if s.DeletedAt.Time.Before(before) && !r.s.subjectInUse(id) {
+			delete(r.s.trashedSubjects, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// subjectInUse reports whether any task, in the trash or not, points to
+// the subject.
+func (s *Store) subjectInUse(id uint) bool {
+	for _, tasks := range []map[uint]models.Task{s.tasks, s.trashedTasks} {
+		for _, t := range tasks {
+			if t.SubjectID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// restoreTasks takes the tasks of one user and their subtasks and
+// deadlines deleted at out of the trash, unlinking tasks from calendar
+// entries that have been imported again meanwhile.
+func (s *Store) restoreTasks(userID uint, ids []uint, at time.Time) {
+	restoring := map[uint]bool{}
+	for _, id := range ids {
+		restoring[id] = true
+	}
+	for level := ids; len(level) > 0; {
+		var subtasks []uint
+		for sid, t := range s.trashedTasks {
+			if t.ParentID != nil && slices.Contains(level, *t.ParentID) && t.DeletedAt.Time.Equal(at) && !restoring[sid] {
+				subtasks = append(subtasks, sid)
+				restoring[sid] = true
+			}
+		}
+		ids, level = append(ids, subtasks...), subtasks
+	}
+	imported := map[string]bool{}
+	for _, t := range s.tasks {
+		if t.UserID == userID && t.ExternalUID != nil {
+			imported[*t.ExternalUID] = true
+		}
+	}
+	for _, d := range s.trashedDeadlines {
+		for _, id := range ids {
+			if d.TaskID == id && d.DeletedAt.Time.Equal(at) {
+				s.restoreDeadline(d)
+			}
+		}
+	}
+	for _, id := range ids {
+		t := s.trashedTasks[id]
+		if t.ExternalUID != nil && imported[*t.ExternalUID] {
+			t.ExternalUID = nil
+		}
+		delete(s.trashedTasks, id)
+		t.DeletedAt = gorm.DeletedAt{}
+		t.Version++
+		s.putTask(t)
+	}
+}
+
+func (s *Store) restoreDeadline(d models.Deadline) {
+	delete(s.trashedDeadlines, d.ID)
+	d.DeletedAt = gorm.DeletedAt{}
+	d.Version++
+	s.deadlines[d.ID] = d
+	s.track("deadlines", nil, d)
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+type UserRepository struct {
+	s *Store
+}
+
+func (r *UserRepository) Create(user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, u := range r.s.users {
+		if u.Email == user.Email {
+			return errors.New("memory: duplicate key value violates unique constraint \"idx_users_email\"")
+		}
+	}
+	user.ID = r.s.id("users")
+	user.CreatedAt = createdAt(user.CreatedAt)
+	r.s.users[user.ID] = *user
+	return nil
+}
+
+func (r *UserRepository) GetAll() ([]models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	users := make([]models.User, 0, len(r.s.users))
+	for _, u := range r.s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (r *UserRepository) List(q *repository.ListQuery) (repository.Page[models.User], error) {
+	users, _ := r.GetAll()
+	return list(users, repository.UserList, q)
+}
+
+func (r *UserRepository) GetByID(id uint) (models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[id]
+	if !ok {
+		return models.User{}, gorm.ErrRecordNotFound
+	}
+	return u, nil
+}
+
+func (r *UserRepository) GetByEmail(email string) (models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, u := range r.s.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return models.User{}, gorm.ErrRecordNotFound
+}
+
+func (r *UserRepository) Update(id uint, data map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if err := apply(&u, data); err != nil {
+		return err
+	}
+	r.s.users[id] = u
+	return nil
+}
+
+// Delete, like the gorm repository, does not report a missing user.
+func (r *UserRepository) Delete(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.users, id)
+	return nil
+}
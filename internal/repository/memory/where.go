@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"github.com/kadyrbayev2005/studysync/internal/rsql"
+)
+
+// where reports whether a task matches a filter expression the way the
+// compiled SQL does.
+func (s *Store) where(w repository.Where, t models.Task) bool {
+	switch {
+	case w.And != nil:
+		return !slices.ContainsFunc(w.And, func(w repository.Where) bool { return !s.where(w, t) })
+	case w.Or != nil:
+		return slices.ContainsFunc(w.Or, func(w repository.Where) bool { return s.where(w, t) })
+	}
+
+	switch w.Field {
+	case "subject":
+		return compared(w.Positive(), s.subjects[t.SubjectID].Name) != w.Negated()
+	case "tag":
+		tagged := slices.ContainsFunc(s.tagsOf(t.ID), func(tag models.Tag) bool { return compared(w.Positive(), tag.Name) })
+		return tagged != w.Negated()
+	}
+
+	v := reflect.ValueOf(repository.ColumnValue(t, repository.TaskFields[w.Field].Column))
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return (len(w.Values) == 1 && w.Values[0] == nil) != w.Negated()
+		}
+		v = v.Elem()
+	}
+	if len(w.Values) == 1 && w.Values[0] == nil {
+		return w.Negated()
+	}
+	return compared(w, v.Interface())
+}
+
+// compared compares a value that is there with the values of w.
+func compared(w repository.Where, v interface{}) bool {
+	if text, ok := v.(string); ok {
+		v = strings.ToLower(text)
+	}
+	if p := w.Pattern(); p != "" {
+		return like(p, v.(string)) != w.Negated()
+	}
+
+	switch c := compareKeys(v, w.Values[0]); w.Op {
+	case rsql.Equal:
+		return c == 0
+	case rsql.NotEqual:
+		return c != 0
+	case rsql.Less:
+		return c < 0
+	case rsql.LessOrEqual:
+		return c <= 0
+	case rsql.Greater:
+		return c > 0
+	case rsql.GreaterOrEqual:
+		return c >= 0
+	}
+	in := slices.ContainsFunc(w.Values, func(x interface{}) bool { return compareKeys(v, x) == 0 })
+	return in != w.Negated()
+}
+
+// like matches s against a pattern where * is any run of characters.
+func like(pattern, s string) bool {
+	expr := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, `.*`)
+	return regexp.MustCompile(`(?s)^` + expr + `$`).MatchString(s)
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db}
+}
+
+// Enqueue inserts a pending notification unless one already exists for the
+// same deadline, channel and offset.
+func (r *NotificationRepository) Enqueue(n *models.Notification) error {
+	if n.Status == "" {
+		n.Status = models.NotificationPending
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(n).Error
+}
+
+// ClaimDue claims up to limit notifications that still need a delivery
+// attempt and returns them. Reminders for deadlines that have already passed
+// or are in the trash are left alone. Claiming moves their next attempt a
+// lease ahead in the same transaction that locks them, skipping rows another
+// worker has locked, so workers in several processes never send the same
+// reminder twice; MarkSent, MarkFailed or Release settle the claim, and one
+// that is never settled is picked up again when the lease runs out.
+func (r *NotificationRepository) ClaimDue(now time.Time, lease time.Duration, maxAttempts, limit int) ([]models.Notification, error) {
+	var ids []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Notification{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "notifications"}, Options: "SKIP LOCKED"}).
+			Joins("JOIN deadlines ON deadlines.id = notifications.deadline_id").
+			Where("notifications.status IN ? AND notifications.next_attempt_at <= ? AND notifications.attempts < ?",
+				[]string{models.NotificationPending, models.NotificationFailed}, now, maxAttempts).
+			Where("deadlines.due_date > ? AND deadlines.deleted_at IS NULL", now).
+			Order("notifications.next_attempt_at").
+			Limit(limit).
+			Pluck("notifications.id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		return tx.Model(&models.Notification{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var ns []models.Notification
+	err = r.db.Preload("Deadline.Task.Subject").Preload("Deadline.User").
+		Where("id IN ?", ids).Order("id").Find(&ns).Error
+	return ns, err
+}
+
+// Release hands claimed notifications back for an attempt at the given
+// time, without counting one.
+func (r *NotificationRepository) Release(ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&models.Notification{}).Where("id IN ?", ids).
+		Update("next_attempt_at", at).Error
+}
+
+func (r *NotificationRepository) MarkSent(id uint, at time.Time) error {
+	return r.db.Model(&models.Notification{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.NotificationSent,
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": "",
+		"sent_at":    at,
+	}).Error
+}
+
+// MarkFailed records a failed attempt and schedules the next one.
+func (r *NotificationRepository) MarkFailed(id uint, cause string, next time.Time) error {
+	return r.db.Model(&models.Notification{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          models.NotificationFailed,
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      cause,
+		"next_attempt_at": next,
+	}).Error
+}
+
+// GetAllByUser returns every notification of the user, newest first.
+func (r *NotificationRepository) GetAllByUser(userID uint) ([]models.Notification, error) {
+	var ns []models.Notification
+	err := r.db.Where("user_id = ?", userID).Order("created_at desc").Find(&ns).Error
+	return ns, err
+}
+
+// DeleteSettledBefore removes sent and failed notifications last touched
+// before the given time. Pending ones are kept for the worker; failed ones
+// are retried within the hour, so older ones are final.
+func (r *NotificationRepository) DeleteSettledBefore(before time.Time) (int64, error) {
+	tx := r.db.Where("status <> ? AND updated_at < ?", models.NotificationPending, before).Delete(&models.Notification{})
+	return tx.RowsAffected, tx.Error
+}
+
+func (r *NotificationRepository) GetByUser(userID uint, page, limit int) ([]models.Notification, int64, error) {
+	var ns []models.Notification
+	var total int64
+
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	tx := r.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := tx.Order("created_at desc").Limit(limit).Offset((page - 1) * limit).Find(&ns).Error
+	return ns, total, err
+}
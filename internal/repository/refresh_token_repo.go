@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db}
+}
+
+func (r *RefreshTokenRepository) Create(t *models.RefreshToken) error {
+	return r.db.Create(t).Error
+}
+
+func (r *RefreshTokenRepository) GetByHash(hash string) (models.RefreshToken, error) {
+	var t models.RefreshToken
+	err := r.db.Where("token_hash = ?", hash).First(&t).Error
+	return t, err
+}
+
+// MarkUsed flags a live token as rotated. It returns gorm.ErrRecordNotFound
+// if the token was already used or revoked, which callers treat as reuse.
+func (r *RefreshTokenRepository) MarkUsed(id uint, at time.Time) error {
+	return affected(r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", at))
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(familyID string, at time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+func (r *RefreshTokenRepository) RevokeAllForUser(userID uint, at time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
+
+// DeleteExpired removes tokens that expired before the given time.
+func (r *RefreshTokenRepository) DeleteExpired(before time.Time) (int64, error) {
+	tx := r.db.Where("expires_at < ?", before).Delete(&models.RefreshToken{})
+	return tx.RowsAffected, tx.Error
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrReminderExists is returned by Create for an offset the deadline
+// already has.
+var ErrReminderExists = errors.New("repository: reminder already exists")
+
+type ReminderRepository struct {
+	db *gorm.DB
+}
+
+func NewReminderRepository(db *gorm.DB) *ReminderRepository {
+	return &ReminderRepository{db}
+}
+
+func (r *ReminderRepository) GetUserDefaults(userID uint) ([]models.ReminderOffset, error) {
+	var rs []models.ReminderOffset
+	err := r.db.Where("user_id = ? AND deadline_id IS NULL", userID).
+		Order("offset_minutes desc").Find(&rs).Error
+	return rs, err
+}
+
+// GetByUser returns the user's default schedule and every per-deadline
+// override.
+func (r *ReminderRepository) GetByUser(userID uint) ([]models.ReminderOffset, error) {
+	var rs []models.ReminderOffset
+	err := r.db.Where("user_id = ?", userID).Order("deadline_id, offset_minutes desc").Find(&rs).Error
+	return rs, err
+}
+
+// ReplaceUserDefaults swaps the user's default schedule for offsets.
+func (r *ReminderRepository) ReplaceUserDefaults(userID uint, offsets []int) ([]models.ReminderOffset, error) {
+	rs := make([]models.ReminderOffset, 0, len(offsets))
+	for _, o := range offsets {
+		rs = append(rs, models.ReminderOffset{UserID: userID, OffsetMinutes: o})
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND deadline_id IS NULL", userID).
+			Delete(&models.ReminderOffset{}).Error; err != nil {
+			return err
+		}
+		if len(rs) == 0 {
+			return nil
+		}
+		return tx.Create(&rs).Error
+	})
+	return rs, err
+}
+
+func (r *ReminderRepository) GetForDeadline(deadlineID uint) ([]models.ReminderOffset, error) {
+	var rs []models.ReminderOffset
+	err := r.db.Where("deadline_id = ?", deadlineID).
+		Order("offset_minutes desc").Find(&rs).Error
+	return rs, err
+}
+
+// Create adds an offset to a deadline's schedule, or to the user's
+// defaults when it has no deadline.
+func (r *ReminderRepository) Create(rem *models.ReminderOffset) error {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(rem)
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrReminderExists
+	}
+	return res.Error
+}
+
+// ReplaceForDeadline swaps the override schedule of a deadline for offsets.
+// An empty list removes the override so the user's defaults apply again.
+func (r *ReminderRepository) ReplaceForDeadline(deadlineID, userID uint, offsets []int) ([]models.ReminderOffset, error) {
+	rs := make([]models.ReminderOffset, 0, len(offsets))
+	for _, o := range offsets {
+		id := deadlineID
+		rs = append(rs, models.ReminderOffset{UserID: userID, DeadlineID: &id, OffsetMinutes: o})
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("deadline_id = ?", deadlineID).
+			Delete(&models.ReminderOffset{}).Error; err != nil {
+			return err
+		}
+		if len(rs) == 0 {
+			return nil
+		}
+		return tx.Create(&rs).Error
+	})
+	return rs, err
+}
+
+func (r *ReminderRepository) DeleteForDeadline(deadlineID, id uint) error {
+	return affected(r.db.Where("deadline_id = ?", deadlineID).Delete(&models.ReminderOffset{}, id))
+}
+
+// MaxOffset returns the largest offset anyone has configured, in minutes.
+func (r *ReminderRepository) MaxOffset() (int, error) {
+	var max *int
+	err := r.db.Model(&models.ReminderOffset{}).Select("MAX(offset_minutes)").Scan(&max).Error
+	if err != nil || max == nil {
+		return 0, err
+	}
+	return *max, nil
+}
+
+// GetOverrides returns the per-deadline schedules of the given deadlines.
+func (r *ReminderRepository) GetOverrides(deadlineIDs []uint) (map[uint][]int, error) {
+	out := map[uint][]int{}
+	if len(deadlineIDs) == 0 {
+		return out, nil
+	}
+
+	var rs []models.ReminderOffset
+	if err := r.db.Where("deadline_id IN ?", deadlineIDs).Find(&rs).Error; err != nil {
+		return nil, err
+	}
+	for _, rem := range rs {
+		out[*rem.DeadlineID] = append(out[*rem.DeadlineID], rem.OffsetMinutes)
+	}
+	return out, nil
+}
+
+// GetDefaults returns the default schedules of the given users.
+func (r *ReminderRepository) GetDefaults(userIDs []uint) (map[uint][]int, error) {
+	out := map[uint][]int{}
+	if len(userIDs) == 0 {
+		return out, nil
+	}
+
+	var rs []models.ReminderOffset
+	if err := r.db.Where("user_id IN ? AND deadline_id IS NULL", userIDs).Find(&rs).Error; err != nil {
+		return nil, err
+	}
+	for _, rem := range rs {
+		out[rem.UserID] = append(out[rem.UserID], rem.OffsetMinutes)
+	}
+	return out, nil
+}
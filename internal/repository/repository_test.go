@@ -0,0 +1,62 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/kadyrbayev2005/studysync/internal/migrations"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"github.com/kadyrbayev2005/studysync/internal/repository/repotest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestConformance runs the repository conformance suite against Postgres.
+// Point STUDYSYNC_TEST_DATABASE_DSN at a scratch database: it is migrated
+// and every table is truncated between subtests.
+func TestConformance(t *testing.T) {
+	dsn := os.Getenv("STUDYSYNC_TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("STUDYSYNC_TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+
+	m, err := migrations.New(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	repotest.Run(t, func(t *testing.T) repotest.Stores {
+		err := db.Exec(`TRUNCATE users, subjects, tasks, deadlines, notifications,
+			reminder_offsets, refresh_tokens, calendar_feeds, changes, tags RESTART IDENTITY CASCADE`).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repotest.Stores{
+			Users:     repository.NewUserRepository(db),
+			Subjects:  repository.NewSubjectRepository(db),
+			Tasks:     repository.NewTaskRepository(db),
+			Deadlines: repository.NewDeadlineRepository(db),
+			Changes:   repository.NewChangeRepository(db),
+			Trash:     repository.NewTrashRepository(db),
+			Deps:      repository.NewDependencyRepository(db),
+			Subtasks:  repository.NewSubtaskRepository(db),
+			Tags:      repository.NewTagRepository(db),
+			Search:    repository.NewSearchRepository(db),
+		}
+	})
+}
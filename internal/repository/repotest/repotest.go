@@ -0,0 +1,1685 @@
+// Package repotest is the conformance suite for the repository Store
+// interfaces. Every backend runs it from its own tests:
+//
+//	repotest.Run(t, func(t *testing.T) repotest.Stores { ... })
+//
+// newStores must return empty stores for each subtest.
+package repotest
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+type Stores struct {
+	Users     repository.UserStore
+	Subjects  repository.SubjectStore
+	Tasks     repository.TaskStore
+	Deadlines repository.DeadlineStore
+	Changes   repository.ChangeStore
+	Trash     repository.TrashStore
+	Deps      repository.DependencyStore
+	Subtasks  repository.SubtaskStore
+	Tags      repository.TagStore
+	Search    repository.SearchStore
+}
+
+// base is a Monday far enough ahead that every series in the suite lies in
+// the future. Times are whole seconds, which Postgres stores exactly.
+var base = time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+
+func Run(t *testing.T, newStores func(t *testing.T) Stores) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, st Stores)
+	}{
+		{"Users", testUsers},
+		{"SubjectScoping", testSubjectScoping},
+		{"TaskCRUD", testTaskCRUD},
+		{"TaskUpdateFromJSON", testTaskUpdateFromJSON},
+		{"GetTasksFilters", testGetTasksFilters},
+		{"GetTasksSortAndPagination", testGetTasksSortAndPagination},
+		{"GetTaskPage", testGetTaskPage},
+		{"Lists", testLists},
+		{"GetOpenOrDueAfter", testGetOpenOrDueAfter},
+		{"Workflows", testWorkflows},
+		{"Deadlines", testDeadlines},
+		{"Versions", testVersions},
+		{"ChangeLog", testChangeLog},
+		{"Trash", testTrash},
+		{"Dependencies", testDependencies},
+		{"Subtasks", testSubtasks},
+		{"Tags", testTags},
+		{"Search", testSearch},
+		{"TaskWhere", testTaskWhere},
+		{"SeriesOccurrences", testSeriesOccurrences},
+		{"SeriesDetachMaster", testSeriesDetachMaster},
+		{"SeriesSplit", testSeriesSplit},
+		{"SeriesReschedule", testSeriesReschedule},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newStores(t))
+		})
+	}
+}
+
+// fixture is two users with a subject each.
+type fixture struct {
+	alice, bob       models.User
+	aliceSub, bobSub models.Subject
+	aliceScope       repository.Scope
+	bobScope         repository.Scope
+}
+
+func seed(t *testing.T, st Stores) fixture {
+	t.Helper()
+	f := fixture{
+		alice: models.User{Name: "Alice", Email: "alice@example.com", Role: "user"},
+		bob:   models.User{Name: "Bob", Email: "bob@example.com", Role: "user"},
+	}
+	must(t, st.Users.Create(&f.alice))
+	must(t, st.Users.Create(&f.bob))
+
+	f.aliceSub = models.Subject{Name: "Calculus", UserID: f.alice.ID}
+	f.bobSub = models.Subject{Name: "History", UserID: f.bob.ID}
+	must(t, st.Subjects.Create(&f.aliceSub))
+	must(t, st.Subjects.Create(&f.bobSub))
+
+	f.aliceScope = repository.Scope{UserID: f.alice.ID}
+	f.bobScope = repository.Scope{UserID: f.bob.ID}
+	return f
+}
+
+func (f fixture) task(title string, due time.Duration) models.Task {
+	return models.Task{
+		Title:     title,
+		Status:    "todo",
+		Deadline:  base.Add(due),
+		SubjectID: f.aliceSub.ID,
+		UserID:    f.alice.ID,
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func notFound(t *testing.T, what string, err error) {
+	t.Helper()
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("%s: got error %v, want gorm.ErrRecordNotFound", what, err)
+	}
+}
+
+func conflict(t *testing.T, what string, err error) {
+	t.Helper()
+	if !errors.Is(err, repository.ErrConflict) {
+		t.Errorf("%s: got error %v, want repository.ErrConflict", what, err)
+	}
+}
+
+func titles(tasks []models.Task) []string {
+	out := make([]string, len(tasks))
+	for i, t := range tasks {
+		out[i] = t.Title
+	}
+	return out
+}
+
+func sameList(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%s: got %v, want %v", what, got, want)
+	}
+}
+
+func sameSet(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	got, want = append([]string(nil), got...), append([]string(nil), want...)
+	sort.Strings(got)
+	sort.Strings(want)
+	sameList(t, what, got, want)
+}
+
+func testUsers(t *testing.T, st Stores) {
+	f := seed(t, st)
+
+	dup := models.User{Name: "Alice 2", Email: f.alice.Email}
+	if err := st.Users.Create(&dup); err == nil {
+		t.Error("Create with a duplicate email succeeded")
+	}
+
+	got, err := st.Users.GetByEmail("alice@example.com")
+	must(t, err)
+	if got.ID != f.alice.ID || got.CreatedAt.IsZero() {
+		t.Errorf("GetByEmail = %+v", got)
+	}
+	_, err = st.Users.GetByEmail("nobody@example.com")
+	notFound(t, "GetByEmail(unknown)", err)
+
+	must(t, st.Users.Update(f.bob.ID, map[string]interface{}{"role": "admin"}))
+	got, err = st.Users.GetByID(f.bob.ID)
+	must(t, err)
+	if got.Role != "admin" {
+		t.Errorf("role after Update = %q, want admin", got.Role)
+	}
+	notFound(t, "Update(unknown)", st.Users.Update(f.bob.ID+100, map[string]interface{}{"role": "admin"}))
+
+	users, err := st.Users.GetAll()
+	must(t, err)
+	if len(users) != 2 {
+		t.Errorf("GetAll returned %d users, want 2", len(users))
+	}
+
+	must(t, st.Users.Delete(f.bob.ID))
+	_, err = st.Users.GetByID(f.bob.ID)
+	notFound(t, "GetByID after Delete", err)
+}
+
+func testSubjectScoping(t *testing.T, st Stores) {
+	f := seed(t, st)
+
+	subjects, err := st.Subjects.GetAll(f.aliceScope)
+	must(t, err)
+	if len(subjects) != 1 || subjects[0].ID != f.aliceSub.ID {
+		t.Errorf("GetAll(alice) = %+v", subjects)
+	}
+	subjects, err = st.Subjects.GetAll(repository.Scope{UserID: f.alice.ID, Admin: true})
+	must(t, err)
+	if len(subjects) != 2 {
+		t.Errorf("GetAll(admin) returned %d subjects, want 2", len(subjects))
+	}
+
+	_, err = st.Subjects.GetByID(f.aliceScope, f.bobSub.ID)
+	notFound(t, "GetByID(other user's subject)", err)
+	notFound(t, "Update(other user's subject)", st.Subjects.Update(f.aliceScope, f.bobSub, map[string]interface{}{"name": "x"}))
+	notFound(t, "Delete(other user's subject)", st.Subjects.Delete(f.aliceScope, f.bobSub))
+
+	s, err := st.Subjects.GetByName(f.aliceScope, "cALCULUS")
+	must(t, err)
+	if s.ID != f.aliceSub.ID {
+		t.Errorf("GetByName matched subject %d, want %d", s.ID, f.aliceSub.ID)
+	}
+	_, err = st.Subjects.GetByName(f.aliceScope, "History")
+	notFound(t, "GetByName(other user's subject)", err)
+
+	must(t, st.Subjects.Update(f.aliceScope, f.aliceSub, map[string]interface{}{"description": "MATH 101"}))
+	s, err = st.Subjects.GetByID(f.aliceScope, f.aliceSub.ID)
+	must(t, err)
+	if s.Description != "MATH 101" {
+		t.Errorf("description after Update = %q", s.Description)
+	}
+
+	task := f.task("Homework", 0)
+	must(t, st.Tasks.Create(&task))
+	must(t, st.Subjects.Delete(f.aliceScope, s))
+	_, err = st.Subjects.GetByID(f.aliceScope, s.ID)
+	notFound(t, "GetByID after Delete", err)
+	_, err = st.Tasks.GetByID(f.aliceScope, task.ID)
+	notFound(t, "task after its subject was deleted", err)
+	must(t, st.Subjects.Delete(f.bobScope, f.bobSub))
+}
+
+func testTaskCRUD(t *testing.T, st Stores) {
+	f := seed(t, st)
+
+	uid := "lms-1@example.edu"
+	task := f.task("Essay", 24*time.Hour)
+	task.Description = "2000 words"
+	task.ExternalUID = &uid
+	must(t, st.Tasks.Create(&task))
+	if task.ID == 0 || task.CreatedAt.IsZero() {
+		t.Fatalf("Create did not fill ID and CreatedAt: %+v", task)
+	}
+
+	dup := f.task("Essay again", 0)
+	dup.ExternalUID = &uid
+	if err := st.Tasks.Create(&dup); err == nil {
+		t.Error("Create with a duplicate external_uid succeeded")
+	}
+
+	got, err := st.Tasks.GetByID(f.aliceScope, task.ID)
+	must(t, err)
+	if got.Title != "Essay" || got.Subject.Name != "Calculus" || !got.Deadline.Equal(task.Deadline) {
+		t.Errorf("GetByID = %+v", got)
+	}
+	_, err = st.Tasks.GetByID(f.bobScope, task.ID)
+	notFound(t, "GetByID(other user's task)", err)
+	_, err = st.Tasks.GetByID(repository.Scope{UserID: f.bob.ID, Admin: true}, task.ID)
+	must(t, err)
+
+	got, err = st.Tasks.GetByExternalUID(f.aliceScope, uid)
+	must(t, err)
+	if got.ID != task.ID {
+		t.Errorf("GetByExternalUID = task %d, want %d", got.ID, task.ID)
+	}
+	_, err = st.Tasks.GetByExternalUID(f.bobScope, uid)
+	notFound(t, "GetByExternalUID(other user)", err)
+
+	notFound(t, "Update(other user's task)", st.Tasks.Update(f.bobScope, task.ID, map[string]interface{}{"title": "x"}))
+	must(t, st.Tasks.Update(f.aliceScope, task.ID, map[string]interface{}{"status": "done"}))
+	got, _ = st.Tasks.GetByID(f.aliceScope, task.ID)
+	if got.Status != "done" || got.Title != "Essay" {
+		t.Errorf("after Update: status %q title %q", got.Status, got.Title)
+	}
+
+	d := models.Deadline{TaskID: task.ID, UserID: f.alice.ID, DueDate: task.Deadline}
+	must(t, st.Deadlines.Create(&d))
+
+	notFound(t, "Delete(other user's task)", st.Tasks.Delete(f.bobScope, task.ID))
+	must(t, st.Tasks.Delete(f.aliceScope, task.ID))
+	_, err = st.Tasks.GetByID(f.aliceScope, task.ID)
+	notFound(t, "GetByID after Delete", err)
+	_, err = st.Deadlines.GetByID(f.aliceScope, d.ID)
+	notFound(t, "deadline after its task was deleted", err)
+}
+
+// testTaskUpdateFromJSON checks that Update accepts the loosely typed
+// values of a decoded JSON body, as the PUT handlers pass them through.
+func testTaskUpdateFromJSON(t *testing.T, st Stores) {
+	f := seed(t, st)
+	other := models.Subject{Name: "Physics", UserID: f.alice.ID}
+	must(t, st.Subjects.Create(&other))
+
+	task := f.task("Lab", 0)
+	must(t, st.Tasks.Create(&task))
+
+	due := base.Add(72 * time.Hour)
+	must(t, st.Tasks.Update(f.aliceScope, task.ID, map[string]interface{}{
+		"deadline":   due.Format(time.RFC3339),
+		"subject_id": float64(other.ID),
+	}))
+
+	got, err := st.Tasks.GetByID(f.aliceScope, task.ID)
+	must(t, err)
+	if !got.Deadline.Equal(due) {
+		t.Errorf("deadline = %v, want %v", got.Deadline, due)
+	}
+	if got.SubjectID != other.ID || got.Subject.Name != "Physics" {
+		t.Errorf("subject = %d %q, want %d Physics", got.SubjectID, got.Subject.Name, other.ID)
+	}
+}
+
+func testGetTasksFilters(t *testing.T, st Stores) {
+	f := seed(t, st)
+	other := models.Subject{Name: "Physics", UserID: f.alice.ID}
+	must(t, st.Subjects.Create(&other))
+
+	create := func(title, desc, status string, subject uint, due time.Duration) {
+		task := f.task(title, due)
+		task.Description, task.Status, task.SubjectID = desc, status, subject
+		must(t, st.Tasks.Create(&task))
+	}
+	create("Read chapter 1", "", "todo", f.aliceSub.ID, 1*time.Hour)
+	create("Problem set", "chapter 2 exercises", "done", f.aliceSub.ID, 2*time.Hour)
+	create("Lab report", "", "in-progress", other.ID, 3*time.Hour)
+	create("Quiz", "", "todo", other.ID, 4*time.Hour)
+
+	bobTask := models.Task{Title: "Bob's chapter", Status: "todo", Deadline: base, SubjectID: f.bobSub.ID, UserID: f.bob.ID}
+	must(t, st.Tasks.Create(&bobTask))
+
+	before, after := base.Add(3*time.Hour), base.Add(2*time.Hour)
+	cases := []struct {
+		name   string
+		filter repository.TaskFilter
+		want   []string
+	}{
+		{"none", repository.TaskFilter{}, []string{"Read chapter 1", "Problem set", "Lab report", "Quiz"}},
+		{"status", repository.TaskFilter{Status: "todo"}, []string{"Read chapter 1", "Quiz"}},
+		{"subject", repository.TaskFilter{SubjectID: &other.ID}, []string{"Lab report", "Quiz"}},
+		{"search title and description", repository.TaskFilter{Search: "CHAPTER"}, []string{"Read chapter 1", "Problem set"}},
+		{"deadline range is inclusive", repository.TaskFilter{DeadlineAfter: &after, DeadlineBefore: &before}, []string{"Problem set", "Lab report"}},
+		{"combined", repository.TaskFilter{Status: "todo", SubjectID: &other.ID}, []string{"Quiz"}},
+	}
+	for _, c := range cases {
+		tasks, total, err := st.Tasks.GetTasks(f.aliceScope, &c.filter)
+		must(t, err)
+		sameSet(t, c.name, titles(tasks), c.want)
+		if total != int64(len(c.want)) {
+			t.Errorf("%s: total = %d, want %d", c.name, total, len(c.want))
+		}
+	}
+
+	tasks, total, err := st.Tasks.GetTasks(repository.Scope{Admin: true}, &repository.TaskFilter{Search: "chapter"})
+	must(t, err)
+	if total != 3 || len(tasks) != 3 {
+		t.Errorf("admin search: total %d, %d tasks, want 3", total, len(tasks))
+	}
+}
+
+func testGetTasksSortAndPagination(t *testing.T, st Stores) {
+	f := seed(t, st)
+
+	// created_at, deadline and title each give a different order
+	rows := []struct {
+		title   string
+		created time.Duration
+		due     time.Duration
+	}{
+		{"b", 3 * time.Minute, 1 * time.Hour},
+		{"d", 1 * time.Minute, 4 * time.Hour},
+		{"a", 4 * time.Minute, 3 * time.Hour},
+		{"c", 2 * time.Minute, 2 * time.Hour},
+	}
+	for _, r := range rows {
+		task := f.task(r.title, r.due)
+		task.CreatedAt = base.Add(-time.Hour + r.created)
+		must(t, st.Tasks.Create(&task))
+	}
+
+	sorts := []struct {
+		sort string
+		want []string
+	}{
+		{"", []string{"a", "b", "c", "d"}},
+		{"created_at", []string{"d", "c", "b", "a"}},
+		{"created_at desc", []string{"a", "b", "c", "d"}},
+		{"deadline", []string{"b", "c", "a", "d"}},
+		{"deadline desc", []string{"d", "a", "c", "b"}},
+		{"title", []string{"a", "b", "c", "d"}},
+		{"title desc", []string{"d", "c", "b", "a"}},
+		{"id; DROP TABLE tasks", []string{"a", "b", "c", "d"}},
+	}
+	for _, s := range sorts {
+		tasks, _, err := st.Tasks.GetTasks(f.aliceScope, &repository.TaskFilter{Sort: s.sort})
+		must(t, err)
+		sameList(t, fmt.Sprintf("sort %q", s.sort), titles(tasks), s.want)
+	}
+
+	pages := []struct {
+		page, limit int
+		want        []string
+	}{
+		{1, 3, []string{"a", "b", "c"}},
+		{2, 3, []string{"d"}},
+		{3, 3, []string{}},
+		{0, 0, []string{"a", "b", "c", "d"}},
+		{1, 1000, []string{"a", "b", "c", "d"}},
+	}
+	for _, p := range pages {
+		filter := repository.TaskFilter{Sort: "title", Page: p.page, Limit: p.limit}
+		tasks, total, err := st.Tasks.GetTasks(f.aliceScope, &filter)
+		must(t, err)
+		sameList(t, fmt.Sprintf("page %d limit %d", p.page, p.limit), titles(tasks), p.want)
+		if total != 4 {
+			t.Errorf("page %d limit %d: total = %d, want 4", p.page, p.limit, total)
+		}
+	}
+
+	// out-of-range paging values are normalised on the filter
+	filter := repository.TaskFilter{Page: -1, Limit: 500}
+	_, _, err := st.Tasks.GetTasks(f.aliceScope, &filter)
+	must(t, err)
+	if filter.Page != 1 || filter.Limit != 100 {
+		t.Errorf("normalised page/limit = %d/%d, want 1/100", filter.Page, filter.Limit)
+	}
+}
+
+func testGetTaskPage(t *testing.T, st Stores) {
+	f := seed(t, st)
+
+	// e ties with b on the deadline and comes after it by id
+	rows := []struct {
+		title   string
+		created time.Duration
+		due     time.Duration
+	}{
+		{"b", 3 * time.Minute, 1 * time.Hour},
+		{"d", 1 * time.Minute, 4 * time.Hour},
+		{"a", 4 * time.Minute, 3 * time.Hour},
+		{"c", 2 * time.Minute, 2 * time.Hour},
+		{"e", 5 * time.Minute, 1 * time.Hour},
+	}
+	create := func(title string, created, due time.Duration) {
+		t.Helper()
+		task := f.task(title, due)
+		task.CreatedAt = base.Add(-time.Hour + created)
+		must(t, st.Tasks.Create(&task))
+	}
+	for _, r := range rows {
+		create(r.title, r.created, r.due)
+	}
+	read := func(sort string, c *repository.Cursor) repository.Page[models.Task] {
+		t.Helper()
+		if c != nil {
+			var err error
+			c, err = repository.TaskList.DecodeCursor(c.Encode(), repository.TaskList.SortOrDefault(sort))
+			must(t, err)
+		}
+		page, err := st.Tasks.GetTaskPage(f.aliceScope, &repository.TaskFilter{Sort: sort, Limit: 2}, c)
+		must(t, err)
+		return page
+	}
+
+	sorts := []struct {
+		sort string
+		want []string
+	}{
+		{"", []string{"[e a]", "[b c]", "[d]"}},
+		{"created_at", []string{"[d c]", "[b a]", "[e]"}},
+		{"deadline", []string{"[b e]", "[c a]", "[d]"}},
+		{"deadline desc", []string{"[d a]", "[c e]", "[b]"}},
+		{"title", []string{"[a b]", "[c d]", "[e]"}},
+		{"title desc", []string{"[e d]", "[c b]", "[a]"}},
+	}
+	for _, s := range sorts {
+		var forward []string
+		page := read(s.sort, nil)
+		if page.Prev != nil {
+			t.Errorf("sort %q: first page has a previous page", s.sort)
+		}
+		for {
+			forward = append(forward, fmt.Sprint(titles(page.Items)))
+			if page.Next == nil || len(forward) > len(s.want) {
+				break
+			}
+			page = read(s.sort, page.Next)
+		}
+		sameList(t, fmt.Sprintf("sort %q forward", s.sort), forward, s.want)
+
+		backward := []string{fmt.Sprint(titles(page.Items))}
+		for page.Prev != nil && len(backward) <= len(s.want) {
+			page = read(s.sort, page.Prev)
+			backward = append([]string{fmt.Sprint(titles(page.Items))}, backward...)
+		}
+		sameList(t, fmt.Sprintf("sort %q backward", s.sort), backward, s.want)
+	}
+
+	// tasks added before the cursor do not shift the next page
+	page := read("created_at", nil)
+	create("f", 0, 0)
+	sameList(t, "page after an insert", titles(read("created_at", page.Next).Items), []string{"b", "a"})
+
+	_, err := st.Tasks.GetTaskPage(f.aliceScope, &repository.TaskFilter{Sort: "deadline"}, page.Next)
+	if !errors.Is(err, repository.ErrInvalidCursor) {
+		t.Errorf("GetTaskPage(cursor of another sort): got error %v, want repository.ErrInvalidCursor", err)
+	}
+	if _, err := repository.TaskList.DecodeCursor("not a cursor", "created_at"); !errors.Is(err, repository.ErrInvalidCursor) {
+		t.Errorf("DecodeCursor(garbage): got error %v, want repository.ErrInvalidCursor", err)
+	}
+	// cursors handed out before the generic list layer still read
+	old := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"created_at desc","t":"2030-01-07T09:00:00Z","i":7}`))
+	if c, err := repository.TaskList.DecodeCursor(old, "created_at desc"); err != nil || !c.At.Equal(base) || c.ID != 7 {
+		t.Errorf("DecodeCursor(earlier cursor) = %+v, %v", c, err)
+	}
+
+	page, err = st.Tasks.GetTaskPage(f.aliceScope, &repository.TaskFilter{Limit: 2}, nil)
+	must(t, err)
+	if page.Total != 6 {
+		t.Errorf("GetTaskPage total = %d, want 6", page.Total)
+	}
+	// counting the total must not cost the page its preloads
+	for _, task := range page.Items {
+		if task.Subject.ID != task.SubjectID || task.Subject.Name == "" {
+			t.Errorf("subject not preloaded on task %d of a page with a total: %+v", task.ID, task.Subject)
+		}
+	}
+	page, err = st.Tasks.GetTaskPage(f.aliceScope, &repository.TaskFilter{Limit: 2, SkipTotal: true}, nil)
+	must(t, err)
+	if page.Total != -1 {
+		t.Errorf("GetTaskPage(SkipTotal) total = %d, want -1", page.Total)
+	}
+	_, total, err := st.Tasks.GetTasks(f.aliceScope, &repository.TaskFilter{SkipTotal: true})
+	must(t, err)
+	if total != -1 {
+		t.Errorf("GetTasks(SkipTotal) total = %d, want -1", total)
+	}
+}
+
+func testLists(t *testing.T, st Stores) {
+	f := seed(t, st)
+
+	// subjects, with the fixture's Calculus created now, long before base
+	for i, name := range []string{"Algebra", "Biology", "Chemistry"} {
+		must(t, st.Subjects.Create(&models.Subject{Name: name, UserID: f.alice.ID, CreatedAt: base.Add(time.Duration(i) * time.Hour)}))
+	}
+	subjects := func(q repository.ListQuery) ([]string, repository.Page[models.Subject]) {
+		t.Helper()
+		page, err := st.Subjects.List(f.aliceScope, &q)
+		must(t, err)
+		var names []string
+		for _, s := range page.Items {
+			names = append(names, s.Name)
+		}
+		return names, page
+	}
+	reread := func(spec repository.ListSpec, c *repository.Cursor) *repository.Cursor {
+		t.Helper()
+		if c == nil {
+			t.Fatal("missing cursor")
+		}
+		c, err := spec.DecodeCursor(c.Encode(), c.Sort)
+		must(t, err)
+		return c
+	}
+
+	names, page := subjects(repository.ListQuery{Sort: "name", Limit: 2})
+	sameList(t, "subjects by name", names, []string{"Algebra", "Biology"})
+	if page.Total != 4 {
+		t.Errorf("subjects total = %d, want 4", page.Total)
+	}
+	names, _ = subjects(repository.ListQuery{Sort: "name", Page: 2, Limit: 2})
+	sameList(t, "subjects by name, page 2", names, []string{"Calculus", "Chemistry"})
+	names, _ = subjects(repository.ListQuery{Sort: "name", Page: 3, Limit: 2})
+	sameList(t, "subjects by name, page 3", names, nil)
+
+	names, page = subjects(repository.ListQuery{Sort: "name desc", Limit: 3, Cursors: true, SkipTotal: true})
+	sameList(t, "subjects by name desc", names, []string{"Chemistry", "Calculus", "Biology"})
+	if page.Prev != nil || page.Total != -1 {
+		t.Errorf("first cursor page: prev %v, total %d", page.Prev, page.Total)
+	}
+	names, page = subjects(repository.ListQuery{Sort: "name desc", Limit: 3, Cursors: true, Cursor: reread(repository.SubjectList, page.Next)})
+	sameList(t, "subjects by name desc, next page", names, []string{"Algebra"})
+	if page.Next != nil {
+		t.Errorf("last cursor page has a next page")
+	}
+	names, _ = subjects(repository.ListQuery{Sort: "name desc", Limit: 3, Cursors: true, Cursor: reread(repository.SubjectList, page.Prev)})
+	sameList(t, "subjects by name desc, previous page", names, []string{"Chemistry", "Calculus", "Biology"})
+
+	names, _ = subjects(repository.ListQuery{Filters: map[string]interface{}{"name": "cALCULUS"}})
+	sameList(t, "subjects named calculus", names, []string{"Calculus"})
+	after, before := base.Add(30*time.Minute), base.Add(time.Hour)
+	names, _ = subjects(repository.ListQuery{Ranges: map[string]repository.TimeRange{"created": {After: &after}}})
+	sameList(t, "subjects created after", names, []string{"Biology", "Chemistry"})
+	from := base
+	names, _ = subjects(repository.ListQuery{Ranges: map[string]repository.TimeRange{"created": {After: &from, Before: &before}}})
+	sameList(t, "subjects created between, inclusive", names, []string{"Algebra", "Biology"})
+
+	page, err := st.Subjects.List(f.bobScope, &repository.ListQuery{})
+	must(t, err)
+	if len(page.Items) != 1 || page.Items[0].ID != f.bobSub.ID || page.Total != 1 {
+		t.Errorf("List(bob) = %+v", page)
+	}
+	page, err = st.Subjects.List(repository.Scope{Admin: true}, &repository.ListQuery{Limit: 2})
+	must(t, err)
+	if page.Total != 5 {
+		t.Errorf("List(admin) total = %d, want 5", page.Total)
+	}
+
+	for what, q := range map[string]repository.ListQuery{
+		"unknown sort":   {Sort: "user_id"},
+		"unknown filter": {Filters: map[string]interface{}{"user_id": f.bob.ID}},
+		"unknown range":  {Ranges: map[string]repository.TimeRange{"due": {After: &after}}},
+	} {
+		if _, err := st.Subjects.List(f.aliceScope, &q); !errors.Is(err, repository.ErrInvalidQuery) {
+			t.Errorf("List(%s): got error %v, want repository.ErrInvalidQuery", what, err)
+		}
+	}
+	_, page = subjects(repository.ListQuery{Limit: 1, Cursors: true})
+	if _, err := st.Subjects.List(f.aliceScope, &repository.ListQuery{Sort: "name", Cursors: true, Cursor: page.Next}); !errors.Is(err, repository.ErrInvalidCursor) {
+		t.Errorf("List(cursor of another sort): got error %v, want repository.ErrInvalidCursor", err)
+	}
+
+	// deadlines, with their task
+	first, second := f.task("First", 0), f.task("Second", 0)
+	must(t, st.Tasks.Create(&first))
+	must(t, st.Tasks.Create(&second))
+	for _, d := range []models.Deadline{
+		{TaskID: first.ID, DueDate: base.Add(2 * time.Hour)},
+		{TaskID: second.ID, DueDate: base.Add(time.Hour)},
+		{TaskID: first.ID, DueDate: base.Add(3 * time.Hour)},
+	} {
+		d.UserID = f.alice.ID
+		must(t, st.Deadlines.Create(&d))
+	}
+	deadlines := func(q repository.ListQuery) ([]string, repository.Page[models.Deadline]) {
+		t.Helper()
+		page, err := st.Deadlines.List(f.aliceScope, &q)
+		must(t, err)
+		var due []string
+		for _, d := range page.Items {
+			if d.Task.ID != d.TaskID || d.Task.Title == "" {
+				t.Errorf("task not preloaded on deadline %d: %+v", d.ID, d.Task)
+			}
+			due = append(due, fmt.Sprintf("%s %s", d.Task.Title, d.DueDate.Sub(base)))
+		}
+		return due, page
+	}
+
+	due, dpage := deadlines(repository.ListQuery{Sort: "due_date desc", Limit: 2, Cursors: true})
+	sameList(t, "deadlines by due date desc", due, []string{"First 3h0m0s", "First 2h0m0s"})
+	due, _ = deadlines(repository.ListQuery{Sort: "due_date desc", Limit: 2, Cursors: true, Cursor: reread(repository.DeadlineList, dpage.Next)})
+	sameList(t, "deadlines by due date desc, next page", due, []string{"Second 1h0m0s"})
+	due, dpage = deadlines(repository.ListQuery{Filters: map[string]interface{}{"task_id": first.ID}})
+	sameList(t, "deadlines of a task", due, []string{"First 2h0m0s", "First 3h0m0s"})
+	// deadlines checks the preloaded task on this page with a total too
+	if dpage.Total != 2 {
+		t.Errorf("deadlines of a task total = %d, want 2", dpage.Total)
+	}
+	after = base.Add(90 * time.Minute)
+	due, _ = deadlines(repository.ListQuery{Sort: "due_date", Ranges: map[string]repository.TimeRange{"due": {Before: &after}}})
+	sameList(t, "deadlines due before", due, []string{"Second 1h0m0s"})
+	dpage, err = st.Deadlines.List(f.bobScope, &repository.ListQuery{})
+	must(t, err)
+	if len(dpage.Items) != 0 {
+		t.Errorf("List(bob) returned %d deadlines, want 0", len(dpage.Items))
+	}
+
+	// users
+	carol := models.User{Name: "Carol", Email: "carol@example.com", Role: "admin"}
+	must(t, st.Users.Create(&carol))
+	users := func(q repository.ListQuery) ([]string, repository.Page[models.User]) {
+		t.Helper()
+		page, err := st.Users.List(&q)
+		must(t, err)
+		var names []string
+		for _, u := range page.Items {
+			names = append(names, u.Name)
+		}
+		return names, page
+	}
+	names, _ = users(repository.ListQuery{Sort: "email desc"})
+	sameList(t, "users by email desc", names, []string{"Carol", "Bob", "Alice"})
+	names, _ = users(repository.ListQuery{Filters: map[string]interface{}{"role": "admin"}})
+	sameList(t, "admins", names, []string{"Carol"})
+	names, _ = users(repository.ListQuery{Filters: map[string]interface{}{"email": "ALICE@example.com"}})
+	sameList(t, "users by email", names, []string{"Alice"})
+
+	var walked []string
+	names, upage := users(repository.ListQuery{Sort: "name", Limit: 1, Cursors: true})
+	for {
+		walked = append(walked, names...)
+		if upage.Next == nil || len(walked) > 3 {
+			break
+		}
+		names, upage = users(repository.ListQuery{Sort: "name", Limit: 1, Cursors: true, Cursor: reread(repository.UserList, upage.Next)})
+	}
+	sameList(t, "users by name, one per page", walked, []string{"Alice", "Bob", "Carol"})
+	if _, err := st.Users.List(&repository.ListQuery{Sort: "password_hash"}); !errors.Is(err, repository.ErrInvalidQuery) {
+		t.Errorf("List(sort by password_hash): got error %v, want repository.ErrInvalidQuery", err)
+	}
+}
+
+func testGetOpenOrDueAfter(t *testing.T, st Stores) {
+	f := seed(t, st)
+
+	create := func(title, status string, due time.Duration) {
+		task := f.task(title, due)
+		task.Status = status
+		must(t, st.Tasks.Create(&task))
+	}
+	create("open, past", "todo", -48*time.Hour)
+	create("done, past", "done", -48*time.Hour)
+	create("done, upcoming", "done", 48*time.Hour)
+	create("open, upcoming", "in-progress", 24*time.Hour)
+
+	tasks, err := st.Tasks.GetOpenOrDueAfter(f.aliceScope, base)
+	must(t, err)
+	sameList(t, "GetOpenOrDueAfter", titles(tasks), []string{"open, past", "open, upcoming", "done, upcoming"})
+	if len(tasks) > 0 && tasks[0].Subject.Name != "Calculus" {
+		t.Errorf("subject not preloaded: %+v", tasks[0].Subject)
+	}
+}
+
+// testWorkflows checks that a subject's workflow and the status times of
+// tasks are stored, that occurrences start in the first status of the
+// workflow and that a completed task counts as done whatever its status.
+func testWorkflows(t *testing.T, st Stores) {
+	f := seed(t, st)
+	wf := models.Workflow{
+		States: []models.WorkflowState{
+			{Name: "draft", Kind: models.KindTodo},
+			{Name: "graded", Kind: models.KindDone},
+		},
+		Transitions: []models.WorkflowTransition{{From: "draft", To: "graded"}},
+	}
+	essays := models.Subject{Name: "English", UserID: f.alice.ID, Workflow: &wf}
+	must(t, st.Subjects.Create(&essays))
+
+	got, err := st.Subjects.GetByID(f.aliceScope, essays.ID)
+	must(t, err)
+	if got.Workflow == nil || got.Workflow.Initial() != "draft" || len(got.Workflow.Transitions) != 1 {
+		t.Fatalf("stored workflow = %+v", got.Workflow)
+	}
+	must(t, st.Subjects.Update(f.aliceScope, got, map[string]interface{}{"workflow": nil}))
+	got, _ = st.Subjects.GetByID(f.aliceScope, essays.ID)
+	if got.Workflow != nil {
+		t.Errorf("workflow after clearing it = %+v", got.Workflow)
+	}
+	must(t, st.Subjects.Update(f.aliceScope, got, map[string]interface{}{"workflow": &wf}))
+	essays, _ = st.Subjects.GetByID(f.aliceScope, essays.ID)
+
+	master := f.task("Essay", 0)
+	master.SubjectID = essays.ID
+	master.Status = "draft"
+	master.RecurrenceRule = "FREQ=WEEKLY;COUNT=3"
+	must(t, st.Tasks.CreateSeries(&master))
+	masters, err := st.Tasks.GetSeriesMasters()
+	must(t, err)
+	if len(masters) != 1 || masters[0].Subject.Workflow == nil {
+		t.Fatalf("series master without its subject's workflow: %+v", masters)
+	}
+	must(t, st.Tasks.CreateOccurrence(masters[0], base.AddDate(0, 0, 7)))
+
+	done := base.Add(-time.Hour)
+	must(t, st.Tasks.Update(f.aliceScope, master.ID, map[string]interface{}{
+		"status": "graded", "started_at": done, "completed_at": done,
+	}))
+	task, err := st.Tasks.GetByID(f.aliceScope, master.ID)
+	must(t, err)
+	if task.StartedAt == nil || !task.StartedAt.Equal(done) || task.CompletedAt == nil || !task.CompletedAt.Equal(done) {
+		t.Errorf("started_at, completed_at = %v, %v, want %v", task.StartedAt, task.CompletedAt, done)
+	}
+
+	tasks, _, err := st.Tasks.GetTasks(f.aliceScope, &repository.TaskFilter{Sort: "deadline"})
+	must(t, err)
+	if len(tasks) != 2 || tasks[1].Status != "draft" {
+		t.Errorf("occurrence = %+v, want it in draft", tasks)
+	}
+
+	open, err := st.Tasks.GetOpenOrDueAfter(f.aliceScope, base.AddDate(0, 0, 1))
+	must(t, err)
+	sameList(t, "GetOpenOrDueAfter", titles(open), []string{"Essay"})
+	if len(open) == 1 && open[0].ID == master.ID {
+		t.Error("GetOpenOrDueAfter returned the graded task")
+	}
+}
+
+func testDeadlines(t *testing.T, st Stores) {
+	f := seed(t, st)
+
+	late, early := f.task("Late", 48*time.Hour), f.task("Early", 24*time.Hour)
+	must(t, st.Tasks.Create(&late))
+	must(t, st.Tasks.Create(&early))
+	bobTask := models.Task{Title: "Bob's", Status: "todo", Deadline: base, SubjectID: f.bobSub.ID, UserID: f.bob.ID}
+	must(t, st.Tasks.Create(&bobTask))
+
+	dl := models.Deadline{TaskID: late.ID, UserID: f.alice.ID, DueDate: late.Deadline}
+	de := models.Deadline{TaskID: early.ID, UserID: f.alice.ID, DueDate: early.Deadline}
+	db := models.Deadline{TaskID: bobTask.ID, UserID: f.bob.ID, DueDate: bobTask.Deadline}
+	for _, d := range []*models.Deadline{&dl, &de, &db} {
+		must(t, st.Deadlines.Create(d))
+	}
+
+	all, err := st.Deadlines.GetAll(f.aliceScope)
+	must(t, err)
+	if len(all) != 2 {
+		t.Fatalf("GetAll(alice) returned %d deadlines, want 2", len(all))
+	}
+	for _, d := range all {
+		if d.Task.ID != d.TaskID || d.Task.Title == "" {
+			t.Errorf("task not preloaded on deadline %d: %+v", d.ID, d.Task)
+		}
+	}
+
+	_, err = st.Deadlines.GetByID(f.aliceScope, db.ID)
+	notFound(t, "GetByID(other user's deadline)", err)
+	got, err := st.Deadlines.GetByID(f.aliceScope, dl.ID)
+	must(t, err)
+	if got.Task.Title != "Late" {
+		t.Errorf("GetByID task = %q, want Late", got.Task.Title)
+	}
+
+	due, err := st.Deadlines.GetDueAfter(f.aliceScope, base.Add(time.Hour))
+	must(t, err)
+	if len(due) != 2 || due[0].ID != de.ID || due[1].ID != dl.ID {
+		t.Fatalf("GetDueAfter = %+v, want early then late", due)
+	}
+	if due[0].Task.Subject.Name != "Calculus" {
+		t.Errorf("GetDueAfter did not preload the task's subject")
+	}
+	due, err = st.Deadlines.GetDueAfter(f.aliceScope, late.Deadline)
+	must(t, err)
+	if len(due) != 0 {
+		t.Errorf("GetDueAfter is not exclusive: %+v", due)
+	}
+
+	moved := base.Add(96 * time.Hour)
+	must(t, st.Deadlines.SetForTask(late, moved))
+	got, _ = st.Deadlines.GetByID(f.aliceScope, dl.ID)
+	if !got.DueDate.Equal(moved) {
+		t.Errorf("SetForTask did not move the deadline: %v", got.DueDate)
+	}
+
+	fresh := f.task("No deadline yet", 0)
+	must(t, st.Tasks.Create(&fresh))
+	must(t, st.Deadlines.SetForTask(fresh, moved))
+	all, _ = st.Deadlines.GetAll(f.aliceScope)
+	if len(all) != 3 {
+		t.Errorf("SetForTask did not create a deadline: %d deadlines", len(all))
+	}
+
+	notFound(t, "Delete(other user's deadline)", st.Deadlines.Delete(f.aliceScope, db))
+	got, _ = st.Deadlines.GetByID(f.aliceScope, dl.ID)
+	must(t, st.Deadlines.Delete(f.aliceScope, got))
+	_, err = st.Deadlines.GetByID(f.aliceScope, dl.ID)
+	notFound(t, "GetByID after Delete", err)
+}
+
+func testVersions(t *testing.T, st Stores) {
+	f := seed(t, st)
+
+	if f.aliceSub.Version != 1 {
+		t.Errorf("new subject has version %d, want 1", f.aliceSub.Version)
+	}
+	stale := f.aliceSub
+	must(t, st.Subjects.Update(f.aliceScope, stale, map[string]interface{}{"name": "Calculus I"}))
+	s, err := st.Subjects.GetByID(f.aliceScope, stale.ID)
+	must(t, err)
+	if s.Version <= stale.Version {
+		t.Errorf("Update left the subject at version %d", s.Version)
+	}
+	conflict(t, "Update(stale subject)", st.Subjects.Update(f.aliceScope, stale, map[string]interface{}{"name": "x"}))
+	conflict(t, "Delete(stale subject)", st.Subjects.Delete(f.aliceScope, stale))
+	must(t, st.Subjects.Update(f.aliceScope, models.Subject{ID: stale.ID}, map[string]interface{}{"description": "unconditional"}))
+
+	task := f.task("Essay", 24*time.Hour)
+	must(t, st.Tasks.Create(&task))
+	if task.Version != 1 {
+		t.Errorf("new task has version %d, want 1", task.Version)
+	}
+	must(t, st.Tasks.Update(f.aliceScope, task.ID, map[string]interface{}{"status": "done"}))
+	conflict(t, "UpdateOccurrence(stale task)", st.Tasks.UpdateOccurrence(f.aliceScope, task, map[string]interface{}{"title": "x"}))
+	conflict(t, "DeleteOccurrence(stale task)", st.Tasks.DeleteOccurrence(f.aliceScope, task))
+	got, err := st.Tasks.GetByID(f.aliceScope, task.ID)
+	must(t, err)
+	if got.Title != "Essay" {
+		t.Errorf("a conflicting write changed the task: %q", got.Title)
+	}
+	must(t, st.Tasks.UpdateOccurrence(f.aliceScope, got, map[string]interface{}{"title": "Long essay"}))
+	notFound(t, "UpdateOccurrence(other user's task)", st.Tasks.UpdateOccurrence(f.bobScope, got, map[string]interface{}{"title": "x"}))
+
+	d := models.Deadline{TaskID: task.ID, UserID: f.alice.ID, DueDate: task.Deadline}
+	must(t, st.Deadlines.Create(&d))
+	must(t, st.Deadlines.SetForTask(task, task.Deadline.Add(time.Hour)))
+	conflict(t, "Delete(stale deadline)", st.Deadlines.Delete(f.aliceScope, d))
+	notFound(t, "Delete(missing deadline)", st.Deadlines.Delete(f.aliceScope, models.Deadline{ID: d.ID + 100, Version: 1}))
+}
+
+// changeLog returns the user's changes after seq as "resource op fields".
+func changeLog(t *testing.T, st Stores, userID uint, after uint64) []string {
+	t.Helper()
+	latest, err := st.Changes.Latest(userID)
+	must(t, err)
+	changes, err := st.Changes.Since(userID, after, latest)
+	must(t, err)
+	out := make([]string, len(changes))
+	for i, c := range changes {
+		if c.UserID != userID {
+			t.Errorf("Since(%d) returned a change of user %d", userID, c.UserID)
+		}
+		if i > 0 && c.Seq <= changes[i-1].Seq {
+			t.Errorf("Since is not ordered by seq: %d after %d", c.Seq, changes[i-1].Seq)
+		}
+		out[i] = strings.TrimSpace(c.Resource + " " + c.Op + " " + c.Fields)
+	}
+	return out
+}
+
+func testChangeLog(t *testing.T, st Stores) {
+	f := seed(t, st)
+
+	sameList(t, "seed", changeLog(t, st, f.alice.ID, 0), []string{"subjects insert"})
+	mark, err := st.Changes.Latest(f.alice.ID)
+	must(t, err)
+
+	task := f.task("Essay", 24*time.Hour)
+	must(t, st.Tasks.Create(&task))
+	must(t, st.Tasks.Update(f.aliceScope, task.ID, map[string]interface{}{"title": "Long essay", "deadline": base}))
+	must(t, st.Tasks.Update(f.aliceScope, task.ID, map[string]interface{}{"title": "Long essay"}))
+	must(t, st.Tasks.SetGeneratedUntil(task.ID, base))
+	d := models.Deadline{TaskID: task.ID, UserID: f.alice.ID, DueDate: base}
+	must(t, st.Deadlines.Create(&d))
+	must(t, st.Tasks.Delete(f.aliceScope, task.ID))
+
+	got := changeLog(t, st, f.alice.ID, mark)
+	sameList(t, "changes", got[:min(3, len(got))], []string{
+		"tasks insert",
+		"tasks update deadline,title",
+		"deadlines insert",
+	})
+	if len(got) > 3 {
+		// the cascade may log the deadline before or after the task
+		sameSet(t, "tombstones", got[3:], []string{"deadlines delete", "tasks delete"})
+	}
+
+	sameList(t, "other user", changeLog(t, st, f.bob.ID, 0), []string{"subjects insert"})
+
+	oldest, newest, err := st.Changes.Horizon()
+	must(t, err)
+	latest, _ := st.Changes.Latest(f.alice.ID)
+	if oldest == 0 || oldest > mark || newest != latest {
+		t.Errorf("Horizon = %d, %d; want at most %d and %d", oldest, newest, mark, latest)
+	}
+	purged, err := st.Changes.Purge(time.Now().Add(time.Hour))
+	must(t, err)
+	if purged != 6 {
+		t.Errorf("Purge deleted %d changes, want all but the newest of 7", purged)
+	}
+	oldest, newest, _ = st.Changes.Horizon()
+	if oldest != latest || newest != latest {
+		t.Errorf("Horizon after Purge = %d, %d; want %d for both", oldest, newest, latest)
+	}
+}
+
+// trash returns the user's trash as "type title".
+func trash(t *testing.T, st Stores, scope repository.Scope) []string {
+	t.Helper()
+	items, err := st.Trash.List(scope)
+	must(t, err)
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = item.Type + " " + item.Title
+	}
+	return out
+}
+
+func testTrash(t *testing.T, st Stores) {
+	f := seed(t, st)
+	parentDeleted := func(what string, err error) {
+		t.Helper()
+		if !errors.Is(err, repository.ErrParentDeleted) {
+			t.Errorf("%s: got error %v, want repository.ErrParentDeleted", what, err)
+		}
+	}
+
+	uid := "lms-1@example.edu"
+	essay := f.task("Essay", 0)
+	essay.ExternalUID = &uid
+	quiz := f.task("Quiz", time.Hour)
+	must(t, st.Tasks.Create(&essay))
+	must(t, st.Tasks.Create(&quiz))
+	essayDue := models.Deadline{TaskID: essay.ID, UserID: f.alice.ID, DueDate: essay.Deadline}
+	quizDue := models.Deadline{TaskID: quiz.ID, UserID: f.alice.ID, DueDate: quiz.Deadline}
+	must(t, st.Deadlines.Create(&essayDue))
+	must(t, st.Deadlines.Create(&quizDue))
+
+	must(t, st.Deadlines.Delete(f.aliceScope, quizDue))
+	must(t, st.Tasks.Delete(f.aliceScope, essay.ID))
+	sameList(t, "trash", trash(t, st, f.aliceScope), []string{"tasks Essay", "deadlines Quiz"})
+
+	// the calendar entry of a deleted task can be imported again
+	again := f.task("Essay v2", 0)
+	again.ExternalUID = &uid
+	must(t, st.Tasks.Create(&again))
+
+	subject, err := st.Subjects.GetByID(f.aliceScope, f.aliceSub.ID)
+	must(t, err)
+	mark, _ := st.Changes.Latest(f.alice.ID)
+	must(t, st.Subjects.Delete(f.aliceScope, subject))
+	sameSet(t, "changes of the subject's delete", changeLog(t, st, f.alice.ID, mark), []string{
+		"subjects delete", "tasks delete", "tasks delete",
+	})
+	sameList(t, "trash after deleting the subject", trash(t, st, f.aliceScope),
+		[]string{"subjects Calculus", "tasks Essay", "deadlines Quiz"})
+	sameList(t, "other user's trash", trash(t, st, f.bobScope), []string{})
+
+	notFound(t, "Restore(other user's subject)", st.Trash.Restore(f.bobScope, models.ResourceSubjects, f.aliceSub.ID))
+	parentDeleted("Restore(task of a deleted subject)", st.Trash.Restore(f.aliceScope, models.ResourceTasks, essay.ID))
+	parentDeleted("Restore(deadline of a deleted task)", st.Trash.Restore(f.aliceScope, models.ResourceDeadlines, quizDue.ID))
+
+	// restoring the subject brings back the tasks deleted with it, but not
+	// what was deleted before
+	must(t, st.Trash.Restore(f.aliceScope, models.ResourceSubjects, f.aliceSub.ID))
+	tasks, _, err := st.Tasks.GetTasks(f.aliceScope, &repository.TaskFilter{Sort: "title"})
+	must(t, err)
+	sameList(t, "tasks after restoring the subject", titles(tasks), []string{"Essay v2", "Quiz"})
+	sameList(t, "trash after restoring the subject", trash(t, st, f.aliceScope), []string{"tasks Essay", "deadlines Quiz"})
+
+	mark, _ = st.Changes.Latest(f.alice.ID)
+	must(t, st.Trash.Restore(f.aliceScope, models.ResourceTasks, essay.ID))
+	sameSet(t, "changes of the task's restore", changeLog(t, st, f.alice.ID, mark), []string{"deadlines insert", "tasks insert"})
+	got, err := st.Tasks.GetByID(f.aliceScope, essay.ID)
+	must(t, err)
+	if got.ExternalUID != nil {
+		t.Errorf("restored task kept external_uid %q that another task took", *got.ExternalUID)
+	}
+	got, err = st.Tasks.GetByExternalUID(f.aliceScope, uid)
+	must(t, err)
+	if got.ID != again.ID {
+		t.Errorf("GetByExternalUID = task %d, want %d", got.ID, again.ID)
+	}
+	must(t, st.Trash.Restore(f.aliceScope, models.ResourceDeadlines, quizDue.ID))
+	deadlines, err := st.Deadlines.GetAll(f.aliceScope)
+	must(t, err)
+	if len(deadlines) != 2 {
+		t.Errorf("%d deadlines after restoring, want 2", len(deadlines))
+	}
+	notFound(t, "Restore(task not in the trash)", st.Trash.Restore(f.aliceScope, models.ResourceTasks, essay.ID))
+	notFound(t, "Restore(unknown type)", st.Trash.Restore(f.aliceScope, "users", f.alice.ID))
+
+	must(t, st.Tasks.Delete(f.aliceScope, quiz.ID))
+	purged, err := st.Trash.Purge(time.Now().Add(-time.Hour))
+	must(t, err)
+	if purged != 0 {
+		t.Errorf("Purge of older rows deleted %d, want 0", purged)
+	}
+	purged, err = st.Trash.Purge(time.Now().Add(time.Hour))
+	must(t, err)
+	if purged != 2 {
+		t.Errorf("Purge deleted %d rows, want the task and its deadline", purged)
+	}
+	sameList(t, "trash after Purge", trash(t, st, f.aliceScope), []string{})
+	notFound(t, "Restore(purged task)", st.Trash.Restore(f.aliceScope, models.ResourceTasks, quiz.ID))
+}
+
+func testDependencies(t *testing.T, st Stores) {
+	f := seed(t, st)
+	cycle := func(what string, err error) {
+		t.Helper()
+		if !errors.Is(err, repository.ErrDependencyCycle) {
+			t.Errorf("%s: got error %v, want repository.ErrDependencyCycle", what, err)
+		}
+	}
+	edges := func() []string {
+		t.Helper()
+		_, deps, err := st.Deps.Graph(f.aliceScope)
+		must(t, err)
+		out := make([]string, len(deps))
+		for i, d := range deps {
+			out[i] = fmt.Sprintf("%d<-%d", d.TaskID, d.BlockerID)
+		}
+		return out
+	}
+
+	review, draft, final := f.task("Review", 0), f.task("Draft", 24*time.Hour), f.task("Final", 48*time.Hour)
+	must(t, st.Tasks.Create(&review))
+	must(t, st.Tasks.Create(&draft))
+	must(t, st.Tasks.Create(&final))
+	other := models.Task{Title: "Other", Status: "todo", SubjectID: f.bobSub.ID, UserID: f.bob.ID}
+	must(t, st.Tasks.Create(&other))
+
+	dep, err := st.Deps.Add(f.aliceScope, final.ID, draft.ID)
+	must(t, err)
+	if dep.UserID != f.alice.ID {
+		t.Errorf("dependency owner = %d, want %d", dep.UserID, f.alice.ID)
+	}
+	_, err = st.Deps.Add(f.aliceScope, draft.ID, review.ID)
+	must(t, err)
+	_, err = st.Deps.Add(f.aliceScope, draft.ID, review.ID)
+	must(t, err)
+
+	_, err = st.Deps.Add(f.aliceScope, review.ID, final.ID)
+	cycle("Add(closing a cycle)", err)
+	_, err = st.Deps.Add(f.aliceScope, review.ID, review.ID)
+	cycle("Add(task on itself)", err)
+	_, err = st.Deps.Add(f.aliceScope, final.ID, other.ID)
+	notFound(t, "Add(other user's blocker)", err)
+	_, err = st.Deps.Add(f.bobScope, other.ID, final.ID)
+	notFound(t, "Add(out of scope)", err)
+
+	blockers, err := st.Deps.Blockers(f.aliceScope, final.ID)
+	must(t, err)
+	sameList(t, "Blockers", titles(blockers), []string{"Draft"})
+	if len(blockers) == 1 && blockers[0].Subject.Name != "Calculus" {
+		t.Errorf("blocker subject not preloaded: %+v", blockers[0].Subject)
+	}
+	_, err = st.Deps.Blockers(f.bobScope, final.ID)
+	notFound(t, "Blockers(other user's task)", err)
+
+	tasks, _, err := st.Deps.Graph(f.aliceScope)
+	must(t, err)
+	sameList(t, "graph tasks", titles(tasks), []string{"Review", "Draft", "Final"})
+	sameList(t, "graph edges", edges(), []string{
+		fmt.Sprintf("%d<-%d", draft.ID, review.ID),
+		fmt.Sprintf("%d<-%d", final.ID, draft.ID),
+	})
+	_, deps, err := st.Deps.Graph(f.bobScope)
+	must(t, err)
+	if len(deps) != 0 {
+		t.Errorf("other user's graph has %d edges", len(deps))
+	}
+
+	// dependencies of a task in the trash are left out until it is back
+	must(t, st.Tasks.Delete(f.aliceScope, draft.ID))
+	sameList(t, "edges with a task in the trash", edges(), []string{})
+	blockers, err = st.Deps.Blockers(f.aliceScope, final.ID)
+	must(t, err)
+	sameList(t, "Blockers in the trash", titles(blockers), []string{})
+	must(t, st.Trash.Restore(f.aliceScope, models.ResourceTasks, draft.ID))
+	if got := edges(); len(got) != 2 {
+		t.Errorf("edges after restore = %v", got)
+	}
+
+	must(t, st.Deps.Remove(f.aliceScope, final.ID, draft.ID))
+	notFound(t, "Remove(twice)", st.Deps.Remove(f.aliceScope, final.ID, draft.ID))
+	notFound(t, "Remove(other user's)", st.Deps.Remove(f.bobScope, draft.ID, review.ID))
+	sameList(t, "edges after Remove", edges(), []string{fmt.Sprintf("%d<-%d", draft.ID, review.ID)})
+}
+
+func testSubtasks(t *testing.T, st Stores) {
+	f := seed(t, st)
+	sub := func(title string, parent models.Task) models.Task {
+		t.Helper()
+		task := f.task(title, 0)
+		task.ParentID = &parent.ID
+		must(t, st.Tasks.Create(&task))
+		return task
+	}
+	item := func(task models.Task, title string, position int) models.ChecklistItem {
+		t.Helper()
+		i := models.ChecklistItem{TaskID: task.ID, Title: title, Position: position}
+		must(t, st.Subtasks.AddItem(f.aliceScope, &i))
+		return i
+	}
+	checklist := func(task models.Task) []string {
+		t.Helper()
+		items, err := st.Subtasks.Checklist(f.aliceScope, task.ID)
+		must(t, err)
+		out := make([]string, len(items))
+		for i, item := range items {
+			out[i] = fmt.Sprintf("%s %t", item.Title, item.Done)
+		}
+		return out
+	}
+
+	essay, quiz := f.task("Essay", 0), f.task("Quiz", 0)
+	must(t, st.Tasks.Create(&essay))
+	must(t, st.Tasks.Create(&quiz))
+	outline := sub("Outline", essay)
+	sources := sub("Sources", outline)
+	draft := sub("Draft", essay)
+
+	subtasks, err := st.Subtasks.Subtasks(f.aliceScope, essay.ID)
+	must(t, err)
+	sameList(t, "Subtasks", titles(subtasks), []string{"Outline", "Draft"})
+	if len(subtasks) > 0 && subtasks[0].Subject.Name != "Calculus" {
+		t.Errorf("subtask subject not preloaded: %+v", subtasks[0].Subject)
+	}
+	_, err = st.Subtasks.Subtasks(f.bobScope, essay.ID)
+	notFound(t, "Subtasks(other user's task)", err)
+
+	tasks, total, err := st.Tasks.GetTasks(f.aliceScope, &repository.TaskFilter{TopLevel: true, Sort: "title"})
+	must(t, err)
+	sameList(t, "GetTasks(top level)", titles(tasks), []string{"Essay", "Quiz"})
+	if total != 2 {
+		t.Errorf("GetTasks(top level) total = %d, want 2", total)
+	}
+
+	conclusion := item(essay, "Conclusion", 2)
+	intro := item(essay, "Intro", 0)
+	item(essay, "Body", 0)
+	item(sources, "Library", 0)
+	if intro.UserID != f.alice.ID || intro.ID == 0 {
+		t.Errorf("AddItem = %+v, want an id and the task's owner", intro)
+	}
+	notFound(t, "AddItem(other user's task)", st.Subtasks.AddItem(f.bobScope, &models.ChecklistItem{TaskID: essay.ID, Title: "x"}))
+	sameList(t, "Checklist", checklist(essay), []string{"Intro false", "Body false", "Conclusion false"})
+
+	must(t, st.Subtasks.UpdateItem(f.aliceScope, intro, map[string]interface{}{"done": true, "position": 3}))
+	sameList(t, "Checklist after UpdateItem", checklist(essay), []string{"Body false", "Conclusion false", "Intro true"})
+	_, err = st.Subtasks.GetItem(f.aliceScope, quiz.ID, intro.ID)
+	notFound(t, "GetItem(item of another task)", err)
+	notFound(t, "UpdateItem(other user's)", st.Subtasks.UpdateItem(f.bobScope, intro, map[string]interface{}{"done": false}))
+
+	desc, items, err := st.Subtasks.Descendants(f.aliceScope, []uint{essay.ID, quiz.ID})
+	must(t, err)
+	sameSet(t, "Descendants", titles(desc), []string{"Outline", "Draft", "Sources"})
+	if len(items) != 4 {
+		t.Errorf("Descendants returned %d checklist items, want 4", len(items))
+	}
+
+	// subtasks go to the trash and come back with their parent
+	must(t, st.Tasks.Delete(f.aliceScope, essay.ID))
+	sameList(t, "trash", trash(t, st, f.aliceScope), []string{"tasks Essay"})
+	_, err = st.Tasks.GetByID(f.aliceScope, sources.ID)
+	notFound(t, "GetByID(subtask of a deleted task)", err)
+	_, err = st.Subtasks.Checklist(f.aliceScope, essay.ID)
+	notFound(t, "Checklist(deleted task)", err)
+	must(t, st.Trash.Restore(f.aliceScope, models.ResourceTasks, essay.ID))
+	_, err = st.Tasks.GetByID(f.aliceScope, sources.ID)
+	must(t, err)
+	sameList(t, "Checklist after restore", checklist(essay), []string{"Body false", "Conclusion false", "Intro true"})
+
+	must(t, st.Tasks.Delete(f.aliceScope, draft.ID))
+	must(t, st.Tasks.Delete(f.aliceScope, essay.ID))
+	if err := st.Trash.Restore(f.aliceScope, models.ResourceTasks, outline.ID); !errors.Is(err, repository.ErrParentDeleted) {
+		t.Errorf("Restore(subtask of a deleted task): got error %v, want repository.ErrParentDeleted", err)
+	}
+	must(t, st.Trash.Restore(f.aliceScope, models.ResourceTasks, essay.ID))
+	subtasks, err = st.Subtasks.Subtasks(f.aliceScope, essay.ID)
+	must(t, err)
+	sameList(t, "Subtasks after restore", titles(subtasks), []string{"Outline"})
+
+	must(t, st.Subtasks.DeleteItem(f.aliceScope, conclusion))
+	notFound(t, "DeleteItem(twice)", st.Subtasks.DeleteItem(f.aliceScope, conclusion))
+	sameList(t, "Checklist after DeleteItem", checklist(essay), []string{"Body false", "Intro true"})
+
+	must(t, st.Tasks.Delete(f.aliceScope, essay.ID))
+	purged, err := st.Trash.Purge(time.Now().Add(time.Hour))
+	must(t, err)
+	if purged != 4 {
+		t.Errorf("Purge deleted %d rows, want the task and its three subtasks", purged)
+	}
+	_, items, err = st.Subtasks.Descendants(f.aliceScope, []uint{essay.ID, quiz.ID})
+	must(t, err)
+	if len(items) != 0 {
+		t.Errorf("%d checklist items left after Purge", len(items))
+	}
+}
+
+func testTags(t *testing.T, st Stores) {
+	f := seed(t, st)
+	tag := func(userID uint, name string) models.Tag {
+		t.Helper()
+		tag := models.Tag{UserID: userID, Name: name, Color: models.DefaultTagColor}
+		must(t, st.Tags.Create(&tag))
+		return tag
+	}
+	names := func(id uint) []string {
+		t.Helper()
+		task, err := st.Tasks.GetByID(f.aliceScope, id)
+		must(t, err)
+		out := make([]string, len(task.Tags))
+		for i, tag := range task.Tags {
+			out[i] = tag.Name
+		}
+		return out
+	}
+	filtered := func(filter repository.TaskFilter) []string {
+		t.Helper()
+		filter.Sort = "title"
+		tasks, total, err := st.Tasks.GetTasks(f.aliceScope, &filter)
+		must(t, err)
+		if int(total) != len(tasks) {
+			t.Errorf("GetTasks(%+v) total = %d for %d tasks", filter, total, len(tasks))
+		}
+		return titles(tasks)
+	}
+	reread := func(task models.Task) models.Task {
+		t.Helper()
+		task, err := st.Tasks.GetByID(f.aliceScope, task.ID)
+		must(t, err)
+		return task
+	}
+
+	exam, reading := tag(f.alice.ID, "exam"), tag(f.alice.ID, "Reading")
+	group := tag(f.alice.ID, "group")
+	bobs := tag(f.bob.ID, "exam")
+	if err := st.Tags.Create(&models.Tag{UserID: f.alice.ID, Name: "EXAM", Color: models.DefaultTagColor}); !errors.Is(err, repository.ErrTagExists) {
+		t.Errorf("Create(duplicate name): got error %v, want repository.ErrTagExists", err)
+	}
+	all, err := st.Tags.GetAll(f.aliceScope)
+	must(t, err)
+	if len(all) != 3 || all[0].Name != "exam" {
+		t.Errorf("GetAll = %+v, want alice's three tags by name", all)
+	}
+	_, err = st.Tags.GetByID(f.aliceScope, bobs.ID)
+	notFound(t, "GetByID(other user's tag)", err)
+
+	essay, quiz, lab := f.task("Essay", 0), f.task("Quiz", 0), f.task("Lab", 0)
+	must(t, st.Tasks.Create(&essay))
+	must(t, st.Tasks.Create(&quiz))
+	must(t, st.Tasks.Create(&lab))
+
+	must(t, st.Tags.Tag(f.aliceScope, essay, []uint{exam.ID, reading.ID, exam.ID}))
+	sameList(t, "tags", names(essay.ID), []string{"exam", "Reading"})
+	conflict(t, "Tag(stale version)", st.Tags.Tag(f.aliceScope, essay, []uint{group.ID}))
+	essay = reread(essay)
+	before := essay.Version
+	must(t, st.Tags.Tag(f.aliceScope, essay, []uint{exam.ID}))
+	if essay = reread(essay); essay.Version != before {
+		t.Errorf("Tag(tags it has) moved the version from %d to %d", before, essay.Version)
+	}
+	notFound(t, "Tag(other user's tag)", st.Tags.Tag(f.aliceScope, essay, []uint{bobs.ID}))
+	notFound(t, "Tag(other user's task)", st.Tags.Tag(f.bobScope, essay, []uint{bobs.ID}))
+	must(t, st.Tags.Tag(f.aliceScope, quiz, []uint{exam.ID, group.ID}))
+	must(t, st.Tags.Tag(f.aliceScope, lab, []uint{group.ID}))
+
+	sameList(t, "tags_any", filtered(repository.TaskFilter{TagsAny: []string{"READING", "group"}}), []string{"Essay", "Lab", "Quiz"})
+	sameList(t, "tags_all", filtered(repository.TaskFilter{TagsAll: []string{"exam", " group", "Exam"}}), []string{"Quiz"})
+	sameList(t, "tags_none", filtered(repository.TaskFilter{TagsNone: []string{"exam"}}), []string{"Lab"})
+	sameList(t, "tags_any and tags_none", filtered(repository.TaskFilter{TagsAny: []string{"group"}, TagsNone: []string{"reading"}}), []string{"Lab", "Quiz"})
+
+	quiz = reread(quiz)
+	before = quiz.Version
+	must(t, st.Tags.Update(f.aliceScope, exam.ID, map[string]interface{}{"name": "Finals", "color": "#e53935"}))
+	if quiz = reread(quiz); quiz.Version == before {
+		t.Error("Update(tag) did not move the version of a tagged task")
+	}
+	sameList(t, "tags after rename", names(quiz.ID), []string{"Finals", "group"})
+	if err := st.Tags.Update(f.aliceScope, reading.ID, map[string]interface{}{"name": "finals"}); !errors.Is(err, repository.ErrTagExists) {
+		t.Errorf("Update(duplicate name): got error %v, want repository.ErrTagExists", err)
+	}
+	notFound(t, "Update(other user's tag)", st.Tags.Update(f.bobScope, exam.ID, map[string]interface{}{"name": "x"}))
+
+	must(t, st.Tags.Untag(f.aliceScope, quiz, []uint{group.ID, reading.ID}))
+	sameList(t, "tags after Untag", names(quiz.ID), []string{"Finals"})
+	conflict(t, "Untag(stale version)", st.Tags.Untag(f.aliceScope, quiz, []uint{exam.ID}))
+
+	essay = reread(essay)
+	before = essay.Version
+	must(t, st.Tags.Delete(f.aliceScope, reading.ID))
+	notFound(t, "Delete(twice)", st.Tags.Delete(f.aliceScope, reading.ID))
+	notFound(t, "Delete(other user's tag)", st.Tags.Delete(f.bobScope, group.ID))
+	if essay = reread(essay); essay.Version == before {
+		t.Error("Delete(tag) did not move the version of a tagged task")
+	}
+	sameList(t, "tags after Delete", names(essay.ID), []string{"Finals"})
+	sameList(t, "tags_any after Delete", filtered(repository.TaskFilter{TagsAny: []string{"reading"}}), []string{})
+}
+
+func testTaskWhere(t *testing.T, st Stores) {
+	f := seed(t, st)
+	math := models.Subject{Name: "Math", UserID: f.alice.ID}
+	must(t, st.Subjects.Create(&math))
+
+	create := func(task models.Task) models.Task {
+		t.Helper()
+		must(t, st.Tasks.Create(&task))
+		return task
+	}
+	essay := create(f.task("Essay draft", 24*time.Hour))
+	set3 := f.task("Problem set 3", 72*time.Hour)
+	set3.SubjectID, set3.Status, set3.CompletedAt = math.ID, "done", &base
+	set3 = create(set3)
+	set4 := f.task("Problem set 4", 240*time.Hour)
+	set4.SubjectID = math.ID
+	create(set4)
+	reading := f.task("Read chapter", 120*time.Hour)
+	reading.Status, reading.EstimateMinutes = "in-progress", 90
+	reading = create(reading)
+	notes := f.task("Notes", 48*time.Hour)
+	notes.SubjectID, notes.ParentID = math.ID, &reading.ID
+	create(notes)
+	create(models.Task{Title: "Bob's exam", Status: "todo", Deadline: base, SubjectID: f.bobSub.ID, UserID: f.bob.ID})
+
+	exam := models.Tag{UserID: f.alice.ID, Name: "Exam", Color: models.DefaultTagColor}
+	homework := models.Tag{UserID: f.alice.ID, Name: "homework", Color: models.DefaultTagColor}
+	must(t, st.Tags.Create(&exam))
+	must(t, st.Tags.Create(&homework))
+	must(t, st.Tags.Tag(f.aliceScope, essay, []uint{exam.ID}))
+	must(t, st.Tags.Tag(f.aliceScope, reading, []uint{homework.ID}))
+
+	// now is base, a Monday at 09:00
+	cases := []struct {
+		where string
+		want  []string
+	}{
+		{"deadline=le=now+7d;status!=done;(subject==math,tag==EXAM)", []string{"Essay draft", "Notes"}},
+		{"title==problem*", []string{"Problem set 3", "Problem set 4"}},
+		{"title!=*SET*", []string{"Essay draft", "Notes", "Read chapter"}},
+		{"title=='essay draft' , title==\"read chapter\"", []string{"Essay draft", "Read chapter"}},
+		{"parent_id==null", []string{"Essay draft", "Problem set 3", "Problem set 4", "Read chapter"}},
+		{"parent_id!=null", []string{"Notes"}},
+		{fmt.Sprintf("parent_id!=%d", reading.ID), []string{"Essay draft", "Problem set 3", "Problem set 4", "Read chapter"}},
+		{"completed_at!=null", []string{"Problem set 3"}},
+		{"completed_at=lt=now+100d", []string{"Problem set 3"}},
+		{"estimate_minutes>=60", []string{"Read chapter"}},
+		{"tag=out=(exam,HOMEWORK)", []string{"Notes", "Problem set 3", "Problem set 4"}},
+		{"tag!=exam", []string{"Notes", "Problem set 3", "Problem set 4", "Read chapter"}},
+		{"status=in=(TODO,done)", []string{"Essay draft", "Notes", "Problem set 3", "Problem set 4"}},
+		{"deadline>today+2d", []string{"Notes", "Problem set 3", "Problem set 4", "Read chapter"}},
+		{"deadline=ge=2030-01-12", []string{"Problem set 4", "Read chapter"}},
+		{"subject=out=(math);((status==todo))", []string{"Essay draft"}},
+		{fmt.Sprintf("subject_id==%d,deadline=lt=%s", math.ID, base.Add(48*time.Hour).Format(time.RFC3339)), []string{"Essay draft", "Problem set 3", "Problem set 4", "Notes"}},
+	}
+	for _, c := range cases {
+		where, err := repository.ParseTaskWhere(c.where, base)
+		if err != nil {
+			t.Errorf("ParseTaskWhere(%q): %v", c.where, err)
+			continue
+		}
+		filter := repository.TaskFilter{Where: where, Sort: "title", Limit: 100}
+		tasks, total, err := st.Tasks.GetTasks(f.aliceScope, &filter)
+		must(t, err)
+		sameSet(t, c.where, titles(tasks), c.want)
+		if int(total) != len(c.want) {
+			t.Errorf("%s: total = %d, want %d", c.where, total, len(c.want))
+		}
+	}
+
+	// expressions combine with the other filters
+	where, err := repository.ParseTaskWhere("subject==math", base)
+	must(t, err)
+	tasks, _, err := st.Tasks.GetTasks(f.aliceScope, &repository.TaskFilter{Where: where, TopLevel: true, Status: "todo"})
+	must(t, err)
+	sameList(t, "subject==math, top level, todo", titles(tasks), []string{"Problem set 4"})
+
+	for _, where := range []string{
+		"",
+		"password_hash==x",
+		"title=lt=a",
+		"deadline==soon",
+		"estimate_minutes==-1",
+		"parent_id=gt=null",
+		"completed_at=in=(null,now)",
+		"status==",
+		"(status==todo",
+		"status==todo;",
+		"status~todo",
+		"status=like=todo",
+		"title=='unterminated",
+		"status==a,b",
+		strings.Repeat("(", 20) + "status==todo" + strings.Repeat(")", 20),
+		strings.TrimSuffix(strings.Repeat("status==todo;", 33), ";"),
+	} {
+		if _, err := repository.ParseTaskWhere(where, base); !errors.Is(err, repository.ErrInvalidQuery) {
+			t.Errorf("ParseTaskWhere(%q): got error %v, want repository.ErrInvalidQuery", where, err)
+		}
+	}
+}
+
+func testSearch(t *testing.T, st Stores) {
+	f := seed(t, st)
+	task := func(title, description string, user models.User, subject models.Subject) models.Task {
+		t.Helper()
+		task := models.Task{Title: title, Description: description, Status: "todo", Deadline: base, SubjectID: subject.ID, UserID: user.ID}
+		must(t, st.Tasks.Create(&task))
+		return task
+	}
+	search := func(scope repository.Scope, filter repository.SearchFilter) []string {
+		t.Helper()
+		hits, err := st.Search.Search(scope, &filter)
+		must(t, err)
+		out := make([]string, len(hits))
+		for i, h := range hits {
+			out[i] = h.Type + " " + h.Title
+		}
+		return out
+	}
+
+	philosophy := models.Subject{Name: "Philosophy", Description: "Ethics and Kant", UserID: f.alice.ID}
+	must(t, st.Subjects.Create(&philosophy))
+	quiz := task("Kant quiz", "", f.alice, philosophy)
+	task("Reading essay", "Compare Kant and Hume", f.alice, philosophy)
+	task("Integrals", "Calculus exercises", f.alice, f.aliceSub)
+	notes := task("Kant notes", "", f.alice, philosophy)
+	task("Kant summary", "", f.bob, f.bobSub)
+	for _, tk := range []models.Task{quiz, notes} {
+		must(t, st.Deadlines.Create(&models.Deadline{TaskID: tk.ID, UserID: f.alice.ID, DueDate: base}))
+	}
+	must(t, st.Tasks.Delete(f.aliceScope, notes.ID))
+
+	// title matches rank above description matches
+	sameList(t, "Search(kant)", search(f.aliceScope, repository.SearchFilter{Query: "Kant"}),
+		[]string{"deadlines Kant quiz", "tasks Kant quiz", "subjects Philosophy", "tasks Reading essay"})
+	sameList(t, "Search(other user)", search(f.bobScope, repository.SearchFilter{Query: "kant"}), []string{"tasks Kant summary"})
+	sameList(t, "Search(limit)", search(f.aliceScope, repository.SearchFilter{Query: "kant", Limit: 1}), []string{"deadlines Kant quiz"})
+	sameList(t, "Search(every word)", search(f.aliceScope, repository.SearchFilter{Query: "essays, kant!"}), []string{"tasks Reading essay"})
+	sameSet(t, "Search(prefix)", search(f.aliceScope, repository.SearchFilter{Query: "calc"}), []string{"subjects Calculus", "tasks Integrals"})
+	sameList(t, "Search(types)", search(f.aliceScope, repository.SearchFilter{Query: "calc", Types: []string{models.ResourceTasks}}), []string{"tasks Integrals"})
+	sameList(t, "Search(no words)", search(f.aliceScope, repository.SearchFilter{Query: " !? "}), []string{})
+
+	hits, err := st.Search.Search(f.aliceScope, &repository.SearchFilter{Query: "kant", Types: []string{models.ResourceDeadlines, models.ResourceSubjects}})
+	must(t, err)
+	if len(hits) != 2 || hits[0].TaskID == nil || *hits[0].TaskID != quiz.ID || hits[0].DueDate == nil {
+		t.Fatalf("deadline hits = %+v, want the quiz deadline with its task and due date", hits)
+	}
+	for _, h := range hits {
+		if !strings.Contains(h.Snippet, "<mark>Kant</mark>") {
+			t.Errorf("snippet of %s %d = %q, want Kant marked", h.Type, h.ID, h.Snippet)
+		}
+	}
+
+	tasks, _, err := st.Tasks.GetTasks(f.aliceScope, &repository.TaskFilter{Search: "kant"})
+	must(t, err)
+	sameList(t, "GetTasks(search) by rank", titles(tasks), []string{"Kant quiz", "Reading essay"})
+	tasks, _, err = st.Tasks.GetTasks(f.aliceScope, &repository.TaskFilter{Search: "read", Sort: "title"})
+	must(t, err)
+	sameList(t, "GetTasks(stemmed search)", titles(tasks), []string{"Reading essay"})
+}
+
+// newSeries creates a weekly series starting at base with occurrences
+// materialised for its first weeks.
+func newSeries(t *testing.T, st Stores, f fixture, rule string, weeks int) models.Task {
+	t.Helper()
+	master := f.task("Lab", 0)
+	master.RecurrenceRule = rule
+	must(t, st.Tasks.CreateSeries(&master))
+	for i := 1; i < weeks; i++ {
+		must(t, st.Tasks.CreateOccurrence(master, base.AddDate(0, 0, 7*i)))
+	}
+	return master
+}
+
+// series returns the titles and deadlines of the tasks in a series, by
+// deadline, as "title@day".
+func series(t *testing.T, st Stores, f fixture) []string {
+	t.Helper()
+	tasks, _, err := st.Tasks.GetTasks(f.aliceScope, &repository.TaskFilter{Sort: "deadline", Limit: 100})
+	must(t, err)
+	out := make([]string, len(tasks))
+	for i, task := range tasks {
+		out[i] = fmt.Sprintf("%s@%d", task.Title, int(task.Deadline.Sub(base).Hours()/24))
+	}
+	return out
+}
+
+func testSeriesOccurrences(t *testing.T, st Stores) {
+	f := seed(t, st)
+	master := newSeries(t, st, f, "FREQ=WEEKLY;COUNT=4", 3)
+
+	// generating an occurrence twice is a no-op
+	must(t, st.Tasks.CreateOccurrence(master, base.AddDate(0, 0, 7)))
+	sameList(t, "series", series(t, st, f), []string{"Lab@0", "Lab@7", "Lab@14"})
+
+	deadlines, err := st.Deadlines.GetAll(f.aliceScope)
+	must(t, err)
+	if len(deadlines) != 3 {
+		t.Errorf("series has %d deadline rows, want 3", len(deadlines))
+	}
+
+	masters, err := st.Tasks.GetSeriesMasters()
+	must(t, err)
+	if len(masters) != 1 || masters[0].ID != master.ID {
+		t.Errorf("GetSeriesMasters = %v", titles(masters))
+	}
+
+	until := base.AddDate(0, 1, 0)
+	must(t, st.Tasks.SetGeneratedUntil(master.ID, until))
+	got, _ := st.Tasks.GetByID(f.aliceScope, master.ID)
+	if got.RecurrenceGeneratedUntil == nil || !got.RecurrenceGeneratedUntil.Equal(until) {
+		t.Errorf("RecurrenceGeneratedUntil = %v, want %v", got.RecurrenceGeneratedUntil, until)
+	}
+
+	// editing the series edits every occurrence
+	must(t, st.Tasks.UpdateFollowing(f.aliceScope, master, map[string]interface{}{"title": "Lab report"}))
+	sameList(t, "after UpdateFollowing", series(t, st, f), []string{"Lab report@0", "Lab report@7", "Lab report@14"})
+}
+
+func testSeriesDetachMaster(t *testing.T, st Stores) {
+	f := seed(t, st)
+	master := newSeries(t, st, f, "FREQ=WEEKLY;COUNT=4", 3)
+
+	// editing only the first task hands the series to the next occurrence
+	must(t, st.Tasks.UpdateOccurrence(f.aliceScope, master, map[string]interface{}{"title": "Intro lab"}))
+	sameList(t, "after UpdateOccurrence", series(t, st, f), []string{"Intro lab@0", "Lab@7", "Lab@14"})
+
+	got, _ := st.Tasks.GetByID(f.aliceScope, master.ID)
+	if got.IsSeriesMaster() {
+		t.Errorf("edited first task still has rule %q", got.RecurrenceRule)
+	}
+	masters, err := st.Tasks.GetSeriesMasters()
+	must(t, err)
+	if len(masters) != 1 || masters[0].Deadline.Sub(base) != 7*24*time.Hour || masters[0].RecurrenceRule != "FREQ=WEEKLY;COUNT=3" {
+		t.Fatalf("new series master = %+v", masters)
+	}
+
+	// deleting the new first task hands the series on again
+	must(t, st.Tasks.DeleteOccurrence(f.aliceScope, masters[0]))
+	sameList(t, "after DeleteOccurrence", series(t, st, f), []string{"Intro lab@0", "Lab@14"})
+	masters, _ = st.Tasks.GetSeriesMasters()
+	if len(masters) != 1 || masters[0].RecurrenceRule != "FREQ=WEEKLY;COUNT=2" {
+		t.Errorf("series master after delete = %+v", masters)
+	}
+}
+
+func testSeriesSplit(t *testing.T, st Stores) {
+	f := seed(t, st)
+	master := newSeries(t, st, f, "FREQ=WEEKLY", 4)
+
+	tasks, _, err := st.Tasks.GetTasks(f.aliceScope, &repository.TaskFilter{Sort: "deadline"})
+	must(t, err)
+	third := tasks[2]
+
+	// "this and following" on the third occurrence splits the series there
+	must(t, st.Tasks.UpdateFollowing(f.aliceScope, third, map[string]interface{}{"title": "Project"}))
+	sameList(t, "after split", series(t, st, f), []string{"Lab@0", "Lab@7", "Project@14", "Project@21"})
+
+	got, _ := st.Tasks.GetByID(f.aliceScope, master.ID)
+	if got.RecurrenceRule != "FREQ=WEEKLY;UNTIL=20300121T085959Z" {
+		t.Errorf("original series rule = %q, want it to end before the split", got.RecurrenceRule)
+	}
+	got, _ = st.Tasks.GetByID(f.aliceScope, third.ID)
+	if got.SeriesID != nil || !got.IsSeriesMaster() {
+		t.Errorf("split occurrence did not become a series master: %+v", got)
+	}
+
+	// deleting "this and following" from the second occurrence ends the
+	// original series there and leaves the new one alone
+	second := tasks[1]
+	must(t, st.Tasks.DeleteFollowing(f.aliceScope, second))
+	sameList(t, "after DeleteFollowing", series(t, st, f), []string{"Lab@0", "Project@14", "Project@21"})
+}
+
+func testSeriesReschedule(t *testing.T, st Stores) {
+	f := seed(t, st)
+	master := newSeries(t, st, f, "FREQ=WEEKLY", 3)
+
+	tasks, _, err := st.Tasks.GetTasks(f.aliceScope, &repository.TaskFilter{Sort: "deadline"})
+	must(t, err)
+	second, third := tasks[1], tasks[2]
+	dueOf := func(taskID uint) []time.Time {
+		t.Helper()
+		ds, err := st.Deadlines.GetAll(f.aliceScope)
+		must(t, err)
+		var due []time.Time
+		for _, d := range ds {
+			if d.TaskID == taskID {
+				due = append(due, d.DueDate)
+			}
+		}
+		return due
+	}
+
+	// moving one occurrence moves its deadline row with it
+	moved := base.AddDate(0, 0, 8)
+	must(t, st.Tasks.UpdateOccurrence(f.aliceScope, second, map[string]interface{}{"deadline": moved}))
+	if due := dueOf(second.ID); len(due) != 1 || !due[0].Equal(moved) {
+		t.Errorf("deadline rows of the moved occurrence = %v, want %v", due, moved)
+	}
+
+	// moving the series drops the untouched future occurrences, but not
+	// the ones in the trash
+	must(t, st.Tasks.DeleteOccurrence(f.aliceScope, third))
+	master, err = st.Tasks.GetByID(f.aliceScope, master.ID)
+	must(t, err)
+	start := base.Add(time.Hour)
+	must(t, st.Tasks.UpdateFollowing(f.aliceScope, master, map[string]interface{}{"deadline": start}))
+	if due := dueOf(master.ID); len(due) != 1 || !due[0].Equal(start) {
+		t.Errorf("deadline rows of the series master = %v, want %v", due, start)
+	}
+	items, err := st.Trash.List(f.aliceScope)
+	must(t, err)
+	found := false
+	for _, item := range items {
+		found = found || item.Type == models.ResourceTasks && item.ID == third.ID
+	}
+	if !found {
+		t.Errorf("trashed occurrence is gone after the series moved: %+v", items)
+	}
+}
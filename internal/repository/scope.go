@@ -0,0 +1,36 @@
+package repository
+
+import "gorm.io/gorm"
+
+// Scope restricts repository queries to the rows a caller may see.
+// Regular users only see their own rows; admins see every tenant.
+type Scope struct {
+	UserID uint
+	Admin  bool
+}
+
+// Allows reports whether a row owned by ownerID is visible in the scope.
+func (s Scope) Allows(ownerID uint) bool {
+	return s.Admin || s.UserID == ownerID
+}
+
+func (s Scope) owned(column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if s.Admin {
+			return db
+		}
+		return db.Where(column+" = ?", s.UserID)
+	}
+}
+
+// affected turns a write that matched no rows into gorm.ErrRecordNotFound,
+// so callers can answer 404 for rows that are missing or belong to someone else.
+func affected(tx *gorm.DB) error {
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
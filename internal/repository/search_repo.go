@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tasks and subjects carry a search_vector column that Postgres keeps up to
+// date from their title or name (weight A) and description (weight B). The
+// vectors are built with the SearchLanguage text search configuration, so
+// words are stemmed: "essays" finds "essay". Every word of a query must
+// match, and matches the start of longer words too.
+
+// SearchLanguage is the text search configuration of the search vectors
+// and queries.
+const SearchLanguage = "english"
+
+// headlineOptions make ts_headline mark matches like SearchHit.Snippet.
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MinWords=10, MaxWords=30"
+
+type SearchRepository struct {
+	db *gorm.DB
+}
+
+func NewSearchRepository(db *gorm.DB) *SearchRepository {
+	return &SearchRepository{db}
+}
+
+// SearchTerms splits a search query into lower-case words, each once.
+func SearchTerms(query string) []string {
+	terms := []string{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !slices.Contains(terms, w) {
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+// tsQuery is the to_tsquery expression matching all terms as prefixes.
+// Terms only hold letters and digits, so they need no quoting.
+func tsQuery(terms []string) clause.Expr {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = t + ":*"
+	}
+	return gorm.Expr("to_tsquery(?, ?)", SearchLanguage, strings.Join(parts, " & "))
+}
+
+// hitColumns selects a SearchHit of resource with the given id column from
+// a row of table, whose title and body columns are searched. The snippet
+// comes from the body when it matches and from the title otherwise.
+func hitColumns(resource, id, table, title, body string, q clause.Expr) clause.Expr {
+	title, body = table+"."+title, table+"."+body
+	return gorm.Expr(fmt.Sprintf(`'%s' AS type, %s AS id, %s AS title, ts_rank(%s.search_vector, ?) AS rank,
+		CASE WHEN to_tsvector(?, %[5]s) @@ ? THEN ts_headline(?, %[5]s, ?, ?)
+		ELSE ts_headline(?, %[3]s, ?, ?) END AS snippet`, resource, id, title, table, body),
+		q, SearchLanguage, q, SearchLanguage, q, headlineOptions, SearchLanguage, q, headlineOptions)
+}
+
+// Search returns the best matches for the query among the subjects, tasks
+// and deadlines in scope, best first.
+func (r *SearchRepository) Search(scope Scope, filter *SearchFilter) ([]models.SearchHit, error) {
+	hits := []models.SearchHit{}
+	terms := SearchTerms(filter.Query)
+	if len(terms) == 0 {
+		return hits, nil
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	q := tsQuery(terms)
+
+	find := func(resource string, tx *gorm.DB) error {
+		if !filter.Wants(resource) {
+			return nil
+		}
+		var part []models.SearchHit
+		err := tx.Order("rank DESC, id").Limit(filter.Limit).Scan(&part).Error
+		hits = append(hits, part...)
+		return err
+	}
+	err := find(models.ResourceSubjects, r.db.Model(&models.Subject{}).Scopes(scope.owned("user_id")).
+		Select("?", hitColumns(models.ResourceSubjects, "subjects.id", "subjects", "name", "description", q)).
+		Where("subjects.search_vector @@ ?", q))
+	if err != nil {
+		return nil, err
+	}
+	err = find(models.ResourceTasks, r.db.Model(&models.Task{}).Scopes(scope.owned("user_id")).
+		Select("?", hitColumns(models.ResourceTasks, "tasks.id", "tasks", "title", "description", q)).
+		Where("tasks.search_vector @@ ?", q))
+	if err != nil {
+		return nil, err
+	}
+	err = find(models.ResourceDeadlines, r.db.Model(&models.Deadline{}).Scopes(scope.owned("deadlines.user_id")).
+		Joins("JOIN tasks ON tasks.id = deadlines.task_id AND tasks.deleted_at IS NULL").
+		Select("?, deadlines.task_id, deadlines.due_date",
+			hitColumns(models.ResourceDeadlines, "deadlines.id", "tasks", "title", "description", q)).
+		Where("tasks.search_vector @@ ?", q))
+	if err != nil {
+		return nil, err
+	}
+	return RankHits(hits, filter.Limit), nil
+}
+
+// RankHits orders hits best first, then by type and id, and keeps the
+// first limit.
+func RankHits(hits []models.SearchHit, limit int) []models.SearchHit {
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		switch {
+		case a.Rank != b.Rank:
+			return a.Rank > b.Rank
+		case a.Type != b.Type:
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+)
+
+// The Store interfaces are what controllers and services depend on. The
+// gorm-backed repositories in this package implement them for Postgres;
+// package memory implements them in memory for tests. Both must pass the
+// conformance suite in package repotest.
+//
+// Missing rows, rows in the trash and rows outside the caller's Scope are
+// reported as gorm.ErrRecordNotFound by every implementation. Writes that take a row
+// rather than an id fail with ErrConflict when the row's version has moved
+// on since it was read (see version.go). List methods read a page of a
+// list as its ListSpec allows (see list.go).
+
+type UserStore interface {
+	Create(user *models.User) error
+	GetAll() ([]models.User, error)
+	List(q *ListQuery) (Page[models.User], error)
+	GetByID(id uint) (models.User, error)
+	GetByEmail(email string) (models.User, error)
+	Update(id uint, data map[string]interface{}) error
+	Delete(id uint) error
+}
+
+type SubjectStore interface {
+	Create(subject *models.Subject) error
+	GetAll(scope Scope) ([]models.Subject, error)
+	List(scope Scope, q *ListQuery) (Page[models.Subject], error)
+	GetByID(scope Scope, id uint) (models.Subject, error)
+	GetByName(scope Scope, name string) (models.Subject, error)
+	Update(scope Scope, subject models.Subject, data map[string]interface{}) error
+	Delete(scope Scope, subject models.Subject) error
+}
+
+type TaskStore interface {
+	Create(task *models.Task) error
+	GetAll(scope Scope) ([]models.Task, error)
+	GetOpenOrDueAfter(scope Scope, t time.Time) ([]models.Task, error)
+	GetByID(scope Scope, id uint) (models.Task, error)
+	GetByExternalUID(scope Scope, uid string) (models.Task, error)
+	GetTasks(scope Scope, filter *TaskFilter) ([]models.Task, int64, error)
+	GetTaskPage(scope Scope, filter *TaskFilter, cursor *Cursor) (Page[models.Task], error)
+	Update(scope Scope, id uint, data map[string]interface{}) error
+	Delete(scope Scope, id uint) error
+
+	// recurring series, see task_series.go; the task arguments are
+	// version-guarded
+	CreateSeries(task *models.Task) error
+	GetSeriesMasters() ([]models.Task, error)
+	CreateOccurrence(master models.Task, at time.Time) error
+	SetGeneratedUntil(id uint, until time.Time) error
+	UpdateOccurrence(scope Scope, task models.Task, data map[string]interface{}) error
+	UpdateFollowing(scope Scope, task models.Task, data map[string]interface{}) error
+	DeleteOccurrence(scope Scope, task models.Task) error
+	DeleteFollowing(scope Scope, task models.Task) error
+}
+
+type DeadlineStore interface {
+	Create(d *models.Deadline) error
+	GetAll(scope Scope) ([]models.Deadline, error)
+	List(scope Scope, q *ListQuery) (Page[models.Deadline], error)
+	GetByID(scope Scope, id uint) (models.Deadline, error)
+	GetDueAfter(scope Scope, t time.Time) ([]models.Deadline, error)
+	SetForTask(task models.Task, due time.Time) error
+	Delete(scope Scope, d models.Deadline) error
+}
+
+// ChangeStore reads the change log of tasks, subjects and deadlines.
+// Sequence numbers are global, but each user's changes are numbered in the
+// order they committed.
+type ChangeStore interface {
+	Latest(userID uint) (uint64, error)
+	Since(userID uint, after, through uint64) ([]models.Change, error)
+	Horizon() (oldest, newest uint64, err error)
+	Purge(before time.Time) (int64, error)
+}
+
+// TrashStore lists, restores and purges the subjects, tasks and deadlines
+// in the trash (see trash_repo.go).
+type TrashStore interface {
+	List(scope Scope) ([]models.TrashItem, error)
+	Restore(scope Scope, resource string, id uint) error
+	Purge(before time.Time) (int64, error)
+}
+
+// DependencyStore keeps which tasks wait for which (see dependency_repo.go).
+// Add fails with ErrDependencyCycle rather than let a task wait for itself.
+type DependencyStore interface {
+	Add(scope Scope, taskID, blockerID uint) (models.TaskDependency, error)
+	Remove(scope Scope, taskID, blockerID uint) error
+	Blockers(scope Scope, taskID uint) ([]models.Task, error)
+	Graph(scope Scope) ([]models.Task, []models.TaskDependency, error)
+}
+
+// SubtaskStore reads the subtasks of tasks and keeps their checklists (see
+// subtask_repo.go). Subtasks themselves are created and changed through
+// TaskStore with their ParentID set.
+type SubtaskStore interface {
+	Subtasks(scope Scope, parentID uint) ([]models.Task, error)
+	Descendants(scope Scope, ids []uint) ([]models.Task, []models.ChecklistItem, error)
+	Checklist(scope Scope, taskID uint) ([]models.ChecklistItem, error)
+	GetItem(scope Scope, taskID, id uint) (models.ChecklistItem, error)
+	AddItem(scope Scope, item *models.ChecklistItem) error
+	UpdateItem(scope Scope, item models.ChecklistItem, data map[string]interface{}) error
+	DeleteItem(scope Scope, item models.ChecklistItem) error
+}
+
+// TagStore keeps users' tags and which tasks carry them (see tag_repo.go).
+// Tag and Untag are version-guarded on the task; tag names are unique per
+// user and clash with ErrTagExists.
+type TagStore interface {
+	Create(tag *models.Tag) error
+	GetAll(scope Scope) ([]models.Tag, error)
+	GetByID(scope Scope, id uint) (models.Tag, error)
+	Update(scope Scope, id uint, data map[string]interface{}) error
+	Delete(scope Scope, id uint) error
+	Tag(scope Scope, task models.Task, tagIDs []uint) error
+	Untag(scope Scope, task models.Task, tagIDs []uint) error
+}
+
+// SearchStore is full-text search across subjects, tasks and deadlines
+// (see search_repo.go).
+type SearchStore interface {
+	Search(scope Scope, filter *SearchFilter) ([]models.SearchHit, error)
+}
+
+var (
+	_ UserStore       = (*UserRepository)(nil)
+	_ SubjectStore    = (*SubjectRepository)(nil)
+	_ TaskStore       = (*TaskRepository)(nil)
+	_ DeadlineStore   = (*DeadlineRepository)(nil)
+	_ ChangeStore     = (*ChangeRepository)(nil)
+	_ TrashStore      = (*TrashRepository)(nil)
+	_ DependencyStore = (*DependencyRepository)(nil)
+	_ SubtaskStore    = (*SubtaskRepository)(nil)
+	_ TagStore        = (*TagRepository)(nil)
+	_ SearchStore     = (*SearchRepository)(nil)
+)
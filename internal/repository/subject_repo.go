@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/kadyrbayev2005/studysync/internal/models"
 
 	"gorm.io/gorm"
@@ -18,22 +20,51 @@ func (r *SubjectRepository) Create(subject *models.Subject) error {
 	return r.db.Create(subject).Error
 }
 
-func (r *SubjectRepository) GetAll() ([]models.Subject, error) {
+func (r *SubjectRepository) GetAll(scope Scope) ([]models.Subject, error) {
 	var subjects []models.Subject
-	err := r.db.Find(&subjects).Error
+	err := r.db.Scopes(scope.owned("user_id")).Find(&subjects).Error
 	return subjects, err
 }
 
-func (r *SubjectRepository) GetByID(id uint) (models.Subject, error) {
+// List reads a page of the subjects in scope (see SubjectList).
+func (r *SubjectRepository) List(scope Scope, q *ListQuery) (Page[models.Subject], error) {
+	return list[models.Subject](r.db.Model(&models.Subject{}).Scopes(scope.owned("user_id")), SubjectList, q)
+}
+
+func (r *SubjectRepository) GetByID(scope Scope, id uint) (models.Subject, error) {
 	var subject models.Subject
-	err := r.db.First(&subject, id).Error
+	err := r.db.Scopes(scope.owned("user_id")).First(&subject, id).Error
 	return subject, err
 }
 
-func (r *SubjectRepository) Update(id uint, data map[string]interface{}) error {
-	return r.db.Model(&models.Subject{}).Where("id = ?", id).Updates(data).Error
+// Update applies data to subject as long as it is still at the version it
+// was read at.
+func (r *SubjectRepository) Update(scope Scope, subject models.Subject, data map[string]interface{}) error {
+	tx := r.db.Model(&models.Subject{}).Scopes(scope.owned("user_id"), atVersion(subject.Version)).
+		Where("id = ?", subject.ID).Updates(bumped(data))
+	return guarded(tx, &models.Subject{}, scope, subject.ID)
 }
 
-func (r *SubjectRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Subject{}, id).Error
+// Delete moves subject to the trash, with its tasks and their deadlines, as
+// long as it is still at the version it was read at.
+func (r *SubjectRepository) Delete(scope Scope, subject models.Subject) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		at := time.Now()
+		res := tx.Model(&models.Subject{}).Scopes(scope.owned("user_id"), atVersion(subject.Version)).
+			Where("id = ?", subject.ID).Updates(trashed(at))
+		if err := guarded(res, &models.Subject{}, scope, subject.ID); err != nil {
+			return err
+		}
+		_, err := trashTasks(tx, at, func(db *gorm.DB) *gorm.DB {
+			return db.Where("subject_id = ?", subject.ID)
+		})
+		return err
+	})
+}
+
+// GetByName finds a subject by name, ignoring case.
+func (r *SubjectRepository) GetByName(scope Scope, name string) (models.Subject, error) {
+	var subject models.Subject
+	err := r.db.Scopes(scope.owned("user_id")).Where("LOWER(name) = LOWER(?)", name).First(&subject).Error
+	return subject, err
 }
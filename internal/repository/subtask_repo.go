@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"gorm.io/gorm"
+)
+
+// A subtask is a task with a parent_id; it goes to the trash and comes back
+// with its parent (see trash_repo.go). Checklist items belong to a task and
+// are out of reach while it is in the trash.
+
+type SubtaskRepository struct {
+	db *gorm.DB
+}
+
+func NewSubtaskRepository(db *gorm.DB) *SubtaskRepository {
+	return &SubtaskRepository{db}
+}
+
+// liveTask checks that the task is in scope and not in the trash, and
+// returns it.
+func (r *SubtaskRepository) liveTask(scope Scope, id uint) (models.Task, error) {
+	var task models.Task
+	err := r.db.Scopes(scope.owned("user_id")).First(&task, id).Error
+	return task, err
+}
+
+// Subtasks returns the direct subtasks of the task, by id, with their
+// subject.
+func (r *SubtaskRepository) Subtasks(scope Scope, parentID uint) ([]models.Task, error) {
+	if _, err := r.liveTask(scope, parentID); err != nil {
+		return nil, err
+	}
+	tasks := []models.Task{}
+	err := r.db.Scopes(scope.owned("user_id"), withTags).Preload("Subject").Where("parent_id = ?", parentID).Order("id").Find(&tasks).Error
+	return tasks, err
+}
+
+// Descendants returns the subtasks of the tasks, at any depth and with
+// their subject, and the checklist items of the tasks and their subtasks.
+func (r *SubtaskRepository) Descendants(scope Scope, ids []uint) ([]models.Task, []models.ChecklistItem, error) {
+	tasks := []models.Task{}
+	all := append([]uint{}, ids...)
+	for level := ids; len(level) > 0; {
+		var subtasks []models.Task
+		if err := r.db.Scopes(scope.owned("user_id")).Preload("Subject").Where("parent_id IN ?", level).
+			Order("id").Find(&subtasks).Error; err != nil {
+			return nil, nil, err
+		}
+		level = make([]uint, len(subtasks))
+		for i, t := range subtasks {
+			level[i] = t.ID
+		}
+		tasks, all = append(tasks, subtasks...), append(all, level...)
+	}
+
+	items := []models.ChecklistItem{}
+	if len(all) > 0 {
+		if err := r.db.Scopes(scope.owned("user_id")).Where("task_id IN ?", all).
+			Order("task_id, position, id").Find(&items).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	return tasks, items, nil
+}
+
+// Checklist returns the checklist of the task.
+func (r *SubtaskRepository) Checklist(scope Scope, taskID uint) ([]models.ChecklistItem, error) {
+	if _, err := r.liveTask(scope, taskID); err != nil {
+		return nil, err
+	}
+	items := []models.ChecklistItem{}
+	err := r.db.Where("task_id = ?", taskID).Order("position, id").Find(&items).Error
+	return items, err
+}
+
+func (r *SubtaskRepository) GetItem(scope Scope, taskID, id uint) (models.ChecklistItem, error) {
+	var item models.ChecklistItem
+	if _, err := r.liveTask(scope, taskID); err != nil {
+		return item, err
+	}
+	err := r.db.Where("task_id = ?", taskID).First(&item, id).Error
+	return item, err
+}
+
+// AddItem adds the item to the checklist of its task, which it takes its
+// owner from.
+func (r *SubtaskRepository) AddItem(scope Scope, item *models.ChecklistItem) error {
+	task, err := r.liveTask(scope, item.TaskID)
+	if err != nil {
+		return err
+	}
+	item.UserID = task.UserID
+	return r.db.Create(item).Error
+}
+
+func (r *SubtaskRepository) UpdateItem(scope Scope, item models.ChecklistItem, data map[string]interface{}) error {
+	if _, err := r.liveTask(scope, item.TaskID); err != nil {
+		return err
+	}
+	return affected(r.db.Model(&models.ChecklistItem{}).Where("id = ? AND task_id = ?", item.ID, item.TaskID).Updates(data))
+}
+
+func (r *SubtaskRepository) DeleteItem(scope Scope, item models.ChecklistItem) error {
+	if _, err := r.liveTask(scope, item.TaskID); err != nil {
+		return err
+	}
+	return affected(r.db.Where("task_id = ?", item.TaskID).Delete(&models.ChecklistItem{}, item.ID))
+}
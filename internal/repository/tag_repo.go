@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tags label tasks across subjects. A task's tags are part of the task:
+// tagging or untagging it, and renaming, recolouring or deleting one of its
+// tags, moves its version on.
+
+// ErrTagExists is returned when a user already has a tag with the name.
+var ErrTagExists = errors.New("repository: a tag with that name already exists")
+
+// taskTag is a row of the task_tags join table.
+type taskTag struct {
+	TaskID uint
+	TagID  uint
+}
+
+func (taskTag) TableName() string { return "task_tags" }
+
+type TagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) *TagRepository {
+	return &TagRepository{db}
+}
+
+// withTags preloads the tags of tasks, by name ignoring case.
+func withTags(db *gorm.DB) *gorm.DB {
+	return db.Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("LOWER(tags.name), tags.id")
+	})
+}
+
+// tagNameFree checks that the user has no tag other than id called name.
+func tagNameFree(tx *gorm.DB, userID, id uint, name string) error {
+	var n int64
+	err := tx.Model(&models.Tag{}).Where("user_id = ? AND id <> ? AND LOWER(name) = LOWER(?)", userID, id, name).
+		Count(&n).Error
+	if err == nil && n > 0 {
+		err = ErrTagExists
+	}
+	return err
+}
+
+// bumpTagged moves on the version of the live tasks tagged with tagID.
+func bumpTagged(tx *gorm.DB, tagID uint) error {
+	tagged := tx.Model(&taskTag{}).Where("tag_id = ?", tagID).Select("task_id")
+	return tx.Model(&models.Task{}).Where("id IN (?)", tagged).Updates(bumped(map[string]interface{}{})).Error
+}
+
+func (r *TagRepository) Create(tag *models.Tag) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tagNameFree(tx, tag.UserID, 0, tag.Name); err != nil {
+			return err
+		}
+		return tx.Create(tag).Error
+	})
+}
+
+// GetAll returns the tags in scope by name, ignoring case.
+func (r *TagRepository) GetAll(scope Scope) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	err := r.db.Scopes(scope.owned("user_id")).Order("LOWER(name), id").Find(&tags).Error
+	return tags, err
+}
+
+func (r *TagRepository) GetByID(scope Scope, id uint) (models.Tag, error) {
+	var tag models.Tag
+	err := r.db.Scopes(scope.owned("user_id")).First(&tag, id).Error
+	return tag, err
+}
+
+func (r *TagRepository) Update(scope Scope, id uint, data map[string]interface{}) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var tag models.Tag
+		if err := tx.Scopes(scope.owned("user_id")).First(&tag, id).Error; err != nil {
+			return err
+		}
+		if name, ok := data["name"].(string); ok {
+			if err := tagNameFree(tx, tag.UserID, tag.ID, name); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&tag).Updates(data).Error; err != nil {
+			return err
+		}
+		return bumpTagged(tx, tag.ID)
+	})
+}
+
+// Delete deletes the tag and takes it off its tasks.
+func (r *TagRepository) Delete(scope Scope, id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var tag models.Tag
+		if err := tx.Scopes(scope.owned("user_id")).First(&tag, id).Error; err != nil {
+			return err
+		}
+		if err := bumpTagged(tx, tag.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&tag).Error
+	})
+}
+
+// Tag adds the tags, which must belong to the task's owner, to the task as
+// long as it is still at the version it was read at. Tags it already has
+// are left alone.
+func (r *TagRepository) Tag(scope Scope, task models.Task, tagIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		ids := uniqueIDs(tagIDs)
+		if err := taggable(tx, scope, task, ids); err != nil {
+			return err
+		}
+		rows := make([]taskTag, len(ids))
+		for i, id := range ids {
+			rows[i] = taskTag{TaskID: task.ID, TagID: id}
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		return tx.Model(&models.Task{}).Where("id = ?", task.ID).Updates(bumped(map[string]interface{}{})).Error
+	})
+}
+
+// Untag takes the tags off the task as long as it is still at the version
+// it was read at.
+func (r *TagRepository) Untag(scope Scope, task models.Task, tagIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		ids := uniqueIDs(tagIDs)
+		if err := taggable(tx, scope, task, ids); err != nil {
+			return err
+		}
+		res := tx.Where("task_id = ? AND tag_id IN ?", task.ID, ids).Delete(&taskTag{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		return tx.Model(&models.Task{}).Where("id = ?", task.ID).Updates(bumped(map[string]interface{}{})).Error
+	})
+}
+
+// taggable claims the task and checks that the tags belong to its owner.
+func taggable(tx *gorm.DB, scope Scope, task models.Task, tagIDs []uint) error {
+	if err := claim(tx, &models.Task{}, scope, task.ID, task.Version); err != nil {
+		return err
+	}
+	var t models.Task
+	if err := tx.Scopes(scope.owned("user_id")).Select("id", "user_id").First(&t, task.ID).Error; err != nil {
+		return err
+	}
+	var n int64
+	if err := tx.Model(&models.Tag{}).Where("id IN ? AND user_id = ?", tagIDs, t.UserID).Count(&n).Error; err != nil {
+		return err
+	}
+	if int(n) != len(tagIDs) {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func uniqueIDs(ids []uint) []uint {
+	return slices.Compact(slices.Sorted(slices.Values(ids)))
+}
+
+// TagNames normalises the tag names of a filter: trimmed, lower case and
+// each once.
+func TagNames(names []string) []string {
+	out := []string{}
+	for _, n := range names {
+		n = strings.ToLower(strings.TrimSpace(n))
+		if n != "" && !slices.Contains(out, n) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
@@ -2,9 +2,11 @@ package repository
 
 import (
 	"strings"
+	"time"
 
 	"github.com/kadyrbayev2005/studysync/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TaskRepository struct {
@@ -16,34 +18,58 @@ func NewTaskRepository(db *gorm.DB) *TaskRepository {
 }
 
 func (r *TaskRepository) Create(task *models.Task) error {
-	return r.db.Create(task).Error
+	return r.db.Omit("Subject", "Tags").Create(task).Error
 }
 
-func (r *TaskRepository) GetAll() ([]models.Task, error) {
+func (r *TaskRepository) GetAll(scope Scope) ([]models.Task, error) {
 	var tasks []models.Task
-	err := r.db.Preload("Subject").Find(&tasks).Error
+	err := r.db.Scopes(scope.owned("user_id"), withTags).Preload("Subject").Find(&tasks).Error
 	return tasks, err
 }
 
-func (r *TaskRepository) GetByID(id uint) (models.Task, error) {
+// GetOpenOrDueAfter returns tasks that are not done yet or whose deadline
+// is after t. Tasks finished before completion times were recorded count
+// as done by their status.
+func (r *TaskRepository) GetOpenOrDueAfter(scope Scope, t time.Time) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.db.Scopes(scope.owned("user_id"), withTags).Preload("Subject").
+		Where("(completed_at IS NULL AND status <> ?) OR deadline > ?", "done", t).Order("deadline").Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *TaskRepository) GetByID(scope Scope, id uint) (models.Task, error) {
 	var task models.Task
-	err := r.db.Preload("Subject").First(&task, id).Error
+	err := r.db.Scopes(scope.owned("user_id"), withTags).Preload("Subject").First(&task, id).Error
 	return task, err
 }
 
-func (r *TaskRepository) Update(id uint, data map[string]interface{}) error {
-	return r.db.Model(&models.Task{}).Where("id = ?", id).Updates(data).Error
+// GetByExternalUID finds the task imported from the calendar entry uid.
+func (r *TaskRepository) GetByExternalUID(scope Scope, uid string) (models.Task, error) {
+	var task models.Task
+	err := r.db.Scopes(scope.owned("user_id"), withTags).Preload("Subject").Where("external_uid = ?", uid).First(&task).Error
+	return task, err
 }
 
-func (r *TaskRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Task{}, id).Error
+func (r *TaskRepository) Update(scope Scope, id uint, data map[string]interface{}) error {
+	return affected(r.db.Model(&models.Task{}).Scopes(scope.owned("user_id")).Where("id = ?", id).Updates(bumped(data)))
 }
 
-func (r *TaskRepository) GetTasks(filter *TaskFilter) ([]models.Task, int64, error) {
-	var tasks []models.Task
-	var total int64
+// Delete moves the task and its deadlines to the trash.
+func (r *TaskRepository) Delete(scope Scope, id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		n, err := trashTasks(tx, time.Now(), func(db *gorm.DB) *gorm.DB {
+			return db.Scopes(scope.owned("user_id")).Where("id = ?", id)
+		})
+		if err == nil && n == 0 {
+			err = gorm.ErrRecordNotFound
+		}
+		return err
+	})
+}
 
-	tx := r.db.Model(&models.Task{}).Preload("Subject")
+// filtered selects the tasks that match the filter, in no order.
+func (r *TaskRepository) filtered(scope Scope, filter *TaskFilter) *gorm.DB {
+	tx := r.db.Model(&models.Task{}).Scopes(scope.owned("user_id"), withTags).Preload("Subject")
 
 	if strings.TrimSpace(filter.Status) != "" {
 		tx = tx.Where("status = ?", filter.Status)
@@ -53,9 +79,9 @@ func (r *TaskRepository) GetTasks(filter *TaskFilter) ([]models.Task, int64, err
 		tx = tx.Where("subject_id = ?", *filter.SubjectID)
 	}
 
-	if strings.TrimSpace(filter.Search) != "" {
-		q := "%" + strings.ToLower(filter.Search) + "%"
-		tx = tx.Where("LOWER(title) LIKE ? OR LOWER(description) LIKE ?", q, q)
+	terms := SearchTerms(filter.Search)
+	if len(terms) > 0 {
+		tx = tx.Where("search_vector @@ ?", tsQuery(terms))
 	}
 
 	if filter.DeadlineBefore != nil {
@@ -65,10 +91,43 @@ func (r *TaskRepository) GetTasks(filter *TaskFilter) ([]models.Task, int64, err
 		tx = tx.Where("deadline >= ?", *filter.DeadlineAfter)
 	}
 
-	if err := tx.Count(&total).Error; err != nil {
-		return nil, 0, err
+	if filter.TopLevel {
+		tx = tx.Where("parent_id IS NULL")
+	}
+
+	if filter.Where != nil {
+		tx = tx.Where(filter.Where.taskExpr())
+	}
+
+	tagged := func(names []string) *gorm.DB {
+		return r.db.Model(&taskTag{}).Joins("JOIN tags ON tags.id = task_tags.tag_id").
+			Where("task_tags.task_id = tasks.id AND LOWER(tags.name) IN ?", names)
 	}
+	if names := TagNames(filter.TagsAny); len(names) > 0 {
+		tx = tx.Where("EXISTS (?)", tagged(names).Select("1"))
+	}
+	if names := TagNames(filter.TagsAll); len(names) > 0 {
+		tx = tx.Where("(?) = ?", tagged(names).Select("COUNT(*)"), len(names))
+	}
+	if names := TagNames(filter.TagsNone); len(names) > 0 {
+		tx = tx.Where("NOT EXISTS (?)", tagged(names).Select("1"))
+	}
+
+	return tx
+}
+
+func (r *TaskRepository) GetTasks(scope Scope, filter *TaskFilter) ([]models.Task, int64, error) {
+	var tasks []models.Task
+	total := int64(-1)
 
+	tx := r.filtered(scope, filter)
+	if !filter.SkipTotal {
+		if err := tx.Count(&total).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+
+	terms := SearchTerms(filter.Search)
 	allowedSorts := map[string]bool{
 		"created_at":      true,
 		"created_at desc": true,
@@ -78,12 +137,13 @@ func (r *TaskRepository) GetTasks(filter *TaskFilter) ([]models.Task, int64, err
 		"title desc":      true,
 	}
 	sort := strings.TrimSpace(filter.Sort)
-	if sort == "" {
-		sort = "created_at desc"
-	}
-	if allowedSorts[sort] {
+	switch {
+	case sort == "" && len(terms) > 0:
+		// best matches first
+		tx = tx.Order(clause.OrderBy{Expression: gorm.Expr("ts_rank(search_vector, ?) DESC, created_at DESC", tsQuery(terms))})
+	case allowedSorts[sort]:
 		tx = tx.Order(sort)
-	} else {
+	default:
 		// fallback на безопасный сорт
 		tx = tx.Order("created_at desc")
 	}
@@ -108,3 +168,19 @@ func (r *TaskRepository) GetTasks(filter *TaskFilter) ([]models.Task, int64, err
 
 	return tasks, total, nil
 }
+
+// GetTaskPage reads the page of tasks after the cursor, or before it, in
+// the order of TaskList.SortOrDefault(filter.Sort). A nil cursor reads the
+// first page. Cursor pages of a search are not ranked.
+func (r *TaskRepository) GetTaskPage(scope Scope, filter *TaskFilter, cursor *Cursor) (Page[models.Task], error) {
+	q := &ListQuery{
+		Limit:     filter.Limit,
+		Sort:      TaskList.SortOrDefault(filter.Sort),
+		Cursors:   true,
+		Cursor:    cursor,
+		SkipTotal: filter.SkipTotal,
+	}
+	page, err := list[models.Task](r.filtered(scope, filter), TaskList, q)
+	filter.Limit = q.Limit
+	return page, err
+}
@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/recurrence"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SeriesFields are the columns that "this and following" edits copy from
+// the edited task to the later occurrences of its series.
+var SeriesFields = []string{"title", "description", "status", "started_at", "completed_at", "estimate_minutes", "subject_id"}
+
+// CreateSeries creates the first task of a recurring series together with
+// its deadline row.
+func (r *TaskRepository) CreateSeries(task *models.Task) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Subject", "Tags").Create(task).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.Deadline{
+			TaskID:  task.ID,
+			UserID:  task.UserID,
+			DueDate: task.Deadline,
+		}).Error
+	})
+}
+
+// GetSeriesMasters returns every task that defines a recurring series,
+// with its subject.
+func (r *TaskRepository) GetSeriesMasters() ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.db.Preload("Subject").Where("recurrence_rule <> ''").Find(&tasks).Error
+	return tasks, err
+}
+
+// CreateOccurrence materialises the occurrence of master at the given
+// time, with its deadline row. The occurrence starts in the first status
+// of the workflow of master's subject. Occurrences that already exist are
+// left untouched.
+func (r *TaskRepository) CreateOccurrence(master models.Task, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		seriesID := master.ID
+		task := models.Task{
+			Title:           master.Title,
+			Description:     master.Description,
+			Status:          master.Subject.TaskWorkflow().Initial(),
+			EstimateMinutes: master.EstimateMinutes,
+			Deadline:        at,
+			SubjectID:       master.SubjectID,
+			UserID:          master.UserID,
+			SeriesID:        &seriesID,
+			OccurrenceAt:    &at,
+		}
+
+		res := tx.Omit("Subject", "Tags").Clauses(clause.OnConflict{DoNothing: true}).Create(&task)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		return tx.Create(&models.Deadline{
+			TaskID:  task.ID,
+			UserID:  task.UserID,
+			DueDate: at,
+		}).Error
+	})
+}
+
+// SetGeneratedUntil records how far ahead a series has been materialised.
+func (r *TaskRepository) SetGeneratedUntil(id uint, until time.Time) error {
+	return r.db.Model(&models.Task{}).Where("id = ?", id).
+		Update("recurrence_generated_until", until).Error
+}
+
+// UpdateOccurrence applies data to a single occurrence. When the task is
+// the first one of a series, the rest of the series is handed over to the
+// next occurrence first, so the edit does not leak into future ones.
+func (r *TaskRepository) UpdateOccurrence(scope Scope, task models.Task, data map[string]interface{}) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		txr := &TaskRepository{tx}
+		if err := claim(tx, &models.Task{}, scope, task.ID, task.Version); err != nil {
+			return err
+		}
+		series := task.IsSeriesMaster() || task.SeriesID != nil
+		if task.IsSeriesMaster() {
+			if err := txr.detachMaster(task); err != nil {
+				return err
+			}
+		}
+		if err := txr.Update(scope, task.ID, data); err != nil {
+			return err
+		}
+		if _, ok := data["deadline"]; ok && series {
+			return txr.moveDeadline(task.ID)
+		}
+		return nil
+	})
+}
+
+// UpdateFollowing applies data to the task and to every later occurrence
+// of its series. Editing a generated occurrence splits the series there:
+// the original series ends before it and the occurrence becomes the first
+// task of a new series.
+func (r *TaskRepository) UpdateFollowing(scope Scope, task models.Task, data map[string]interface{}) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		txr := &TaskRepository{tx}
+		if err := claim(tx, &models.Task{}, scope, task.ID, task.Version); err != nil {
+			return err
+		}
+
+		if task.SeriesID != nil {
+			var err error
+			if task, err = txr.splitAt(task); err != nil {
+				return err
+			}
+		}
+		series := task.IsSeriesMaster()
+		if err := txr.Update(scope, task.ID, data); err != nil {
+			return err
+		}
+		_, startChanged := data["deadline"]
+		if startChanged && series {
+			if err := txr.moveDeadline(task.ID); err != nil {
+				return err
+			}
+		}
+		if !series {
+			return nil
+		}
+
+		_, ruleChanged := data["recurrence_rule"]
+		if ruleChanged || startChanged {
+			// future occurrences no longer line up with the series; drop the
+			// ones nobody started for good and let the generator rebuild
+			// them. Trashed ones stay in the trash for the user to restore.
+			if err := tx.Unscoped().Where("series_id = ? AND status = ? AND occurrence_at > ? AND deleted_at IS NULL", task.ID, task.Subject.TaskWorkflow().Initial(), time.Now()).
+				Delete(&models.Task{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Task{}).Where("id = ?", task.ID).
+				Update("recurrence_generated_until", nil).Error; err != nil {
+				return err
+			}
+		}
+
+		shared := map[string]interface{}{}
+		for _, f := range SeriesFields {
+			if v, ok := data[f]; ok {
+				shared[f] = v
+			}
+		}
+		if len(shared) == 0 {
+			return nil
+		}
+		return tx.Model(&models.Task{}).Where("series_id = ?", task.ID).Updates(bumped(shared)).Error
+	})
+}
+
+// moveDeadline moves the deadline row of a series task, which reminders
+// and the calendar feed read, to the task's deadline.
+func (r *TaskRepository) moveDeadline(id uint) error {
+	var task models.Task
+	if err := r.db.Select("id", "user_id", "deadline").First(&task, id).Error; err != nil {
+		return err
+	}
+	return (&DeadlineRepository{r.db}).SetForTask(task, task.Deadline)
+}
+
+// DeleteFollowing moves the task and every later occurrence of its series
+// to the trash, ending the series before it. Restoring the task restores
+// them too.
+func (r *TaskRepository) DeleteFollowing(scope Scope, task models.Task) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		txr := &TaskRepository{tx}
+		if err := claim(tx, &models.Task{}, scope, task.ID, task.Version); err != nil {
+			return err
+		}
+
+		if task.SeriesID != nil {
+			var err error
+			if task, err = txr.splitAt(task); err != nil {
+				return err
+			}
+		}
+		at := time.Now()
+		if _, err := trashTasks(tx, at, func(db *gorm.DB) *gorm.DB {
+			return db.Where("series_id = ?", task.ID)
+		}); err != nil {
+			return err
+		}
+		n, err := trashTasks(tx, at, func(db *gorm.DB) *gorm.DB {
+			return db.Scopes(scope.owned("user_id")).Where("id = ?", task.ID)
+		})
+		if err == nil && n == 0 {
+			err = gorm.ErrRecordNotFound
+		}
+		return err
+	})
+}
+
+// DeleteOccurrence moves a single task to the trash. Deleting the first task of a
+// series hands the rest of the series over to the next occurrence.
+func (r *TaskRepository) DeleteOccurrence(scope Scope, task models.Task) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		txr := &TaskRepository{tx}
+		if err := claim(tx, &models.Task{}, scope, task.ID, task.Version); err != nil {
+			return err
+		}
+		if task.IsSeriesMaster() {
+			if err := txr.detachMaster(task); err != nil {
+				return err
+			}
+		}
+		return txr.Delete(scope, task.ID)
+	})
+}
+
+// detachMaster turns the first task of a series into a plain task and
+// makes the next occurrence the first task of the remaining series.
+func (r *TaskRepository) detachMaster(master models.Task) error {
+	rule, err := recurrence.Parse(master.RecurrenceRule)
+	if err != nil {
+		return err
+	}
+
+	var next models.Task
+	err = r.db.Where("series_id = ?", master.ID).Order("occurrence_at").First(&next).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// nothing materialised yet: create the next occurrence to take over
+		at, ok := rule.Next(master.Deadline, master.Deadline)
+		if !ok {
+			return r.setRule(master.ID, "")
+		}
+		if err := r.CreateOccurrence(master, at); err != nil {
+			return err
+		}
+		err = r.db.Where("series_id = ?", master.ID).Order("occurrence_at").First(&next).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.promote(master, rule, next); err != nil {
+		return err
+	}
+	return r.setRule(master.ID, "")
+}
+
+// splitAt ends the series of occurrence right before it and starts a new
+// series at it. It returns the occurrence as the new series master.
+func (r *TaskRepository) splitAt(occurrence models.Task) (models.Task, error) {
+	var master models.Task
+	if err := r.db.First(&master, *occurrence.SeriesID).Error; err != nil {
+		return occurrence, err
+	}
+	rule, err := recurrence.Parse(master.RecurrenceRule)
+	if err != nil {
+		return occurrence, err
+	}
+
+	promoted, err := r.promote(master, rule, occurrence)
+	if err != nil {
+		return occurrence, err
+	}
+
+	truncated := rule.Truncate(master.Deadline, *occurrence.OccurrenceAt)
+	if err := r.setRule(master.ID, truncated.String()); err != nil {
+		return occurrence, err
+	}
+	return promoted, nil
+}
+
+// promote makes occurrence the master of the part of master's series that
+// starts at it, moving the later occurrences over.
+func (r *TaskRepository) promote(master models.Task, rule recurrence.Rule, occurrence models.Task) (models.Task, error) {
+	at := *occurrence.OccurrenceAt
+	remainder := rule.Remainder(master.Deadline, at)
+
+	if err := r.db.Model(&models.Task{}).Where("series_id = ? AND occurrence_at > ?", master.ID, at).
+		Updates(bumped(map[string]interface{}{"series_id": occurrence.ID})).Error; err != nil {
+		return occurrence, err
+	}
+
+	updates := map[string]interface{}{
+		"recurrence_rule":            remainder.String(),
+		"series_id":                  nil,
+		"recurrence_generated_until": master.RecurrenceGeneratedUntil,
+	}
+	if err := r.db.Model(&models.Task{}).Where("id = ?", occurrence.ID).Updates(bumped(updates)).Error; err != nil {
+		return occurrence, err
+	}
+
+	occurrence.RecurrenceRule = remainder.String()
+	occurrence.SeriesID = nil
+	occurrence.RecurrenceGeneratedUntil = master.RecurrenceGeneratedUntil
+	return occurrence, nil
+}
+
+func (r *TaskRepository) setRule(id uint, rule string) error {
+	return r.db.Model(&models.Task{}).Where("id = ?", id).
+		Updates(bumped(map[string]interface{}{"recurrence_rule": rule})).Error
+}
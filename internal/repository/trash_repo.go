@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"gorm.io/gorm"
+)
+
+// Deleting a subject, task or deadline moves it to the trash by setting
+// deleted_at, which gorm's soft delete hides from every other query. Rows
+// that go to the trash together, such as a subject and its tasks, share
+// the same deleted_at, so restoring one brings the others back.
+
+// ErrParentDeleted is returned when restoring a row whose subject, task or
+// series is still in the trash.
+var ErrParentDeleted = errors.New("repository: the row it belongs to is in the trash")
+
+// trashed returns the update that moves a row to the trash at.
+func trashed(at time.Time) map[string]interface{} {
+	return bumped(map[string]interface{}{"deleted_at": at})
+}
+
+// restored returns the update that takes a row out of the trash.
+func restored() map[string]interface{} {
+	return bumped(map[string]interface{}{"deleted_at": nil})
+}
+
+// trashTasks moves the live tasks that match, their subtasks and their
+// deadlines to the trash at. It returns how many matching tasks it moved.
+func trashTasks(tx *gorm.DB, at time.Time, match func(*gorm.DB) *gorm.DB) (int64, error) {
+	n, err := trashTaskRows(tx, at, match)
+	if err != nil {
+		return 0, err
+	}
+	// one level of subtasks at a time, until a level comes up empty
+	subtasks := func(db *gorm.DB) *gorm.DB {
+		return db.Where("parent_id IN (?)", tx.Unscoped().Model(&models.Task{}).Where("deleted_at = ?", at).Select("id"))
+	}
+	for moved := n; moved > 0; {
+		if moved, err = trashTaskRows(tx, at, subtasks); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+func trashTaskRows(tx *gorm.DB, at time.Time, match func(*gorm.DB) *gorm.DB) (int64, error) {
+	ids := tx.Model(&models.Task{}).Scopes(match).Select("id")
+	if err := tx.Model(&models.Deadline{}).Where("task_id IN (?)", ids).Updates(trashed(at)).Error; err != nil {
+		return 0, err
+	}
+	res := tx.Model(&models.Task{}).Scopes(match).Updates(trashed(at))
+	return res.RowsAffected, res.Error
+}
+
+type TrashRepository struct {
+	db *gorm.DB
+}
+
+func NewTrashRepository(db *gorm.DB) *TrashRepository {
+	return &TrashRepository{db}
+}
+
+// List returns what is in the trash, latest deletion first. Rows deleted
+// together with their subject, parent task or series are left out; they
+// come back with it.
+func (r *TrashRepository) List(scope Scope) ([]models.TrashItem, error) {
+	var subjects []models.Subject
+	var tasks []models.Task
+	var deadlines []models.Deadline
+
+	inTrash := func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Scopes(scope.owned("user_id")).Where("deleted_at IS NOT NULL")
+	}
+	if err := r.db.Scopes(inTrash).Find(&subjects).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Scopes(inTrash).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Scopes(inTrash).Preload("Task", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Find(&deadlines).Error; err != nil {
+		return nil, err
+	}
+	return TrashItems(subjects, tasks, deadlines), nil
+}
+
+// TrashItems lists the rows in the trash that were deleted on their own.
+// deadlines must have their task loaded.
+func TrashItems(subjects []models.Subject, tasks []models.Task, deadlines []models.Deadline) []models.TrashItem {
+	subjectDeleted := map[uint]time.Time{}
+	taskDeleted := map[uint]time.Time{}
+	for _, s := range subjects {
+		subjectDeleted[s.ID] = s.DeletedAt.Time
+	}
+	for _, t := range tasks {
+		taskDeleted[t.ID] = t.DeletedAt.Time
+	}
+	with := func(deleted map[uint]time.Time, id uint, at time.Time) bool {
+		parent, ok := deleted[id]
+		return ok && parent.Equal(at)
+	}
+
+	items := []models.TrashItem{}
+	for _, s := range subjects {
+		items = append(items, models.TrashItem{
+			Type: models.ResourceSubjects, ID: s.ID, Title: s.Name, DeletedAt: s.DeletedAt.Time,
+		})
+	}
+	for _, t := range tasks {
+		at := t.DeletedAt.Time
+		if with(subjectDeleted, t.SubjectID, at) || (t.SeriesID != nil && with(taskDeleted, *t.SeriesID, at)) ||
+			(t.ParentID != nil && with(taskDeleted, *t.ParentID, at)) {
+			continue
+		}
+		items = append(items, models.TrashItem{
+			Type: models.ResourceTasks, ID: t.ID, Title: t.Title, DeletedAt: at,
+		})
+	}
+	for _, d := range deadlines {
+		at := d.DeletedAt.Time
+		if with(taskDeleted, d.TaskID, at) {
+			continue
+		}
+		items = append(items, models.TrashItem{
+			Type: models.ResourceDeadlines, ID: d.ID, Title: d.Task.Title, DeletedAt: at,
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].DeletedAt.Equal(items[j].DeletedAt) {
+			return items[i].DeletedAt.After(items[j].DeletedAt)
+		}
+		return items[i].ID > items[j].ID
+	})
+	return items
+}
+
+// Restore takes a row out of the trash with everything deleted together
+// with it: a subject's tasks, a series' occurrences and tasks' subtasks
+// and deadlines. A row that is not in the trash is gorm.ErrRecordNotFound;
+// one whose subject, task or series is still there is ErrParentDeleted.
+func (r *TrashRepository) Restore(scope Scope, resource string, id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		inTrash := tx.Unscoped().Scopes(scope.owned("user_id")).Where("deleted_at IS NOT NULL")
+
+		switch resource {
+		case models.ResourceSubjects:
+			var s models.Subject
+			if err := inTrash.First(&s, id).Error; err != nil {
+				return err
+			}
+			var ids []uint
+			if err := tx.Unscoped().Model(&models.Task{}).Where("subject_id = ? AND deleted_at = ?", s.ID, s.DeletedAt.Time).
+				Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			if err := restoreTasks(tx, s.UserID, ids, s.DeletedAt.Time); err != nil {
+				return err
+			}
+			return tx.Unscoped().Model(&models.Subject{}).Where("id = ?", s.ID).Updates(restored()).Error
+
+		case models.ResourceTasks:
+			var t models.Task
+			if err := inTrash.First(&t, id).Error; err != nil {
+				return err
+			}
+			if err := live(tx, &models.Subject{}, t.SubjectID); err != nil {
+				return err
+			}
+			for _, parent := range []*uint{t.SeriesID, t.ParentID} {
+				if parent == nil {
+					continue
+				}
+				if err := live(tx, &models.Task{}, *parent); err != nil {
+					return err
+				}
+			}
+			var ids []uint
+			if err := tx.Unscoped().Model(&models.Task{}).Where("series_id = ? AND deleted_at = ?", t.ID, t.DeletedAt.Time).
+				Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			return restoreTasks(tx, t.UserID, append(ids, t.ID), t.DeletedAt.Time)
+
+		case models.ResourceDeadlines:
+			var d models.Deadline
+			if err := inTrash.First(&d, id).Error; err != nil {
+				return err
+			}
+			if err := live(tx, &models.Task{}, d.TaskID); err != nil {
+				return err
+			}
+			return tx.Unscoped().Model(&models.Deadline{}).Where("id = ?", d.ID).Updates(restored()).Error
+		}
+		return gorm.ErrRecordNotFound
+	})
+}
+
+// live checks that the row a restored row belongs to is not in the trash.
+func live(tx *gorm.DB, model interface{}, id uint) error {
+	var n int64
+	if err := tx.Model(model).Where("id = ?", id).Count(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrParentDeleted
+	}
+	return nil
+}
+
+// restoreTasks takes the tasks of one user and their subtasks and
+// deadlines deleted at out of the trash. A task whose calendar entry was
+// imported again in the meantime loses its link to the entry, which now
+// belongs to the new task.
+func restoreTasks(tx *gorm.DB, userID uint, ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	for level := ids; len(level) > 0; {
+		var subtasks []uint
+		if err := tx.Unscoped().Model(&models.Task{}).Where("parent_id IN ? AND deleted_at = ?", level, at).
+			Pluck("id", &subtasks).Error; err != nil {
+			return err
+		}
+		ids, level = append(ids, subtasks...), subtasks
+	}
+	imported := tx.Model(&models.Task{}).Where("user_id = ? AND external_uid IS NOT NULL", userID).Select("external_uid")
+	if err := tx.Unscoped().Model(&models.Task{}).Where("id IN ? AND external_uid IN (?)", ids, imported).
+		Update("external_uid", nil).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Model(&models.Deadline{}).Where("task_id IN ? AND deleted_at = ?", ids, at).
+		Updates(restored()).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(&models.Task{}).Where("id IN ?", ids).Updates(restored()).Error
+}
+
+// Purge deletes for good what went to the trash before the given time and
+// returns how many subjects, tasks and deadlines it removed.
+func (r *TrashRepository) Purge(before time.Time) (int64, error) {
+	var n int64
+	steps := []struct {
+		model interface{}
+		where string
+	}{
+		{&models.Deadline{}, "deleted_at < ?"},
+		{&models.Task{}, "deleted_at < ?"},
+		// a task created while its subject was being deleted still
+		// points to it
+		{&models.Subject{}, "deleted_at < ? AND NOT EXISTS (SELECT 1 FROM tasks WHERE tasks.subject_id = subjects.id)"},
+	}
+	for _, s := range steps {
+		res := r.db.Unscoped().Where(s.where, before).Delete(s.model)
+		if res.Error != nil {
+			return n, res.Error
+		}
+		n += res.RowsAffected
+	}
+	return n, nil
+}
@@ -23,6 +23,11 @@ func (r *UserRepository) GetAll() ([]models.User, error) {
 	return users, err
 }
 
+// List reads a page of the users (see UserList).
+func (r *UserRepository) List(q *ListQuery) (Page[models.User], error) {
+	return list[models.User](r.db.Model(&models.User{}), UserList, q)
+}
+
 func (r *UserRepository) GetByID(id uint) (models.User, error) {
 	var user models.User
 	err := r.db.First(&user, id).Error
@@ -38,3 +43,7 @@ func (r *UserRepository) GetByEmail(email string) (models.User, error) {
 func (r *UserRepository) Delete(id uint) error {
 	return r.db.Delete(&models.User{}, id).Error
 }
+
+func (r *UserRepository) Update(id uint, data map[string]interface{}) error {
+	return affected(r.db.Model(&models.User{}).Where("id = ?", id).Updates(data))
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"errors"
+	"maps"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tasks, subjects and deadlines carry a version that goes up on every
+// change. Writes that are given a row as it was read only apply while the
+// row is still at that version; a zero version makes them unconditional.
+
+// ErrConflict is returned by a version-guarded write when the row has
+// changed since it was read.
+var ErrConflict = errors.New("repository: row was modified since it was read")
+
+// bumped returns a copy of data that also increments the version column.
+func bumped(data map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(data)+1)
+	maps.Copy(out, data)
+	out["version"] = gorm.Expr("version + 1")
+	return out
+}
+
+// atVersion restricts a write to rows still at version.
+func atVersion(version uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if version == 0 {
+			return db
+		}
+		return db.Where("version = ?", version)
+	}
+}
+
+// guarded is affected for version-guarded writes: when nothing matched it
+// tells a row that has changed from one that is missing or out of scope.
+func guarded(tx *gorm.DB, model interface{}, scope Scope, id uint) error {
+	err := affected(tx)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return stale(tx.Session(&gorm.Session{NewDB: true}), model, scope, id)
+}
+
+// stale reports why a row could not be matched at the expected version.
+func stale(db *gorm.DB, model interface{}, scope Scope, id uint) error {
+	var n int64
+	if err := db.Model(model).Scopes(scope.owned("user_id")).Where("id = ?", id).Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrConflict
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// claim locks a row for the rest of the transaction after checking that it
+// is still at version.
+func claim(tx *gorm.DB, model interface{}, scope Scope, id, version uint) error {
+	if version == 0 {
+		return nil
+	}
+	var ids []uint
+	err := tx.Model(model).Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(scope.owned("user_id")).
+		Where("id = ? AND version = ?", id, version).Pluck("id", &ids).Error
+	if err != nil || len(ids) > 0 {
+		return err
+	}
+	return stale(tx, model, scope, id)
+}
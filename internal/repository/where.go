@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/rsql"
+	"gorm.io/gorm/clause"
+)
+
+// Filter expressions narrow task lists further than TaskFilter's fields
+// can: comparisons of the fields in TaskFields, in RSQL (see package rsql),
+// combined with and and or. They are checked against TaskFields once, into
+// a Where, and then compiled into parameterised SQL; no part of the
+// expression other than its arguments reaches the database.
+//
+// Text compares ignoring case, and * in an argument to == or != matches
+// any run of characters. Times are RFC3339, a date (midnight UTC), or now
+// or today (midnight in now's zone) with an optional offset such as +7d or
+// -12h in m, h, d or w. null matches a missing parent or time with == and
+// != only. Comparisons with a missing value do not hold, except != and
+// =out=, which do.
+
+// maxWhereTerms is how many comparisons a filter expression may have.
+const maxWhereTerms = 32
+
+// WhereField is a field filter expressions can compare.
+type WhereField struct {
+	Column   string
+	Kind     KeyKind
+	Nullable bool
+}
+
+// TaskFields are the fields of a task filter expression. subject is the
+// name of the task's subject and tag the name of any of its tags.
+var TaskFields = map[string]WhereField{
+	"title":            {"title", KeyText, false},
+	"description":      {"description", KeyText, false},
+	"status":           {"status", KeyText, false},
+	"subject":          {"name", KeyText, false},
+	"subject_id":       {"subject_id", KeyNumber, false},
+	"tag":              {"name", KeyText, false},
+	"parent_id":        {"parent_id", KeyNumber, true},
+	"estimate_minutes": {"estimate_minutes", KeyNumber, false},
+	"deadline":         {"deadline", KeyTime, false},
+	"created_at":       {"created_at", KeyTime, false},
+	"started_at":       {"started_at", KeyTime, true},
+	"completed_at":     {"completed_at", KeyTime, true},
+}
+
+// Where is a checked filter expression: the And or Or of other Wheres, or
+// a comparison of Field with Values. Values are of the field's kind, text
+// in lower case, or nil for null.
+type Where struct {
+	And    []Where
+	Or     []Where
+	Field  string
+	Op     string // as in package rsql
+	Values []interface{}
+}
+
+// Negated reports whether the comparison is != or =out=.
+func (w Where) Negated() bool {
+	return w.Op == rsql.NotEqual || w.Op == rsql.NotIn
+}
+
+// Pattern returns the pattern of a comparison of text with a wildcard
+// argument, or "" for other comparisons.
+func (w Where) Pattern() string {
+	if len(w.Values) != 1 || w.Op == rsql.In || w.Op == rsql.NotIn {
+		return ""
+	}
+	if s, ok := w.Values[0].(string); ok && strings.Contains(s, "*") {
+		return s
+	}
+	return ""
+}
+
+// ParseTaskWhere checks a filter expression on tasks, reading relative
+// times from now. Expressions that are not RSQL, or compare what
+// TaskFields does not have, fail with ErrInvalidQuery.
+func ParseTaskWhere(query string, now time.Time) (*Where, error) {
+	n, err := rsql.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	terms := 0
+	w, err := checkWhere(n, TaskFields, now, &terms)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func checkWhere(n rsql.Node, fields map[string]WhereField, now time.Time, terms *int) (Where, error) {
+	var w Where
+	var err error
+	switch n := n.(type) {
+	case rsql.And:
+		w.And, err = checkWheres(n, fields, now, terms)
+		return w, err
+	case rsql.Or:
+		w.Or, err = checkWheres(n, fields, now, terms)
+		return w, err
+	}
+
+	c := n.(rsql.Comparison)
+	if *terms++; *terms > maxWhereTerms {
+		return Where{}, fmt.Errorf("%w: more than %d comparisons", ErrInvalidQuery, maxWhereTerms)
+	}
+	field, ok := fields[c.Selector]
+	if !ok {
+		return Where{}, fmt.Errorf("%w: cannot filter by %q", ErrInvalidQuery, c.Selector)
+	}
+	w.Field, w.Op = c.Selector, c.Op
+	ordered := c.Op != rsql.Equal && c.Op != rsql.NotEqual && c.Op != rsql.In && c.Op != rsql.NotIn
+	if ordered && field.Kind == KeyText {
+		return Where{}, fmt.Errorf("%w: %s cannot be compared with %s", ErrInvalidQuery, c.Selector, c.Op)
+	}
+	for _, arg := range c.Args {
+		if arg == "null" && field.Nullable {
+			if len(c.Args) > 1 || (c.Op != rsql.Equal && c.Op != rsql.NotEqual) {
+				return Where{}, fmt.Errorf("%w: null takes == or != alone", ErrInvalidQuery)
+			}
+			w.Values = append(w.Values, nil)
+			continue
+		}
+		v, err := whereValue(field.Kind, arg, now)
+		if err != nil {
+			return Where{}, fmt.Errorf("%w: invalid value %q for %s", ErrInvalidQuery, arg, c.Selector)
+		}
+		w.Values = append(w.Values, v)
+	}
+	return w, nil
+}
+
+func checkWheres(nodes []rsql.Node, fields map[string]WhereField, now time.Time, terms *int) ([]Where, error) {
+	ws := make([]Where, 0, len(nodes))
+	for _, n := range nodes {
+		w, err := checkWhere(n, fields, now, terms)
+		if err != nil {
+			return nil, err
+		}
+		ws = append(ws, w)
+	}
+	return ws, nil
+}
+
+var relativeTime = regexp.MustCompile(`^(now|today)(?:([+-])(\d+)([mhdw]))?$`)
+
+func whereValue(kind KeyKind, arg string, now time.Time) (interface{}, error) {
+	switch kind {
+	case KeyText:
+		return strings.ToLower(arg), nil
+	case KeyNumber:
+		return parseKey(kind, arg)
+	}
+
+	if m := relativeTime.FindStringSubmatch(arg); m != nil {
+		t := now
+		if m[1] == "today" {
+			y, mo, d := now.Date()
+			t = time.Date(y, mo, d, 0, 0, 0, 0, now.Location())
+		}
+		if m[2] == "" {
+			return t, nil
+		}
+		n, err := strconv.Atoi(m[3])
+		if err != nil {
+			return nil, err
+		}
+		if m[2] == "-" {
+			n = -n
+		}
+		switch m[4] {
+		case "m":
+			return t.Add(time.Duration(n) * time.Minute), nil
+		case "h":
+			return t.Add(time.Duration(n) * time.Hour), nil
+		case "d":
+			return t.AddDate(0, 0, n), nil
+		}
+		return t.AddDate(0, 0, 7*n), nil
+	}
+	if t, err := time.Parse("2006-01-02", arg); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, arg); err == nil {
+		return t, nil
+	}
+	return nil, errors.New("not a time")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `*`, `%`)
+
+// taskExpr compiles a filter expression on tasks.
+func (w Where) taskExpr() clause.Expr {
+	switch {
+	case w.And != nil:
+		return joinExprs(w.And, " AND ")
+	case w.Or != nil:
+		return joinExprs(w.Or, " OR ")
+	}
+
+	field := TaskFields[w.Field]
+	switch w.Field {
+	case "subject":
+		names := w.Positive().compare("LOWER(name)", field)
+		return clause.Expr{
+			SQL:  "subject_id " + notIf(w.Negated()) + "IN (SELECT id FROM subjects WHERE " + names.SQL + " AND deleted_at IS NULL)",
+			Vars: names.Vars,
+		}
+	case "tag":
+		names := w.Positive().compare("LOWER(tags.name)", field)
+		return clause.Expr{
+			SQL:  notIf(w.Negated()) + "EXISTS (SELECT 1 FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE task_tags.task_id = tasks.id AND " + names.SQL + ")",
+			Vars: names.Vars,
+		}
+	}
+	column := field.Column
+	if field.Kind == KeyText {
+		column = "LOWER(" + column + ")"
+	}
+	return w.compare(column, field)
+}
+
+// Positive turns != and =out= into == and =in=. subject and tag compare
+// names in a subquery, which the negation is put around.
+func (w Where) Positive() Where {
+	switch w.Op {
+	case rsql.NotEqual:
+		w.Op = rsql.Equal
+	case rsql.NotIn:
+		w.Op = rsql.In
+	}
+	return w
+}
+
+var sqlOps = map[string]string{
+	rsql.Equal:          "=",
+	rsql.NotEqual:       "<>",
+	rsql.Less:           "<",
+	rsql.LessOrEqual:    "<=",
+	rsql.Greater:        ">",
+	rsql.GreaterOrEqual: ">=",
+}
+
+func (w Where) compare(column string, field WhereField) clause.Expr {
+	switch {
+	case len(w.Values) == 1 && w.Values[0] == nil:
+		return clause.Expr{SQL: column + " IS " + notIf(w.Negated()) + "NULL"}
+	case w.Pattern() != "":
+		return clause.Expr{SQL: column + " " + notIf(w.Negated()) + "LIKE ?", Vars: []interface{}{likeEscaper.Replace(w.Pattern())}}
+	}
+
+	var e clause.Expr
+	if w.Op == rsql.In || w.Op == rsql.NotIn {
+		e = clause.Expr{SQL: column + " " + notIf(w.Negated()) + "IN ?", Vars: []interface{}{w.Values}}
+	} else {
+		e = clause.Expr{SQL: column + " " + sqlOps[w.Op] + " ?", Vars: w.Values}
+	}
+	if field.Nullable && w.Negated() {
+		e.SQL = "(" + column + " IS NULL OR " + e.SQL + ")"
+	}
+	return e
+}
+
+func joinExprs(ws []Where, sep string) clause.Expr {
+	var e clause.Expr
+	for i, w := range ws {
+		c := w.taskExpr()
+		if i > 0 {
+			e.SQL += sep
+		}
+		e.SQL += "(" + c.SQL + ")"
+		e.Vars = append(e.Vars, c.Vars...)
+	}
+	return e
+}
+
+func notIf(negated bool) string {
+	if negated {
+		return "NOT "
+	}
+	return ""
+}
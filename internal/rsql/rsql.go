@@ -0,0 +1,291 @@
+// Package rsql parses RSQL, the URI-friendly query language based on FIQL,
+// into a tree of comparisons:
+//
+//	deadline=le=now+7d;status!=done;(subject==Math,tag==exam)
+//
+// ";" is and, "," is or, and binds tighter than or, and parentheses group.
+// A comparison is a selector, an operator and an argument, or a
+// parenthesised list of them for =in= and =out=. Arguments with spaces or
+// reserved characters are quoted with ' or ", with \ escaping the next
+// character. What selectors exist and what their arguments mean is up to
+// the caller.
+package rsql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// The operators of a Comparison. <, <=, > and >= are read as =lt=, =le=,
+// =gt= and =ge=.
+const (
+	Equal          = "=="
+	NotEqual       = "!="
+	Less           = "=lt="
+	LessOrEqual    = "=le="
+	Greater        = "=gt="
+	GreaterOrEqual = "=ge="
+	In             = "=in="
+	NotIn          = "=out="
+)
+
+// MaxDepth is how deep parentheses may nest.
+const MaxDepth = 16
+
+var aliases = map[string]string{
+	"<": Less, "<=": LessOrEqual, ">": Greater, ">=": GreaterOrEqual,
+}
+
+var operators = map[string]bool{
+	Equal: true, NotEqual: true, Less: true, LessOrEqual: true,
+	Greater: true, GreaterOrEqual: true, In: true, NotIn: true,
+}
+
+// Node is an And, an Or or a Comparison.
+type Node interface {
+	node()
+}
+
+// And holds when all of its nodes do; Or when any of them does. Both have
+// at least two nodes.
+type (
+	And []Node
+	Or  []Node
+)
+
+// Comparison compares what Selector names with Args. Only =in= and =out=
+// take more than one argument.
+type Comparison struct {
+	Selector string
+	Op       string
+	Args     []string
+}
+
+func (And) node()        {}
+func (Or) node()         {}
+func (Comparison) node() {}
+
+// SyntaxError is a query that is not RSQL.
+type SyntaxError struct {
+	Offset int // in bytes
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("rsql: %s at offset %d", e.Msg, e.Offset)
+}
+
+// Parse reads a query.
+func Parse(query string) (Node, error) {
+	p := &parser{s: query}
+	n, err := p.or(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.s) {
+		return nil, p.errorf("unexpected %q", p.s[p.pos])
+	}
+	return n, nil
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+// next returns the next byte after spaces, or 0 at the end.
+func (p *parser) next() byte {
+	if p.skipSpace(); p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) or(depth int) (Node, error) {
+	var nodes Or
+	for {
+		n, err := p.and(depth)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		if p.next() != ',' {
+			break
+		}
+		p.pos++
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *parser) and(depth int) (Node, error) {
+	var nodes And
+	for {
+		n, err := p.constraint(depth)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		if p.next() != ';' {
+			break
+		}
+		p.pos++
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *parser) constraint(depth int) (Node, error) {
+	if p.next() != '(' {
+		return p.comparison()
+	}
+	if depth == MaxDepth {
+		return nil, p.errorf("parentheses nested too deep")
+	}
+	p.pos++
+	n, err := p.or(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+	if p.next() != ')' {
+		return nil, p.errorf("missing )")
+	}
+	p.pos++
+	return n, nil
+}
+
+func (p *parser) comparison() (Node, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && isSelectorByte(p.s[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, p.errorf("missing selector")
+	}
+	c := Comparison{Selector: p.s[start:p.pos]}
+
+	op, err := p.operator()
+	if err != nil {
+		return nil, err
+	}
+	c.Op = op
+
+	if p.next() == '(' {
+		p.pos++
+		for {
+			arg, err := p.argument()
+			if err != nil {
+				return nil, err
+			}
+			c.Args = append(c.Args, arg)
+			if p.next() != ',' {
+				break
+			}
+			p.pos++
+		}
+		if p.next() != ')' {
+			return nil, p.errorf("missing )")
+		}
+		p.pos++
+	} else {
+		arg, err := p.argument()
+		if err != nil {
+			return nil, err
+		}
+		c.Args = []string{arg}
+		end := p.pos
+		if next := p.next(); next != 0 && p.pos > end && !strings.ContainsRune(";,)", rune(next)) {
+			// most likely a + that a query string turned into a space
+			p.pos = end
+			return nil, p.errorf("space in unquoted argument")
+		}
+	}
+	if len(c.Args) > 1 && c.Op != In && c.Op != NotIn {
+		return nil, p.errorf("%s takes a single argument", c.Op)
+	}
+	return c, nil
+}
+
+func (p *parser) operator() (string, error) {
+	p.skipSpace()
+	rest := p.s[p.pos:]
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if strings.HasPrefix(rest, op) {
+			p.pos += len(op)
+			if alias, ok := aliases[op]; ok {
+				return alias, nil
+			}
+			return op, nil
+		}
+	}
+	if strings.HasPrefix(rest, "=") {
+		if end := strings.IndexByte(rest[1:], '='); end >= 0 {
+			if op := rest[:end+2]; operators[op] {
+				p.pos += len(op)
+				return op, nil
+			}
+		}
+	}
+	return "", p.errorf("unknown operator")
+}
+
+func (p *parser) argument() (string, error) {
+	p.skipSpace()
+	if p.pos < len(p.s) && (p.s[p.pos] == '\'' || p.s[p.pos] == '"') {
+		return p.quoted()
+	}
+	start := p.pos
+	for p.pos < len(p.s) && !isReserved(p.s[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("missing argument")
+	}
+	return p.s[start:p.pos], nil
+}
+
+func (p *parser) quoted() (string, error) {
+	quote := p.s[p.pos]
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && p.pos < len(p.s):
+			b.WriteByte(p.s[p.pos])
+			p.pos++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated string")
+}
+
+func isSelectorByte(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isReserved(c byte) bool {
+	return strings.IndexByte("\"'();,=!<> \t\r\n", c) >= 0
+}
@@ -0,0 +1,119 @@
+package rsql
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func cmp(selector, op string, args ...string) Comparison {
+	return Comparison{Selector: selector, Op: op, Args: args}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Node
+	}{
+		{"status==done", cmp("status", Equal, "done")},
+		{" status != done ", cmp("status", NotEqual, "done")},
+		{"a==1;b==2", And{cmp("a", Equal, "1"), cmp("b", Equal, "2")}},
+		{"a==1,b==2", Or{cmp("a", Equal, "1"), cmp("b", Equal, "2")}},
+		// and binds tighter than or
+		{"a==1;b==2,c==3", Or{And{cmp("a", Equal, "1"), cmp("b", Equal, "2")}, cmp("c", Equal, "3")}},
+		{"a==1;(b==2,c==3)", And{cmp("a", Equal, "1"), Or{cmp("b", Equal, "2"), cmp("c", Equal, "3")}}},
+		{"((a==1))", cmp("a", Equal, "1")},
+		{"a=lt=1", cmp("a", Less, "1")},
+		{"a<1", cmp("a", Less, "1")},
+		{"a<=1", cmp("a", LessOrEqual, "1")},
+		{"a>1", cmp("a", Greater, "1")},
+		{"a>=1", cmp("a", GreaterOrEqual, "1")},
+		{"a=ge=now-7d", cmp("a", GreaterOrEqual, "now-7d")},
+		{"deadline=le=now+7d", cmp("deadline", LessOrEqual, "now+7d")},
+		{"a=in=(x, 'y z' ,\"q\\\"r\")", cmp("a", In, "x", "y z", `q"r`)},
+		{"a=out=(x)", cmp("a", NotIn, "x")},
+		{"a=in=x", cmp("a", In, "x")},
+		{`title=='it\'s'`, cmp("title", Equal, "it's")},
+		{`title=="a;b,(c)"`, cmp("title", Equal, "a;b,(c)")},
+		{`title==''`, cmp("title", Equal, "")},
+		{"title==*draft*", cmp("title", Equal, "*draft*")},
+		{"created_at>2030-01-07T09:00:00Z", cmp("created_at", Greater, "2030-01-07T09:00:00Z")},
+		{"sub.field-x==1", cmp("sub.field-x", Equal, "1")},
+		{strings.Repeat("(", MaxDepth) + "a==1" + strings.Repeat(")", MaxDepth), cmp("a", Equal, "1")},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %#v, want %#v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query  string
+		offset int
+		msg    string
+	}{
+		{"", 0, "missing selector"},
+		{"   ", 3, "missing selector"},
+		{"status", 6, "unknown operator"},
+		{"status=like=done", 6, "unknown operator"},
+		{"status~done", 6, "unknown operator"},
+		{"status==", 8, "missing argument"},
+		{"status==done;", 13, "missing selector"},
+		{"status==done,", 13, "missing selector"},
+		{"(status==done", 13, "missing )"},
+		{"status==done)", 12, `unexpected ')'`},
+		{"a=in=(x,y", 9, "missing )"},
+		{"a=in=(x,)", 8, "missing argument"},
+		{"a==(x,y)", 8, "== takes a single argument"},
+		{"title=='draft", 7, "unterminated string"},
+		{`title=='draft\'`, 7, "unterminated string"},
+		{"title=='a'b", 10, `unexpected 'b'`},
+		{"==done", 0, "missing selector"},
+		{strings.Repeat("(", MaxDepth+1) + "a==1" + strings.Repeat(")", MaxDepth+1), MaxDepth, "parentheses nested too deep"},
+		{"title==essay draft", 12, "space in unquoted argument"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("Parse(%q): got error %v, want a SyntaxError", tt.query, err)
+			continue
+		}
+		if se.Offset != tt.offset || se.Msg != tt.msg {
+			t.Errorf("Parse(%q): got %q at %d, want %q at %d", tt.query, se.Msg, se.Offset, tt.msg, tt.offset)
+		}
+	}
+}
+
+// A + left unescaped in a query string arrives as a space. It is
+// reported where it was rather than read as two arguments.
+func TestParsePlusFromQueryString(t *testing.T) {
+	query, err := url.QueryUnescape("deadline=le=now+7d;status!=done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "deadline=le=now 7d;status!=done" {
+		t.Fatalf("query string decoded to %q", query)
+	}
+	_, err = Parse(query)
+	var se *SyntaxError
+	if !errors.As(err, &se) || se.Offset != len("deadline=le=now") || se.Msg != "space in unquoted argument" {
+		t.Errorf("Parse(%q): got error %v, want a space in unquoted argument at %d", query, err, len("deadline=le=now"))
+	}
+
+	// escaped, or quoted, it reads
+	query, _ = url.QueryUnescape("deadline=le=now%2B7d;title=='essay+draft'")
+	want := And{cmp("deadline", LessOrEqual, "now+7d"), cmp("title", Equal, "essay draft")}
+	if got, err := Parse(query); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(%q) = %#v, %v, want %#v", query, got, err, want)
+	}
+}
@@ -7,9 +7,36 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/kadyrbayev2005/studysync/internal/config"
 )
 
-var jwtKey = []byte("replace-with-secure-secret") // change in production
+var (
+	jwtKeys     = newHMACKeySet([]byte(config.InsecureJWTSecret))
+	jwtIssuer   = "studysync"
+	jwtAudience = "studysync-api"
+	accessTTL   = 15 * time.Minute
+	refreshTTL  = 30 * 24 * time.Hour
+)
+
+// InitAuth loads the signing keys and token settings from the config.
+func InitAuth(cfg config.JWTConfig) error {
+	keys, err := LoadKeySet(cfg)
+	if err != nil {
+		return err
+	}
+
+	jwtKeys = keys
+	jwtIssuer = cfg.Issuer
+	jwtAudience = cfg.Audience
+	accessTTL = cfg.AccessTTL.Duration
+	refreshTTL = cfg.RefreshTTL.Duration
+	return nil
+}
+
+// PublicJWKS returns the public keys other services use to verify tokens.
+func PublicJWKS() JWKS {
+	return jwtKeys.JWKS()
+}
 
 const (
 	RoleAdmin = "admin"
@@ -22,25 +49,41 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// GenerateJWT issues a short-lived access token. Every token carries a
+// unique jti so it can be revoked individually.
 func GenerateJWT(userID uint, role string) (string, error) {
-	expiration := time.Now().Add(24 * time.Hour)
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	expiration := time.Now().Add(accessTTL)
 	claims := &Claims{
 		UserID: userID,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    jwtIssuer,
+			Audience:  jwt.ClaimStrings{jwtAudience},
 			ExpiresAt: jwt.NewNumericDate(expiration),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	token := jwt.NewWithClaims(jwtKeys.signingMethod, claims)
+	token.Header["kid"] = jwtKeys.signingKID
+	return token.SignedString(jwtKeys.signingKey)
 }
 
+// ParseJWT verifies a token against the key named by its kid header and
+// checks expiry, issuer and audience.
 func ParseJWT(tokenStr string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, jwtKeys.keyFunc,
+		jwt.WithValidMethods(jwtKeys.validMethods()),
+		jwt.WithIssuer(jwtIssuer),
+		jwt.WithAudience(jwtAudience),
+		jwt.WithExpirationRequired(),
+	)
 	if err != nil {
 		return nil, err
 	}
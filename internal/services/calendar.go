@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/ical"
+	"github.com/kadyrbayev2005/studysync/internal/models"
+)
+
+// calendarHistory is how far back the feed still lists past deadlines.
+const calendarHistory = 30 * 24 * time.Hour
+
+// todoStatus maps kinds of workflow state to VTODO STATUS values. Calendars
+// have no blocked status; a blocked task still needs action.
+var todoStatus = map[string]string{
+	models.KindTodo:      "NEEDS-ACTION",
+	models.KindActive:    "IN-PROCESS",
+	models.KindBlocked:   "NEEDS-ACTION",
+	models.KindDone:      "COMPLETED",
+	models.KindCancelled: "CANCELLED",
+}
+
+// GenerateFeedToken returns a new calendar feed token and the hash to store.
+func GenerateFeedToken() (string, string, error) {
+	raw, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	return raw, hashToken(raw), nil
+}
+
+func FeedTokenHash(raw string) string {
+	return hashToken(raw)
+}
+
+// CalendarSince is the earliest due date included in a calendar feed.
+func CalendarSince(now time.Time) time.Time {
+	return now.Add(-calendarHistory)
+}
+
+// BuildCalendar renders deadlines as VEVENTs, each with a VALARM per
+// reminder offset, and tasks as VTODOs.
+func BuildCalendar(deadlines []models.Deadline, tasks []models.Task, offsets func(models.Deadline) []int, now time.Time) *ical.Component {
+	cal := ical.NewComponent("VCALENDAR").
+		Add("VERSION", "2.0").
+		Add("PRODID", "-//StudySync//Calendar Feed//EN").
+		Add("CALSCALE", "GREGORIAN").
+		Add("METHOD", "PUBLISH").
+		AddText("X-WR-CALNAME", "StudySync").
+		Add("X-PUBLISHED-TTL", "PT1H")
+	cal.Props = append(cal.Props, ical.Property{
+		Name:   "REFRESH-INTERVAL",
+		Params: map[string]string{"VALUE": "DURATION"},
+		Value:  "PT1H",
+	})
+
+	for _, d := range deadlines {
+		ev := ical.NewComponent("VEVENT").
+			Add("UID", fmt.Sprintf("deadline-%d@studysync", d.ID)).
+			AddTime("DTSTAMP", now).
+			AddTime("DTSTART", d.DueDate).
+			AddText("SUMMARY", "Due: "+d.Task.Title)
+		if !d.CreatedAt.IsZero() {
+			ev.AddTime("CREATED", d.CreatedAt)
+		}
+		if desc := describeTask(d.Task); desc != "" {
+			ev.AddText("DESCRIPTION", desc)
+		}
+		if d.Task.Subject.Name != "" {
+			ev.AddText("CATEGORIES", d.Task.Subject.Name)
+		}
+
+		for _, o := range offsets(d) {
+			ev.AddComponent(ical.NewComponent("VALARM").
+				Add("ACTION", "DISPLAY").
+				AddText("DESCRIPTION", d.Task.Title+" is due").
+				Add("TRIGGER", fmt.Sprintf("-PT%dM", o)))
+		}
+		cal.AddComponent(ev)
+	}
+
+	for _, t := range tasks {
+		todo := ical.NewComponent("VTODO").
+			Add("UID", fmt.Sprintf("task-%d@studysync", t.ID)).
+			AddTime("DTSTAMP", now).
+			AddText("SUMMARY", t.Title)
+		if !t.CreatedAt.IsZero() {
+			todo.AddTime("CREATED", t.CreatedAt)
+		}
+		if !t.Deadline.IsZero() {
+			todo.AddTime("DUE", t.Deadline)
+		}
+		if t.Description != "" {
+			todo.AddText("DESCRIPTION", t.Description)
+		}
+		if t.Subject.Name != "" {
+			todo.AddText("CATEGORIES", t.Subject.Name)
+		}
+		if state, ok := t.Subject.TaskWorkflow().State(t.Status); ok {
+			todo.Add("STATUS", todoStatus[state.Kind])
+		}
+		if t.CompletedAt != nil {
+			todo.AddTime("COMPLETED", *t.CompletedAt)
+		}
+		cal.AddComponent(todo)
+	}
+
+	return cal
+}
+
+func describeTask(t models.Task) string {
+	var parts []string
+	if t.Subject.Name != "" {
+		parts = append(parts, "Subject: "+t.Subject.Name)
+	}
+	if t.Description != "" {
+		parts = append(parts, t.Description)
+	}
+	return strings.Join(parts, "\n\n")
+}
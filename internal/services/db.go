@@ -3,22 +3,20 @@ package services
 import (
 	"fmt"
 
-	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/config"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func ConnectDB() (*gorm.DB, error) {
-	dsn := "user=postgres password=postgres dbname=studysync port=5433 sslmode=disable"
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+func ConnectDB(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(cfg.DSN), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
 
-	// Auto migrate models
-	db.AutoMigrate(&models.User{}, &models.Subject{}, &models.Task{}, &models.Deadline{})
-	fmt.Println("Connected to database and migrated successfully")
+	// the schema is managed by internal/migrations ("migrate up")
+	fmt.Println("Connected to database")
 
 	return db, nil
 }
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+)
+
+// CheckBlockers rejects data, a task update passed through ChangeStatus,
+// when it completes the task while tasks it waits for are still open.
+func CheckBlockers(deps repository.DependencyStore, scope repository.Scope, task models.Task, data map[string]interface{}) error {
+	if _, completes := data["completed_at"].(time.Time); !completes {
+		return nil
+	}
+	blockers, err := deps.Blockers(scope, task.ID)
+	if err != nil {
+		return err
+	}
+	var open []string
+	for _, b := range blockers {
+		if b.IsOpen() {
+			open = append(open, fmt.Sprintf("#%d %q", b.ID, b.Title))
+		}
+	}
+	if len(open) == 0 {
+		return nil
+	}
+	return &StatusError{Reason: "the task is waiting for " + strings.Join(open, ", ")}
+}
+
+// DependencyGraph plans the open tasks of a dependency graph as early as
+// possible: a task can start once its open blockers are finished, and not
+// before now, and takes its estimate. The critical path leads up to the
+// task left with the least time before its deadline, or the one finishing
+// last when no task has a deadline.
+func DependencyGraph(tasks []models.Task, edges []models.TaskDependency, now time.Time) models.DependencyGraph {
+	g := models.DependencyGraph{Nodes: []models.GraphNode{}, Edges: edges, CriticalPath: []uint{}}
+	if g.Edges == nil {
+		g.Edges = []models.TaskDependency{}
+	}
+
+	byID := map[uint]models.Task{}
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+	blockers := map[uint][]uint{}
+	for _, e := range edges {
+		blockers[e.TaskID] = append(blockers[e.TaskID], e.BlockerID)
+	}
+
+	finish := map[uint]time.Time{}
+	via := map[uint]uint{}
+	visiting := map[uint]bool{}
+	var plan func(id uint) time.Time
+	plan = func(id uint) time.Time {
+		if at, ok := finish[id]; ok {
+			return at
+		}
+		// a cycle can only come from restoring tasks from the trash;
+		// it is cut where it is found
+		visiting[id] = true
+		start := now
+		for _, b := range blockers[id] {
+			if visiting[b] || !byID[b].IsOpen() {
+				continue
+			}
+			if at := plan(b); at.After(start) {
+				start, via[id] = at, b
+			}
+		}
+		visiting[id] = false
+		finish[id] = start.Add(time.Duration(byID[id].EstimateMinutes) * time.Minute)
+		return finish[id]
+	}
+
+	var end uint
+	var endSlack *int
+	for _, t := range tasks {
+		node := models.GraphNode{
+			ID:              t.ID,
+			Title:           t.Title,
+			Status:          t.Status,
+			Deadline:        t.Deadline,
+			EstimateMinutes: t.EstimateMinutes,
+			Open:            t.IsOpen(),
+		}
+		if node.Open {
+			at := plan(t.ID)
+			node.EarliestFinish = &at
+			switch {
+			case !t.Deadline.IsZero():
+				slack := int(t.Deadline.Sub(at) / time.Minute)
+				node.SlackMinutes = &slack
+				node.Late = slack < 0
+				if endSlack == nil || slack < *endSlack {
+					end, endSlack = t.ID, &slack
+				}
+			case endSlack == nil && (end == 0 || at.After(finish[end])):
+				end = t.ID
+			}
+		}
+		g.Late = g.Late || node.Late
+		g.Nodes = append(g.Nodes, node)
+	}
+
+	if end != 0 {
+		for id := end; id != 0; id = via[id] {
+			g.CriticalPath = append([]uint{id}, g.CriticalPath...)
+		}
+		onPath := map[uint]bool{}
+		for _, id := range g.CriticalPath {
+			onPath[id] = true
+		}
+		for i := range g.Nodes {
+			g.Nodes[i].Critical = onPath[g.Nodes[i].ID]
+		}
+	}
+	return g
+}
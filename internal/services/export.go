@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+// UserExport is everything StudySync stores about one user.
+type UserExport struct {
+	ExportedAt      time.Time               `json:"exported_at"`
+	User            models.User             `json:"user"`
+	Subjects        []models.Subject        `json:"subjects"`
+	Tags            []models.Tag            `json:"tags"`
+	Tasks           []models.Task           `json:"tasks"`
+	Deadlines       []models.Deadline       `json:"deadlines"`
+	Dependencies    []models.TaskDependency `json:"dependencies"`
+	ChecklistItems  []models.ChecklistItem  `json:"checklist_items"`
+	Trash           []models.TrashItem      `json:"trash"`
+	ReminderOffsets []models.ReminderOffset `json:"reminder_offsets"`
+	Notifications   []models.Notification   `json:"notifications"`
+	CalendarFeed    *models.CalendarFeed    `json:"calendar_feed,omitempty"`
+}
+
+// ExportUser collects the data of userID for a data export request.
+func ExportUser(db *gorm.DB, userID uint) (UserExport, error) {
+	out := UserExport{ExportedAt: time.Now().UTC()}
+	scope := repository.Scope{UserID: userID}
+
+	var err error
+	if out.User, err = repository.NewUserRepository(db).GetByID(userID); err != nil {
+		return out, err
+	}
+	if out.Subjects, err = repository.NewSubjectRepository(db).GetAll(scope); err != nil {
+		return out, err
+	}
+	if out.Tags, err = repository.NewTagRepository(db).GetAll(scope); err != nil {
+		return out, err
+	}
+	if out.Tasks, err = repository.NewTaskRepository(db).GetAll(scope); err != nil {
+		return out, err
+	}
+	if out.Deadlines, err = repository.NewDeadlineRepository(db).GetAll(scope); err != nil {
+		return out, err
+	}
+	if _, out.Dependencies, err = repository.NewDependencyRepository(db).Graph(scope); err != nil {
+		return out, err
+	}
+	ids := make([]uint, len(out.Tasks))
+	for i, t := range out.Tasks {
+		ids[i] = t.ID
+	}
+	if _, out.ChecklistItems, err = repository.NewSubtaskRepository(db).Descendants(scope, ids); err != nil {
+		return out, err
+	}
+	if out.Trash, err = repository.NewTrashRepository(db).List(scope); err != nil {
+		return out, err
+	}
+	if out.ReminderOffsets, err = repository.NewReminderRepository(db).GetByUser(userID); err != nil {
+		return out, err
+	}
+	if out.Notifications, err = repository.NewNotificationRepository(db).GetAllByUser(userID); err != nil {
+		return out, err
+	}
+
+	feed, err := repository.NewCalendarFeedRepository(db).GetByUser(userID)
+	if err == nil {
+		out.CalendarFeed = &feed
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return out, err
+	}
+	return out, nil
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/ical"
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/recurrence"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+// importStatus maps VTODO STATUS values to kinds of workflow state; a task
+// gets the first status of that kind in its subject's workflow.
+var importStatus = map[string]string{
+	"NEEDS-ACTION": models.KindTodo,
+	"IN-PROCESS":   models.KindActive,
+	"COMPLETED":    models.KindDone,
+}
+
+// importSubject is used for entries that name no subject, since every
+// task needs one.
+const importSubject = "Imported"
+
+// errDryRun rolls back the import transaction of a dry run.
+var errDryRun = errors.New("dry run")
+
+// Importer turns iCalendar data into subjects, tasks and deadlines.
+type Importer struct {
+	db *gorm.DB
+}
+
+func NewImporter(db *gorm.DB) *Importer {
+	return &Importer{db}
+}
+
+// importEntry is a VEVENT or VTODO read from the calendar.
+type importEntry struct {
+	uid         string
+	kind        string
+	title       string
+	description string
+	subject     string
+	status      string // kind of state
+	due         time.Time
+	rule        string
+	cancelled   bool
+}
+
+// ImportCalendar imports the VEVENTs and VTODOs of cal for userID. Entries
+// are matched to earlier imports by UID: changed ones are updated, the
+// rest are skipped. A dry run does the same work in a transaction that is
+// rolled back, so the result is an exact preview.
+func (im *Importer) ImportCalendar(userID uint, cal *ical.Component, dryRun bool) (models.ImportResult, error) {
+	result := models.ImportResult{DryRun: dryRun, SubjectsCreated: []string{}, Items: []models.ImportItem{}}
+
+	err := im.db.Transaction(func(tx *gorm.DB) error {
+		run := calendarImport{
+			scope:     repository.Scope{UserID: userID},
+			tasks:     repository.NewTaskRepository(tx),
+			subjects:  repository.NewSubjectRepository(tx),
+			deadlines: repository.NewDeadlineRepository(tx),
+			deps:      repository.NewDependencyRepository(tx),
+			calName:   cal.Text("X-WR-CALNAME"),
+			byName:    map[string]models.Subject{},
+			result:    &result,
+			now:       time.Now(),
+		}
+		for _, c := range cal.Components {
+			if c.Name != "VEVENT" && c.Name != "VTODO" {
+				continue
+			}
+			if err := run.entry(readEntry(c)); err != nil {
+				return err
+			}
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return result, err
+	}
+	return result, nil
+}
+
+type calendarImport struct {
+	scope     repository.Scope
+	tasks     repository.TaskStore
+	subjects  repository.SubjectStore
+	deadlines repository.DeadlineStore
+	deps      repository.DependencyStore
+	calName   string
+	byName    map[string]models.Subject
+	result    *models.ImportResult
+	now       time.Time
+}
+
+func (run *calendarImport) entry(e importEntry) error {
+	if e.subject == "" {
+		e.subject = run.calName
+	}
+	if e.subject == "" {
+		e.subject = importSubject
+	}
+	item := models.ImportItem{UID: e.uid, Kind: e.kind, Title: e.title, Subject: e.subject}
+
+	skip := func(reason string) error {
+		item.Action = models.ImportSkip
+		item.Reason = reason
+		run.result.Skipped++
+		run.result.Items = append(run.result.Items, item)
+		return nil
+	}
+
+	switch {
+	case strings.HasSuffix(e.uid, "@studysync"):
+		return skip("exported by StudySync")
+	case e.cancelled:
+		return skip("cancelled")
+	case e.title == "":
+		return skip("no summary")
+	}
+
+	if e.rule != "" {
+		rule, err := recurrence.Parse(e.rule)
+		if err != nil || e.due.IsZero() {
+			// unsupported RRULE parts: keep the first occurrence only
+			e.rule = ""
+		} else {
+			e.rule = rule.String()
+		}
+	}
+
+	subject, err := run.subject(e.subject)
+	if err != nil {
+		return err
+	}
+
+	existing, err := run.tasks.GetByExternalUID(run.scope, e.uid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		task, err := run.create(e, subject)
+		if err != nil {
+			return err
+		}
+		item.Action = models.ImportCreate
+		item.TaskID = task.ID
+		run.result.Created++
+		run.result.Items = append(run.result.Items, item)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	item.TaskID = existing.ID
+	changes := map[string]interface{}{}
+	if existing.Title != e.title {
+		changes["title"] = e.title
+	}
+	if existing.Description != e.description {
+		changes["description"] = e.description
+	}
+	if existing.SubjectID != subject.ID {
+		changes["subject_id"] = subject.ID
+	}
+	wf := subject.TaskWorkflow()
+	if e.kind == "VTODO" && e.status != "" {
+		cur, ok := wf.State(existing.Status)
+		if next, found := wf.FirstOfKind(e.status); found && (!ok || cur.Kind != e.status) {
+			changes["status"] = next.Name
+		}
+	}
+	if err := ChangeStatus(wf, existing, changes, run.now); err != nil {
+		// a move the workflow does not allow; the task keeps its status
+		delete(changes, "status")
+	} else if err := CheckBlockers(run.deps, run.scope, existing, changes); err != nil {
+		if !IsStatusError(err) {
+			return err
+		}
+		// still waiting for other tasks
+		for _, k := range []string{"status", "started_at", "completed_at"} {
+			delete(changes, k)
+		}
+	}
+	// the schedule of a series is owned by StudySync once imported
+	dueChanged := !existing.IsSeriesMaster() && !e.due.IsZero() && !existing.Deadline.Equal(e.due)
+	if dueChanged {
+		changes["deadline"] = e.due
+	}
+	if len(changes) == 0 {
+		return skip("unchanged")
+	}
+
+	if existing.IsSeriesMaster() {
+		err = run.tasks.UpdateFollowing(run.scope, existing, changes)
+	} else {
+		err = run.tasks.Update(run.scope, existing.ID, changes)
+	}
+	if err != nil {
+		return err
+	}
+	if dueChanged {
+		if err := run.deadlines.SetForTask(existing, e.due); err != nil {
+			return err
+		}
+	}
+
+	item.Action = models.ImportUpdate
+	run.result.Updated++
+	run.result.Items = append(run.result.Items, item)
+	return nil
+}
+
+func (run *calendarImport) create(e importEntry, subject models.Subject) (models.Task, error) {
+	uid := e.uid
+	task := models.Task{
+		Title:          e.title,
+		Description:    e.description,
+		Deadline:       e.due,
+		SubjectID:      subject.ID,
+		Subject:        subject,
+		UserID:         run.scope.UserID,
+		RecurrenceRule: e.rule,
+		ExternalUID:    &uid,
+	}
+	wf := subject.TaskWorkflow()
+	if state, ok := wf.FirstOfKind(e.status); ok {
+		task.Status = state.Name
+	}
+	if err := StartStatus(wf, &task, run.now); err != nil {
+		return task, err
+	}
+
+	if task.RecurrenceRule != "" {
+		if err := run.tasks.CreateSeries(&task); err != nil {
+			return task, err
+		}
+		return task, GenerateSeries(run.tasks, task, run.now)
+	}
+
+	if err := run.tasks.Create(&task); err != nil {
+		return task, err
+	}
+	if task.Deadline.IsZero() {
+		return task, nil
+	}
+	return task, run.deadlines.Create(&models.Deadline{
+		TaskID:  task.ID,
+		UserID:  task.UserID,
+		DueDate: task.Deadline,
+	})
+}
+
+// subject returns the user's subject with the given name, creating it on
+// first use.
+func (run *calendarImport) subject(name string) (models.Subject, error) {
+	key := strings.ToLower(name)
+	if s, ok := run.byName[key]; ok {
+		return s, nil
+	}
+
+	s, err := run.subjects.GetByName(run.scope, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s = models.Subject{Name: name, UserID: run.scope.UserID}
+		if err := run.subjects.Create(&s); err != nil {
+			return s, err
+		}
+		run.result.SubjectsCreated = append(run.result.SubjectsCreated, name)
+	} else if err != nil {
+		return s, err
+	}
+
+	run.byName[key] = s
+	return s, nil
+}
+
+func readEntry(c *ical.Component) importEntry {
+	e := importEntry{
+		uid:         strings.TrimSpace(c.Text("UID")),
+		kind:        c.Name,
+		title:       strings.TrimSpace(c.Text("SUMMARY")),
+		description: strings.TrimSpace(c.Text("DESCRIPTION")),
+		rule:        c.Text("RRULE"),
+	}
+
+	if p, ok := c.Get("CATEGORIES"); ok {
+		e.subject = strings.TrimSpace(firstListValue(p.Value))
+	}
+
+	status := strings.ToUpper(c.Text("STATUS"))
+	e.cancelled = status == "CANCELLED"
+	e.status = importStatus[status]
+
+	// VTODOs are due at DUE; assignments exported as VEVENTs at DTSTART
+	for _, name := range []string{"DUE", "DTSTART"} {
+		p, ok := c.Get(name)
+		if !ok {
+			continue
+		}
+		t, allDay, err := p.Time()
+		if err != nil {
+			continue
+		}
+		if allDay {
+			// an all-day entry is due by the end of that day
+			t = t.Add(24*time.Hour - time.Minute)
+		}
+		e.due = t.UTC()
+		break
+	}
+
+	if e.uid == "" {
+		// UID is mandatory, but some exporters drop it; derive a stable one
+		sum := sha256.Sum256([]byte(e.kind + "\n" + e.title + "\n" + e.due.Format(time.RFC3339)))
+		e.uid = hex.EncodeToString(sum[:16]) + "@import"
+	}
+	return e
+}
+
+// firstListValue returns the first entry of a comma-separated TEXT list.
+func firstListValue(raw string) string {
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case ',':
+			return ical.UnescapeText(raw[:i])
+		}
+	}
+	return ical.UnescapeText(raw)
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kadyrbayev2005/studysync/internal/config"
+)
+
+const hmacKeyID = "hs256"
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    interface{} // []byte for HMAC, otherwise a crypto.PublicKey
+}
+
+// KeySet holds the key used to sign new tokens and every key that is still
+// accepted for verification, indexed by kid.
+type KeySet struct {
+	signingKID    string
+	signingMethod jwt.SigningMethod
+	signingKey    interface{}
+	verify        map[string]verificationKey
+}
+
+// LoadKeySet builds the key set described by cfg. For HS256 it wraps the
+// shared secret; for RS256 and EdDSA it reads every <kid>.pem in KeysDir.
+func LoadKeySet(cfg config.JWTConfig) (*KeySet, error) {
+	if cfg.Algorithm == config.JWTAlgHS256 {
+		return newHMACKeySet([]byte(cfg.Secret)), nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(cfg.KeysDir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{verify: map[string]verificationKey{}}
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+
+		private, public, err := readPEMKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %s: %w", kid, err)
+		}
+		method, err := methodFor(public)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %s: %w", kid, err)
+		}
+		ks.verify[kid] = verificationKey{method: method, key: public}
+
+		if kid == cfg.SigningKeyID {
+			if private == nil {
+				return nil, fmt.Errorf("jwt key %s: signing key must be a private key", kid)
+			}
+			if method.Alg() != cfg.Algorithm {
+				return nil, fmt.Errorf("jwt key %s: is a %s key, but algorithm is %s", kid, method.Alg(), cfg.Algorithm)
+			}
+			ks.signingKID, ks.signingMethod, ks.signingKey = kid, method, private
+		}
+	}
+
+	if ks.signingKey == nil {
+		return nil, fmt.Errorf("jwt signing key %q not found in %s", cfg.SigningKeyID, cfg.KeysDir)
+	}
+	return ks, nil
+}
+
+func newHMACKeySet(secret []byte) *KeySet {
+	return &KeySet{
+		signingKID:    hmacKeyID,
+		signingMethod: jwt.SigningMethodHS256,
+		signingKey:    secret,
+		verify: map[string]verificationKey{
+			hmacKeyID: {method: jwt.SigningMethodHS256, key: secret},
+		},
+	}
+}
+
+// keyFunc picks the verification key by the token's kid header and makes
+// sure the token was signed with the algorithm that key belongs to.
+func (ks *KeySet) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	k, ok := ks.verify[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if t.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", t.Method.Alg(), kid)
+	}
+	return k.key, nil
+}
+
+func (ks *KeySet) validMethods() []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, k := range ks.verify {
+		if !seen[k.method.Alg()] {
+			seen[k.method.Alg()] = true
+			out = append(out, k.method.Alg())
+		}
+	}
+	return out
+}
+
+// JWK is a single public key in RFC 7517 format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public verification keys. Shared HMAC secrets are
+// never published, so an HS256 key set yields an empty list.
+func (ks *KeySet) JWKS() JWKS {
+	out := JWKS{Keys: []JWK{}}
+	for kid, k := range ks.verify {
+		switch pub := k.key.(type) {
+		case *rsa.PublicKey:
+			out.Keys = append(out.Keys, JWK{
+				Kty: "RSA", Kid: kid, Use: "sig", Alg: k.method.Alg(),
+				N: b64(pub.N.Bytes()),
+				E: b64(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			out.Keys = append(out.Keys, JWK{
+				Kty: "OKP", Kid: kid, Use: "sig", Alg: k.method.Alg(),
+				Crv: "Ed25519",
+				X:   b64(pub),
+			})
+		}
+	}
+	sort.Slice(out.Keys, func(i, j int) bool { return out.Keys[i].Kid < out.Keys[j].Kid })
+	return out
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// readPEMKey reads a private or public key. For a private key both return
+// values are set; for a public key only the second one is.
+func readPEMKey(path string) (crypto.Signer, crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		signer, ok := k.(crypto.Signer)
+		if !ok {
+			return nil, nil, fmt.Errorf("unsupported private key type %T", k)
+		}
+		return signer, signer.Public(), nil
+	case "RSA PRIVATE KEY":
+		k, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return k, k.Public(), nil
+	case "PUBLIC KEY":
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		return nil, k, err
+	case "RSA PUBLIC KEY":
+		k, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		return nil, k, err
+	default:
+		return nil, nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+func methodFor(pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", pub)
+	}
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	"gorm.io/gorm"
+)
+
+// exclusively runs fn unless a worker in another process is already running
+// the job called name. The job is guarded by a Postgres advisory lock held
+// on a connection of its own, so the lock goes with the process if it dies.
+// Jobs that sweep the whole database, such as generating occurrences or
+// purging the trash, run in one process at a time this way.
+func exclusively(ctx context.Context, db *gorm.DB, name string, fn func()) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		fmt.Printf("%s: %v\n", name, err)
+		return
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		fmt.Printf("%s: %v\n", name, err)
+		return
+	}
+	defer conn.Close()
+
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	key := int64(h.Sum64())
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		fmt.Printf("%s: %v\n", name, err)
+		return
+	}
+	if !locked {
+		return
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			fmt.Printf("%s: %v\n", name, err)
+		}
+	}()
+	fn()
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kadyrbayev2005/studysync/internal/config"
+)
+
+const (
+	MailTransportSMTP   = "smtp"
+	MailTransportOutbox = "outbox"
+	MailTransportLog    = "log"
+)
+
+// Message is a single outgoing email. Text is always sent; HTML is
+// attached as an alternative part when it is not empty.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers email. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer builds the transport selected by cfg.Transport.
+func NewMailer(cfg config.MailConfig) (Mailer, error) {
+	if cfg.From == "" {
+		return nil, errors.New("mailer: from address is required")
+	}
+
+	switch strings.ToLower(cfg.Transport) {
+	case MailTransportSMTP:
+		if cfg.SMTPHost == "" || cfg.SMTPPort == 0 {
+			return nil, errors.New("mailer: smtp host and port are required")
+		}
+		return NewSMTPMailer(cfg), nil
+	case MailTransportOutbox, "":
+		return NewOutboxMailer(cfg.From, cfg.OutboxDir)
+	case MailTransportLog:
+		return NewOutboxMailer(cfg.From, "")
+	default:
+		return nil, fmt.Errorf("mailer: unknown transport %q", cfg.Transport)
+	}
+}
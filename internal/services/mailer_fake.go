@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeMailer is a test double that records every message it is asked to
+// send. Set Err to make Send fail.
+type FakeMailer struct {
+	mu   sync.Mutex
+	sent []Message
+	Err  error
+}
+
+func (m *FakeMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Sent returns a copy of the messages delivered so far.
+func (m *FakeMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]Message, len(m.sent))
+	copy(out, m.sent)
+	return out
+}
+
+func (m *FakeMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = nil
+}
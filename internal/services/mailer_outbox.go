@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// OutboxMailer is the development transport. With a directory it writes
+// every message as an .eml file that any mail client can open; without
+// one it only logs the message to stdout.
+type OutboxMailer struct {
+	from string
+	dir  string
+	seq  atomic.Uint64
+}
+
+func NewOutboxMailer(from, dir string) (*OutboxMailer, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("outbox: %w", err)
+		}
+	}
+	return &OutboxMailer{from: from, dir: dir}, nil
+}
+
+func (m *OutboxMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if m.dir == "" {
+		fmt.Printf("[mail] to=%s subject=%q\n%s\n", msg.To, msg.Subject, msg.Text)
+		return nil
+	}
+
+	now := time.Now()
+	body, err := buildMIME(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%04d-%s.eml",
+		now.UTC().Format("20060102T150405"),
+		m.seq.Add(1)%10000,
+		sanitizeFilename(msg.To),
+	)
+	return os.WriteFile(filepath.Join(m.dir, name), body, 0o644)
+}
+
+func sanitizeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
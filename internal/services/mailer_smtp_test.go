@@ -0,0 +1,101 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMIME(t *testing.T) {
+	date := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+	msg := Message{
+		To:      "ada@example.com",
+		Subject: "StudySync Reminder: Übung 3 is due soon",
+		Text:    "Hi Ada,\n\n\"Übung 3\" is due soon.",
+		HTML:    "<p>Hi Ada,</p>",
+	}
+	raw, err := buildMIME("StudySync <noreply@studysync.local>", msg, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("not an RFC 5322 message: %v\n%s", err, raw)
+	}
+
+	var dec mime.WordDecoder
+	subject, err := dec.DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Subject = %q, %v, want %q", subject, err, msg.Subject)
+	}
+	headers := map[string]string{
+		"From":         "StudySync <noreply@studysync.local>",
+		"To":           "ada@example.com",
+		"Date":         "Mon, 07 Jan 2030 09:00:00 +0000",
+		"Mime-Version": "1.0",
+	}
+	for name, want := range headers {
+		if got := m.Header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	ctype, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || ctype != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", m.Header.Get("Content-Type"), err)
+	}
+	r := multipart.NewReader(m.Body, params["boundary"])
+	for _, want := range []struct{ ctype, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		// NextRawPart leaves the quoted-printable body for the test to decode
+		p, err := r.NextRawPart()
+		if err != nil {
+			t.Fatalf("part %s: %v", want.ctype, err)
+		}
+		if got := p.Header.Get("Content-Type"); got != want.ctype {
+			t.Errorf("part Content-Type = %q, want %q", got, want.ctype)
+		}
+		// text lines go out with CRLF endings
+		body, err := io.ReadAll(quotedprintable.NewReader(p))
+		if wantBody := strings.ReplaceAll(want.body, "\n", "\r\n"); err != nil || string(body) != wantBody {
+			t.Errorf("part %s = %q, %v, want %q", want.ctype, body, err, wantBody)
+		}
+	}
+	if _, err := r.NextPart(); err != io.EOF {
+		t.Errorf("more parts after the HTML one: %v", err)
+	}
+}
+
+func TestBuildMIMEPlainText(t *testing.T) {
+	msg := Message{To: "ada@example.com", Subject: "Reminder", Text: "due = soon"}
+	raw, err := buildMIME("noreply@studysync.local", msg, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Header.Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := m.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+		t.Errorf("Content-Transfer-Encoding = %q", got)
+	}
+	encoded, _ := io.ReadAll(m.Body)
+	if !strings.Contains(string(encoded), "due =3D soon") {
+		t.Errorf("body is not quoted-printable: %q", encoded)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(encoded)))
+	if err != nil || string(body) != msg.Text {
+		t.Errorf("body = %q, %v, want %q", body, err, msg.Text)
+	}
+}
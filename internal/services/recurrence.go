@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/recurrence"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+// recurrenceHorizon is how far ahead occurrences of recurring tasks are
+// materialised as task and deadline rows.
+const recurrenceHorizon = 30 * 24 * time.Hour
+
+func StartRecurrenceWorker(ctx context.Context, db *gorm.DB) {
+	tasks := repository.NewTaskRepository(db)
+
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	generate := func() {
+		exclusively(ctx, db, "recurrence worker", func() { GenerateOccurrences(tasks, time.Now()) })
+	}
+
+	generate()
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("Recurrence worker stopped")
+			return
+		case <-ticker.C:
+			generate()
+		}
+	}
+}
+
+// GenerateOccurrences materialises the upcoming occurrences of every
+// recurring series.
+func GenerateOccurrences(tasks repository.TaskStore, now time.Time) {
+	masters, err := tasks.GetSeriesMasters()
+	if err != nil {
+		fmt.Println("recurrence query error:", err)
+		return
+	}
+
+	for _, m := range masters {
+		if err := GenerateSeries(tasks, m, now); err != nil {
+			fmt.Printf("Failed to generate occurrences of task %d: %v\n", m.ID, err)
+		}
+	}
+}
+
+// GenerateSeries materialises the occurrences of one series up to the
+// horizon. Generation resumes where the previous run stopped, so an
+// occurrence the student deleted is not recreated.
+func GenerateSeries(tasks repository.TaskStore, master models.Task, now time.Time) error {
+	rule, err := recurrence.Parse(master.RecurrenceRule)
+	if err != nil {
+		return err
+	}
+
+	from := master.Deadline
+	if master.RecurrenceGeneratedUntil != nil && master.RecurrenceGeneratedUntil.After(from) {
+		from = *master.RecurrenceGeneratedUntil
+	}
+	if from.Before(now) {
+		from = now
+	}
+	until := now.Add(recurrenceHorizon)
+	if !until.After(from) {
+		return nil
+	}
+
+	for _, at := range rule.Between(master.Deadline, from, until) {
+		if err := tasks.CreateOccurrence(master, at); err != nil {
+			return err
+		}
+	}
+	return tasks.SetGeneratedUntil(master.ID, until)
+}
+
+// SeriesError is a recurring task that cannot be started as given.
+type SeriesError struct {
+	Reason string
+}
+
+func (e *SeriesError) Error() string { return e.Reason }
+
+// StartSeries creates task as the first task of a recurring series and
+// materialises its upcoming occurrences. The series starts at the first
+// date on or after the task's deadline that matches the rule.
+func StartSeries(tasks repository.TaskStore, task *models.Task, now time.Time) error {
+	if task.Deadline.IsZero() {
+		return &SeriesError{"recurring tasks need a deadline as the series start"}
+	}
+	rule, err := recurrence.Parse(task.RecurrenceRule)
+	if err != nil {
+		return &SeriesError{err.Error()}
+	}
+	first, ok := rule.Next(task.Deadline, task.Deadline.Add(-time.Nanosecond))
+	if !ok {
+		return &SeriesError{"recurrence_rule has no occurrences"}
+	}
+	task.Deadline = first
+	task.RecurrenceRule = rule.String()
+
+	if err := tasks.CreateSeries(task); err != nil {
+		return err
+	}
+	if err := GenerateSeries(tasks, *task, now); err != nil {
+		return fmt.Errorf("generate occurrences: %w", err)
+	}
+	return nil
+}
+
+// IsSeriesError reports whether err is a SeriesError.
+func IsSeriesError(err error) bool {
+	var se *SeriesError
+	return errors.As(err, &se)
+}
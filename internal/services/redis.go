@@ -1,17 +1,19 @@
 package services
 
 import (
-    "context"
-    "github.com/redis/go-redis/v9"
+	"context"
+
+	"github.com/kadyrbayev2005/studysync/internal/config"
+	"github.com/redis/go-redis/v9"
 )
 
 var RedisClient *redis.Client
 var Ctx = context.Background()
 
-func InitRedis() {
-    RedisClient = redis.NewClient(&redis.Options{
-        Addr:     "localhost:6379",
-        Password: "",
-        DB:       0,
-    })
+func InitRedis(cfg config.RedisConfig) {
+	RedisClient = redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
 }
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+)
+
+// EffectiveOffsets picks the schedule that applies to a deadline: its own
+// override if there is one, otherwise the owner's defaults, otherwise the
+// system default.
+func EffectiveOffsets(override, defaults []int) []int {
+	if len(override) > 0 {
+		return override
+	}
+	if len(defaults) > 0 {
+		return defaults
+	}
+	return models.DefaultReminderOffsets
+}
+
+// DueOffset returns the offset whose firing time (due - offset) is the most
+// recent one at or before now. Earlier firing times that were missed are
+// superseded by it, so a late-created deadline or a stopped worker never
+// produces a burst of stale reminders.
+func DueOffset(due, now time.Time, offsets []int) (int, bool) {
+	if !due.After(now) {
+		return 0, false
+	}
+
+	best, found := 0, false
+	for _, o := range offsets {
+		fireAt := due.Add(-time.Duration(o) * time.Minute)
+		if fireAt.After(now) {
+			continue
+		}
+		// the smallest passed offset is the latest firing time
+		if !found || o < best {
+			best, found = o, true
+		}
+	}
+	return best, found
+}
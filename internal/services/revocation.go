@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Access tokens are stateless, so revoking one before it expires means
+// remembering it until it would have expired anyway. Single tokens are
+// denylisted by jti; "log out everywhere" stores a per-user cut-off and
+// rejects every token issued before it.
+
+func denylistKey(jti string) string {
+	return "auth:denylist:" + jti
+}
+
+func revokedBeforeKey(userID uint) string {
+	return fmt.Sprintf("auth:revoked-before:%d", userID)
+}
+
+// RevokeAccessToken denylists a single access token until it expires.
+func RevokeAccessToken(claims *Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	return RedisClient.Set(Ctx, denylistKey(claims.ID), 1, ttl).Err()
+}
+
+// RevokeUserAccessTokens invalidates every access token issued to the user
+// up to now.
+func RevokeUserAccessTokens(userID uint) error {
+	return RedisClient.Set(Ctx, revokedBeforeKey(userID), time.Now().Unix(), accessTTL).Err()
+}
+
+// IsAccessTokenRevoked reports whether the token was revoked individually
+// or by a "log out everywhere" for its user.
+func IsAccessTokenRevoked(claims *Claims) (bool, error) {
+	if claims.ID != "" {
+		n, err := RedisClient.Exists(Ctx, denylistKey(claims.ID)).Result()
+		if err != nil {
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+
+	cutoff, err := RedisClient.Get(Ctx, revokedBeforeKey(claims.UserID)).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	ts, err := strconv.ParseInt(cutoff, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	// iat has second precision; the token that triggered the logout is
+	// denylisted by jti, so tokens from the same second are let through
+	// rather than locking out a login that immediately follows.
+	return claims.IssuedAt == nil || claims.IssuedAt.Unix() < ts, nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import "github.com/kadyrbayev2005/studysync/internal/models"
+
+// FillProgress sets the progress of the tasks that have subtasks or
+// checklist items, given all their subtasks and the checklist items of
+// them all. Each item and each subtask that is not cancelled weighs the
+// same; a subtask counts with its own progress, or as done or not. A task
+// that is done is at 100, and only then.
+func FillProgress(tasks, subtasks []models.Task, items []models.ChecklistItem) {
+	children := map[uint][]models.Task{}
+	for _, t := range subtasks {
+		if t.ParentID != nil {
+			children[*t.ParentID] = append(children[*t.ParentID], t)
+		}
+	}
+	checklist := map[uint][]models.ChecklistItem{}
+	for _, i := range items {
+		checklist[i.TaskID] = append(checklist[i.TaskID], i)
+	}
+
+	// percent returns how much of the task is done and whether it has any
+	// parts to measure that by
+	var percent func(t models.Task) (float64, bool)
+	percent = func(t models.Task) (float64, bool) {
+		var sum float64
+		n := 0
+		for _, c := range children[t.ID] {
+			if c.IsCancelled() {
+				continue
+			}
+			p, _ := percent(c)
+			sum += p
+			n++
+		}
+		for _, i := range checklist[t.ID] {
+			if i.Done {
+				sum += 100
+			}
+			n++
+		}
+		switch {
+		case !t.IsOpen() && !t.IsCancelled():
+			return 100, n > 0
+		case n == 0:
+			return 0, false
+		}
+		// a task that is not done is never quite finished
+		return min(sum/float64(n), 99), true
+	}
+
+	for i := range tasks {
+		tasks[i].Progress = nil
+		if p, ok := percent(tasks[i]); ok {
+			v := int(p)
+			tasks[i].Progress = &v
+		}
+	}
+}
@@ -0,0 +1,631 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/patch"
+	"github.com/kadyrbayev2005/studysync/internal/recurrence"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSyncTokenInvalid is returned for a token the server did not issue.
+	ErrSyncTokenInvalid = errors.New("invalid sync token")
+	// ErrSyncTokenExpired is returned for a token older than the retained
+	// change log; the client has to sync again without one.
+	ErrSyncTokenExpired = errors.New("sync token expired, sync again without a token")
+)
+
+// syncError is a change the client sent that cannot be applied; its text
+// goes back to the client.
+type syncError string
+
+func (e syncError) Error() string { return string(e) }
+
+// Syncer runs the offline sync protocol: it applies a client's change set
+// and returns what changed on the server since the client's last sync,
+// read from the change log.
+//
+// Edits are merged per field. A field that only the client changed takes
+// the client's value; a field that the server changed as well since the
+// client's token goes to whichever change was made last, and is reported
+// as a conflict.
+type Syncer struct {
+	db *gorm.DB
+}
+
+func NewSyncer(db *gorm.DB) *Syncer {
+	return &Syncer{db}
+}
+
+// Sync applies req for userID and returns the changes since its token.
+// The client's changes are applied in order in one transaction; one that
+// fails is rolled back on its own and reported in Rejected.
+func (s *Syncer) Sync(userID uint, req models.SyncRequest) (models.SyncResponse, error) {
+	changes := repository.NewChangeRepository(s.db)
+	resp := models.SyncResponse{
+		Subjects:  []models.Subject{},
+		Tasks:     []models.Task{},
+		Deadlines: []models.Deadline{},
+		Deleted:   []models.Tombstone{},
+		Created:   []models.SyncCreated{},
+		Conflicts: []models.SyncConflict{},
+		Rejected:  []models.SyncRejected{},
+	}
+
+	since, err := checkSyncToken(changes, req.Token)
+	if err != nil {
+		return resp, err
+	}
+
+	run := &syncRun{
+		scope:   repository.Scope{UserID: userID},
+		now:     time.Now(),
+		server:  map[string]map[string]time.Time{},
+		deleted: map[string]bool{},
+		ids:     map[string]uint{},
+		resp:    &resp,
+	}
+
+	if len(req.Changes) > 0 {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := run.serverChanges(repository.NewChangeRepository(tx), since); err != nil {
+				return err
+			}
+			for _, c := range req.Changes {
+				run.apply(tx, c)
+			}
+			return nil
+		})
+		if err != nil {
+			return resp, err
+		}
+	}
+
+	return resp, run.pull(s.db, changes, since)
+}
+
+func checkSyncToken(changes repository.ChangeStore, token string) (uint64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	since, err := strconv.ParseUint(token, 10, 64)
+	if err != nil {
+		return 0, ErrSyncTokenInvalid
+	}
+	if since == 0 {
+		return 0, nil
+	}
+	oldest, newest, err := changes.Horizon()
+	if err != nil {
+		return 0, err
+	}
+	if since > newest || since+1 < oldest {
+		return 0, ErrSyncTokenExpired
+	}
+	return since, nil
+}
+
+type syncRun struct {
+	scope repository.Scope
+	now   time.Time
+	// server holds when each column of a row last changed on the server
+	// since the client's token, by rowKey; "" is the row's latest change.
+	server  map[string]map[string]time.Time
+	deleted map[string]bool
+	// ids maps the client ids of the rows created so far to their ids, by
+	// rowKey of the client id.
+	ids  map[string]uint
+	resp *models.SyncResponse
+}
+
+// syncRepos are the repositories of one change's savepoint.
+type syncRepos struct {
+	subjects  repository.SubjectStore
+	tasks     repository.TaskStore
+	deadlines repository.DeadlineStore
+	deps      repository.DependencyStore
+}
+
+// syncOutcome is what applying one change adds to the response; it is only
+// kept when the change commits.
+type syncOutcome struct {
+	created   *models.SyncCreated
+	conflicts []models.SyncConflict
+}
+
+func rowKey(resource string, id interface{}) string {
+	return fmt.Sprintf("%s/%v", resource, id)
+}
+
+func (run *syncRun) serverChanges(changes repository.ChangeStore, since uint64) error {
+	latest, err := changes.Latest(run.scope.UserID)
+	if err != nil {
+		return err
+	}
+	log, err := changes.Since(run.scope.UserID, since, latest)
+	if err != nil {
+		return err
+	}
+	for _, c := range log {
+		key := rowKey(c.Resource, c.ResourceID)
+		fields := run.server[key]
+		if fields == nil {
+			fields = map[string]time.Time{}
+			run.server[key] = fields
+		}
+		fields[""] = c.ChangedAt
+		for _, f := range c.FieldList() {
+			fields[f] = c.ChangedAt
+		}
+		run.deleted[key] = c.Op == models.ChangeDelete
+	}
+	return nil
+}
+
+func (run *syncRun) apply(tx *gorm.DB, c models.SyncChange) {
+	// a client clock running ahead must not win every conflict
+	if c.ChangedAt.IsZero() || c.ChangedAt.After(run.now) {
+		c.ChangedAt = run.now
+	}
+
+	var out syncOutcome
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		r := syncRepos{
+			subjects:  repository.NewSubjectRepository(tx),
+			tasks:     repository.NewTaskRepository(tx),
+			deadlines: repository.NewDeadlineRepository(tx),
+			deps:      repository.NewDependencyRepository(tx),
+		}
+		var err error
+		out, err = run.change(r, c)
+		return err
+	})
+	if err != nil {
+		var se syncError
+		msg := "failed to apply change"
+		switch {
+		case errors.As(err, &se):
+			msg = se.Error()
+		case errors.Is(err, repository.ErrConflict):
+			msg = "the row changed while syncing, send the change again"
+		default:
+			log.Printf("Failed to sync %s of %s %d for user %d: %v", c.Op, c.Resource, c.ID, run.scope.UserID, err)
+		}
+		run.resp.Rejected = append(run.resp.Rejected, models.SyncRejected{
+			Resource: c.Resource, ID: c.ID, ClientID: c.ClientID, Error: msg,
+		})
+		return
+	}
+
+	if out.created != nil {
+		run.ids[rowKey(out.created.Resource, out.created.ClientID)] = out.created.ID
+		run.resp.Created = append(run.resp.Created, *out.created)
+	}
+	run.resp.Conflicts = append(run.resp.Conflicts, out.conflicts...)
+}
+
+func (run *syncRun) change(r syncRepos, c models.SyncChange) (syncOutcome, error) {
+	if c.Op == models.SyncCreate && c.ClientID == "" {
+		return syncOutcome{}, syncError("client_id is required to create a row")
+	}
+	if c.Op != models.SyncCreate && c.ID == 0 {
+		return syncOutcome{}, syncError("id is required")
+	}
+
+	switch c.Resource + " " + c.Op {
+	case "subjects create":
+		return run.createSubject(r, c)
+	case "subjects update":
+		return run.updateSubject(r, c)
+	case "subjects delete":
+		return run.deleteSubject(r, c)
+	case "tasks create":
+		return run.createTask(r, c)
+	case "tasks update":
+		return run.updateTask(r, c)
+	case "tasks delete":
+		return run.deleteTask(r, c)
+	case "deadlines create":
+		return run.createDeadline(r, c)
+	case "deadlines update":
+		return syncOutcome{}, syncError("deadlines cannot be changed, delete and create them instead")
+	case "deadlines delete":
+		return run.deleteDeadline(r, c)
+	}
+	return syncOutcome{}, syncError(fmt.Sprintf("unknown change %q on %q", c.Op, c.Resource))
+}
+
+func (run *syncRun) createSubject(r syncRepos, c models.SyncChange) (syncOutcome, error) {
+	var p models.SubjectPatch
+	if err := applyFields(models.SubjectPatch{}, c.Fields, &p); err != nil {
+		return syncOutcome{}, err
+	}
+	subject := models.Subject{Name: p.Name, Description: p.Description, Workflow: p.Workflow, UserID: run.scope.UserID}
+	if err := r.subjects.Create(&subject); err != nil {
+		return syncOutcome{}, err
+	}
+	return syncOutcome{created: &models.SyncCreated{Resource: c.Resource, ClientID: c.ClientID, ID: subject.ID}}, nil
+}
+
+func (run *syncRun) updateSubject(r syncRepos, c models.SyncChange) (syncOutcome, error) {
+	subject, err := r.subjects.GetByID(run.scope, c.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return run.missing(c)
+	}
+	if err != nil {
+		return syncOutcome{}, err
+	}
+
+	cur := models.NewSubjectPatch(subject)
+	fields, out := run.merge(c, cur)
+	var p models.SubjectPatch
+	if err := applyFields(cur, fields, &p); err != nil {
+		return out, err
+	}
+	if data := p.Changes(subject); len(data) > 0 {
+		return out, r.subjects.Update(run.scope, subject, data)
+	}
+	return out, nil
+}
+
+func (run *syncRun) deleteSubject(r syncRepos, c models.SyncChange) (syncOutcome, error) {
+	subject, err := r.subjects.GetByID(run.scope, c.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return run.missing(c)
+	}
+	if err != nil {
+		return syncOutcome{}, err
+	}
+	if out, keep := run.keepRow(c); keep {
+		return out, nil
+	}
+	// its tasks go to the trash with it and reach the client as tombstones
+	return syncOutcome{}, r.subjects.Delete(run.scope, subject)
+}
+
+func (run *syncRun) createTask(r syncRepos, c models.SyncChange) (syncOutcome, error) {
+	if err := run.resolveID(c.Fields, "subject_id", models.ResourceSubjects); err != nil {
+		return syncOutcome{}, err
+	}
+	var p models.TaskPatch
+	if err := applyFields(models.NewTaskPatch(models.Task{}), c.Fields, &p); err != nil {
+		return syncOutcome{}, err
+	}
+	subject, err := r.subjects.GetByID(run.scope, p.SubjectID)
+	if err != nil {
+		return syncOutcome{}, syncError("subject not found")
+	}
+
+	task := models.Task{
+		Title:           p.Title,
+		Description:     p.Description,
+		Status:          p.Status,
+		Deadline:        p.Deadline,
+		SubjectID:       p.SubjectID,
+		Subject:         subject,
+		EstimateMinutes: p.EstimateMinutes,
+		UserID:          run.scope.UserID,
+		RecurrenceRule:  p.RecurrenceRule,
+	}
+	if err := StartStatus(subject.TaskWorkflow(), &task, run.now); err != nil {
+		return syncOutcome{}, syncError(err.Error())
+	}
+	if task.RecurrenceRule != "" {
+		err = StartSeries(r.tasks, &task, run.now)
+		if IsSeriesError(err) {
+			return syncOutcome{}, syncError(err.Error())
+		}
+	} else {
+		err = r.tasks.Create(&task)
+	}
+	if err != nil {
+		return syncOutcome{}, err
+	}
+	return syncOutcome{created: &models.SyncCreated{Resource: c.Resource, ClientID: c.ClientID, ID: task.ID}}, nil
+}
+
+func (run *syncRun) updateTask(r syncRepos, c models.SyncChange) (syncOutcome, error) {
+	task, err := r.tasks.GetByID(run.scope, c.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return run.missing(c)
+	}
+	if err != nil {
+		return syncOutcome{}, err
+	}
+	if err := run.resolveID(c.Fields, "subject_id", models.ResourceSubjects); err != nil {
+		return syncOutcome{}, err
+	}
+
+	cur := models.NewTaskPatch(task)
+	fields, out := run.merge(c, cur)
+	var p models.TaskPatch
+	if err := applyFields(cur, fields, &p); err != nil {
+		return out, err
+	}
+	data := p.Changes(task)
+
+	wf := task.Subject.TaskWorkflow()
+	if _, ok := data["subject_id"]; ok {
+		subject, err := r.subjects.GetByID(repository.Scope{UserID: task.UserID}, p.SubjectID)
+		if err != nil {
+			return out, syncError("subject not found")
+		}
+		wf = subject.TaskWorkflow()
+	}
+	if err := ChangeStatus(wf, task, data, c.ChangedAt); err != nil {
+		return out, syncError(err.Error())
+	}
+	if err := CheckBlockers(r.deps, run.scope, task, data); err != nil {
+		if IsStatusError(err) {
+			return out, syncError(err.Error())
+		}
+		return out, err
+	}
+	if _, ok := data["recurrence_rule"]; ok {
+		if task.IsSeriesMaster() || task.SeriesID != nil {
+			return out, syncError("the recurrence_rule of a recurring task can only be changed with PATCH /tasks/{id}?apply_to=following")
+		}
+		if p.RecurrenceRule != "" {
+			rule, err := recurrence.Parse(p.RecurrenceRule)
+			if err != nil {
+				return out, syncError(err.Error())
+			}
+			data["recurrence_rule"] = rule.String()
+		}
+	}
+
+	if len(data) > 0 {
+		return out, r.tasks.UpdateOccurrence(run.scope, task, data)
+	}
+	return out, nil
+}
+
+func (run *syncRun) deleteTask(r syncRepos, c models.SyncChange) (syncOutcome, error) {
+	task, err := r.tasks.GetByID(run.scope, c.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return run.missing(c)
+	}
+	if err != nil {
+		return syncOutcome{}, err
+	}
+	if out, keep := run.keepRow(c); keep {
+		return out, nil
+	}
+	return syncOutcome{}, r.tasks.DeleteOccurrence(run.scope, task)
+}
+
+func (run *syncRun) createDeadline(r syncRepos, c models.SyncChange) (syncOutcome, error) {
+	if err := run.resolveID(c.Fields, "task_id", models.ResourceTasks); err != nil {
+		return syncOutcome{}, err
+	}
+	var req models.DeadlineRequest
+	if err := applyFields(models.DeadlineRequest{}, c.Fields, &req); err != nil {
+		return syncOutcome{}, err
+	}
+	task, err := r.tasks.GetByID(run.scope, req.TaskID)
+	if err != nil {
+		return syncOutcome{}, syncError("task not found")
+	}
+
+	d := models.Deadline{TaskID: task.ID, UserID: task.UserID, DueDate: req.DueDate}
+	if err := r.deadlines.Create(&d); err != nil {
+		return syncOutcome{}, err
+	}
+	return syncOutcome{created: &models.SyncCreated{Resource: c.Resource, ClientID: c.ClientID, ID: d.ID}}, nil
+}
+
+func (run *syncRun) deleteDeadline(r syncRepos, c models.SyncChange) (syncOutcome, error) {
+	d, err := r.deadlines.GetByID(run.scope, c.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return run.missing(c)
+	}
+	if err != nil {
+		return syncOutcome{}, err
+	}
+	if out, keep := run.keepRow(c); keep {
+		return out, nil
+	}
+	return syncOutcome{}, r.deadlines.Delete(run.scope, d)
+}
+
+// missing handles a change to a row that does not exist. A row the server
+// deleted since the client's token stays deleted: deleting it again is a
+// no-op and editing it is a conflict the server wins.
+func (run *syncRun) missing(c models.SyncChange) (syncOutcome, error) {
+	if !run.deleted[rowKey(c.Resource, c.ID)] {
+		return syncOutcome{}, syncError("not found")
+	}
+	if c.Op == models.SyncDelete {
+		return syncOutcome{}, nil
+	}
+	return syncOutcome{conflicts: []models.SyncConflict{{Resource: c.Resource, ID: c.ID, Winner: "server"}}}, nil
+}
+
+// keepRow decides a delete of a row that the server changed since the
+// client's token: the later of the two wins.
+func (run *syncRun) keepRow(c models.SyncChange) (syncOutcome, bool) {
+	at, ok := run.server[rowKey(c.Resource, c.ID)][""]
+	if !ok || c.ChangedAt.After(at) {
+		return syncOutcome{}, false
+	}
+	return syncOutcome{conflicts: []models.SyncConflict{{Resource: c.Resource, ID: c.ID, Winner: "server"}}}, true
+}
+
+// merge returns the fields of c that win against the server's changes
+// since the client's token, and the conflicts it resolved.
+func (run *syncRun) merge(c models.SyncChange, cur interface{}) (map[string]json.RawMessage, syncOutcome) {
+	var out syncOutcome
+	changed := run.server[rowKey(c.Resource, c.ID)]
+	if len(changed) == 0 {
+		return c.Fields, out
+	}
+
+	doc, _ := json.Marshal(cur)
+	var server map[string]interface{}
+	json.Unmarshal(doc, &server)
+
+	names := make([]string, 0, len(c.Fields))
+	for name := range c.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := map[string]json.RawMessage{}
+	for _, name := range names {
+		raw := c.Fields[name]
+		at, ok := changed[name]
+		if !ok {
+			fields[name] = raw
+			continue
+		}
+		var client interface{}
+		json.Unmarshal(raw, &client)
+		if reflect.DeepEqual(client, server[name]) {
+			continue
+		}
+
+		conflict := models.SyncConflict{
+			Resource:    c.Resource,
+			ID:          c.ID,
+			Field:       name,
+			ClientValue: client,
+			ServerValue: server[name],
+			Winner:      "server",
+		}
+		if c.ChangedAt.After(at) {
+			conflict.Winner = "client"
+			fields[name] = raw
+		}
+		out.conflicts = append(out.conflicts, conflict)
+	}
+	return fields, out
+}
+
+// resolveID replaces a client id given for the field name with the id of
+// the row created for it earlier in the request.
+func (run *syncRun) resolveID(fields map[string]json.RawMessage, name, resource string) error {
+	var clientID string
+	if json.Unmarshal(fields[name], &clientID) != nil {
+		return nil
+	}
+	id, ok := run.ids[rowKey(resource, clientID)]
+	if !ok {
+		return syncError(fmt.Sprintf("%s: unknown client_id %q", name, clientID))
+	}
+	fields[name] = json.RawMessage(strconv.FormatUint(uint64(id), 10))
+	return nil
+}
+
+// applyFields merges fields into cur, a patch DTO, and decodes and
+// validates the result into dst, like a PATCH request.
+func applyFields(cur interface{}, fields map[string]json.RawMessage, dst interface{}) error {
+	if fields == nil {
+		fields = map[string]json.RawMessage{}
+	}
+	doc, err := json.Marshal(cur)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return syncError(err.Error())
+	}
+	merged, err := patch.MergePatch(doc, body)
+	if err != nil {
+		return syncError(err.Error())
+	}
+	if err := patch.Decode(merged, dst); err != nil {
+		return syncError(err.Error())
+	}
+	if err := binding.Validator.ValidateStruct(dst); err != nil {
+		return syncError(err.Error())
+	}
+	if v, ok := dst.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return syncError(err.Error())
+		}
+	}
+	return nil
+}
+
+// pull fills the response with the user's rows that changed after since,
+// or with all of them for a first sync, and the token for the next sync.
+// The token is read first, so a row changing meanwhile is sent again next
+// time rather than missed.
+func (run *syncRun) pull(db *gorm.DB, changes repository.ChangeStore, since uint64) error {
+	subjects := repository.NewSubjectRepository(db)
+	tasks := repository.NewTaskRepository(db)
+	deadlines := repository.NewDeadlineRepository(db)
+	resp := run.resp
+
+	latest, err := changes.Latest(run.scope.UserID)
+	if err != nil {
+		return err
+	}
+	resp.Token = strconv.FormatUint(latest, 10)
+
+	if since == 0 {
+		resp.Full = true
+		if resp.Subjects, err = subjects.GetAll(run.scope); err != nil {
+			return err
+		}
+		if resp.Tasks, err = tasks.GetAll(run.scope); err != nil {
+			return err
+		}
+		resp.Deadlines, err = deadlines.GetAll(run.scope)
+		return err
+	}
+
+	entries, err := changes.Since(run.scope.UserID, since, latest)
+	if err != nil {
+		return err
+	}
+	// only the last change of each row matters
+	last := map[string]int{}
+	for i, c := range entries {
+		last[rowKey(c.Resource, c.ResourceID)] = i
+	}
+
+	for i, c := range entries {
+		if last[rowKey(c.Resource, c.ResourceID)] != i {
+			continue
+		}
+		if c.Op == models.ChangeDelete {
+			resp.Deleted = append(resp.Deleted, models.Tombstone{Resource: c.Resource, ID: c.ResourceID, DeletedAt: c.ChangedAt})
+			continue
+		}
+		// a row that is gone by now has a delete further on in the log
+		switch c.Resource {
+		case models.ResourceSubjects:
+			if s, err := subjects.GetByID(run.scope, c.ResourceID); err == nil {
+				resp.Subjects = append(resp.Subjects, s)
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		case models.ResourceTasks:
+			if t, err := tasks.GetByID(run.scope, c.ResourceID); err == nil {
+				resp.Tasks = append(resp.Tasks, t)
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		case models.ResourceDeadlines:
+			if d, err := deadlines.GetByID(run.scope, c.ResourceID); err == nil {
+				resp.Deadlines = append(resp.Deadlines, d)
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+	}
+	return nil
+}
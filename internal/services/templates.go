@@ -0,0 +1,47 @@
+package services
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var (
+	reminderText = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/reminder.txt.tmpl"))
+	reminderHTML = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/reminder.html.tmpl"))
+)
+
+type ReminderData struct {
+	UserName        string
+	TaskTitle       string
+	TaskDescription string
+	SubjectName     string
+	DueDate         time.Time
+}
+
+// RenderReminder builds the reminder email for a single deadline.
+func RenderReminder(to string, data ReminderData) (Message, error) {
+	if data.UserName == "" {
+		data.UserName = "there"
+	}
+
+	var text, html bytes.Buffer
+	if err := reminderText.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	if err := reminderHTML.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      to,
+		Subject: "StudySync Reminder: " + data.TaskTitle + " is due soon",
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// IssueTokenPair creates an access token and a refresh token for the user.
+// An empty familyID starts a new rotation chain (a new login).
+func IssueTokenPair(repo *repository.RefreshTokenRepository, userID uint, role, familyID string) (models.TokenResponse, error) {
+	access, err := GenerateJWT(userID, role)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	if familyID == "" {
+		if familyID, err = randomToken(16); err != nil {
+			return models.TokenResponse{}, err
+		}
+	}
+
+	raw, err := randomToken(32)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	rt := models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(refreshTTL),
+	}
+	if err := repo.Create(&rt); err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	return models.TokenResponse{
+		Token:        access,
+		AccessToken:  access,
+		RefreshToken: raw,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(accessTTL / time.Second),
+	}, nil
+}
+
+// ConsumeRefreshToken validates a refresh token and marks it used so it can
+// be exchanged exactly once. Presenting a token that was already used means
+// it leaked: the whole family is revoked and ErrRefreshTokenReused returned.
+func ConsumeRefreshToken(repo *repository.RefreshTokenRepository, raw string) (models.RefreshToken, error) {
+	now := time.Now()
+
+	rt, err := repo.GetByHash(hashToken(raw))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return rt, ErrRefreshTokenInvalid
+		}
+		return rt, err
+	}
+
+	if rt.RevokedAt != nil {
+		return rt, ErrRefreshTokenInvalid
+	}
+	if rt.UsedAt != nil {
+		return rt, revokeReused(repo, rt, now)
+	}
+	if now.After(rt.ExpiresAt) {
+		return rt, ErrRefreshTokenInvalid
+	}
+
+	if err := repo.MarkUsed(rt.ID, now); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// lost a race with another request presenting the same token
+			return rt, revokeReused(repo, rt, now)
+		}
+		return rt, err
+	}
+	return rt, nil
+}
+
+// RevokeRefreshToken revokes the family of the given token, if it belongs
+// to userID. Unknown tokens are ignored so logout is idempotent.
+func RevokeRefreshToken(repo *repository.RefreshTokenRepository, userID uint, raw string) error {
+	rt, err := repo.GetByHash(hashToken(raw))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if rt.UserID != userID {
+		return nil
+	}
+	return repo.RevokeFamily(rt.FamilyID, time.Now())
+}
+
+func revokeReused(repo *repository.RefreshTokenRepository, rt models.RefreshToken, now time.Time) error {
+	if err := repo.RevokeFamily(rt.FamilyID, now); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/repository"
+	"gorm.io/gorm"
+)
+
+// StartTrashWorker purges deleted subjects, tasks and deadlines once they
+// have been in the trash longer than retention.
+func StartTrashWorker(ctx context.Context, db *gorm.DB, retention time.Duration) {
+	trash := repository.NewTrashRepository(db)
+
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	purge := func() {
+		exclusively(ctx, db, "trash worker", func() { PurgeTrash(trash, time.Now().Add(-retention)) })
+	}
+
+	purge()
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("Trash worker stopped")
+			return
+		case <-ticker.C:
+			purge()
+		}
+	}
+}
+
+// PurgeTrash deletes for good what went to the trash before the given time.
+func PurgeTrash(trash repository.TrashStore, before time.Time) {
+	n, err := trash.Purge(before)
+	if err != nil {
+		fmt.Println("trash purge error:", err)
+		return
+	}
+	if n > 0 {
+		fmt.Printf("Purged %d items from the trash\n", n)
+	}
+}
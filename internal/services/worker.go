@@ -6,10 +6,22 @@ import (
 	"time"
 
 	"github.com/kadyrbayev2005/studysync/internal/models"
+	"github.com/kadyrbayev2005/studysync/internal/repository"
 	"gorm.io/gorm"
 )
 
-func StartReminderWorker(ctx context.Context, db *gorm.DB) {
+const (
+	maxDeliveryAttempts = 5
+	deliveryBatchSize   = 100
+	// deliveryLease is how long a claimed batch is kept from other workers:
+	// long enough to send all of it with every delivery timing out.
+	deliveryLease = deliveryBatchSize * smtpTimeout
+)
+
+func StartReminderWorker(ctx context.Context, db *gorm.DB, mailer Mailer) {
+	notifications := repository.NewNotificationRepository(db)
+	reminders := repository.NewReminderRepository(db)
+
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
@@ -20,39 +32,151 @@ func StartReminderWorker(ctx context.Context, db *gorm.DB) {
 			return
 		case <-ticker.C:
 			now := time.Now()
-			soon := now.Add(15 * time.Minute)
+			scheduleReminders(db, reminders, notifications, now)
+			deliverReminders(ctx, notifications, mailer, now)
+		}
+	}
+}
 
-			var due []models.Deadline
+// scheduleReminders records a pending notification for every deadline whose
+// reminder schedule has an offset firing now. Offsets that already have one
+// are skipped by the notification log's unique index, so each reminder is
+// sent once.
+func scheduleReminders(db *gorm.DB, reminders *repository.ReminderRepository, notifications *repository.NotificationRepository, now time.Time) {
+	lookahead, err := reminders.MaxOffset()
+	if err != nil {
+		fmt.Println("worker query error:", err)
+		return
+	}
+	for _, o := range models.DefaultReminderOffsets {
+		lookahead = max(lookahead, o)
+	}
 
-			if err := db.Preload("Task").Preload("User").
-				Where("due_date > ? AND due_date <= ?", now, soon).
-				Find(&due).Error; err != nil {
+	var due []models.Deadline
 
-				fmt.Println("worker query error:", err)
-				continue
-			}
+	if err := db.Where("due_date > ? AND due_date <= ?", now, now.Add(time.Duration(lookahead)*time.Minute)).
+		Find(&due).Error; err != nil {
+
+		fmt.Println("worker query error:", err)
+		return
+	}
+
+	deadlineIDs := make([]uint, 0, len(due))
+	userIDs := make([]uint, 0, len(due))
+	for _, d := range due {
+		deadlineIDs = append(deadlineIDs, d.ID)
+		userIDs = append(userIDs, d.UserID)
+	}
+
+	overrides, err := reminders.GetOverrides(deadlineIDs)
+	if err != nil {
+		fmt.Println("worker query error:", err)
+		return
+	}
+	defaults, err := reminders.GetDefaults(userIDs)
+	if err != nil {
+		fmt.Println("worker query error:", err)
+		return
+	}
+
+	for _, d := range due {
+		offset, ok := DueOffset(d.DueDate, now, EffectiveOffsets(overrides[d.ID], defaults[d.UserID]))
+		if !ok {
+			continue
+		}
+
+		n := models.Notification{
+			DeadlineID:    d.ID,
+			UserID:        d.UserID,
+			Channel:       models.ChannelEmail,
+			OffsetMinutes: offset,
+			NextAttemptAt: now,
+		}
+		if err := notifications.Enqueue(&n); err != nil {
+			fmt.Println("Failed to enqueue reminder:", err)
+		}
+	}
+}
 
-			for _, d := range due {
-				userEmail := d.User.Email
-				if userEmail == "" {
-					continue
-				}
-
-				subject := "StudySync Reminder: Upcoming Deadline"
-				body := fmt.Sprintf(
-					"Task '%s' is due at %s\nDescription: %s",
-					d.Task.Title,
-					d.DueDate.Format(time.RFC3339),
-					d.Task.Description,
-				)
-
-				err := SendEmail(userEmail, subject, body)
-				if err != nil {
-					fmt.Println("Failed to send email:", err)
-				} else {
-					fmt.Println("Email sent to", userEmail)
-				}
+// deliveryQueue is the part of the notification log that deliverReminders
+// works through.
+type deliveryQueue interface {
+	ClaimDue(now time.Time, lease time.Duration, maxAttempts, limit int) ([]models.Notification, error)
+	Release(ids []uint, at time.Time) error
+	MarkSent(id uint, at time.Time) error
+	MarkFailed(id uint, cause string, next time.Time) error
+}
+
+// deliverReminders sends pending notifications and retries failed ones
+// with exponential backoff until maxDeliveryAttempts is reached. What is
+// left of the batch when ctx is done is released for the next worker.
+func deliverReminders(ctx context.Context, notifications deliveryQueue, mailer Mailer, now time.Time) {
+	pending, err := notifications.ClaimDue(now, deliveryLease, maxDeliveryAttempts, deliveryBatchSize)
+	if err != nil {
+		fmt.Println("worker query error:", err)
+		return
+	}
+
+	release := func(rest []models.Notification) {
+		ids := make([]uint, 0, len(rest))
+		for _, n := range rest {
+			ids = append(ids, n.ID)
+		}
+		if err := notifications.Release(ids, now); err != nil {
+			fmt.Println("Failed to release notifications:", err)
+		}
+	}
+
+	for i, n := range pending {
+		if ctx.Err() != nil {
+			release(pending[i:])
+			return
+		}
+		if err := sendReminder(ctx, mailer, n.Deadline); err != nil {
+			if ctx.Err() != nil {
+				// stopped, not failed: the attempt does not count
+				release(pending[i:])
+				return
+			}
+			fmt.Println("Failed to send email:", err)
+			next := now.Add(retryBackoff(n.Attempts + 1))
+			if err := notifications.MarkFailed(n.ID, err.Error(), next); err != nil {
+				fmt.Println("Failed to record notification:", err)
 			}
+			continue
 		}
+
+		if err := notifications.MarkSent(n.ID, time.Now()); err != nil {
+			fmt.Println("Failed to record notification:", err)
+			continue
+		}
+		fmt.Println("Email sent to", n.Deadline.User.Email)
+	}
+}
+
+func sendReminder(ctx context.Context, mailer Mailer, d models.Deadline) error {
+	if d.User.Email == "" {
+		return fmt.Errorf("user %d has no email address", d.UserID)
+	}
+
+	msg, err := RenderReminder(d.User.Email, ReminderData{
+		UserName:        d.User.Name,
+		TaskTitle:       d.Task.Title,
+		TaskDescription: d.Task.Description,
+		SubjectName:     d.Task.Subject.Name,
+		DueDate:         d.DueDate,
+	})
+	if err != nil {
+		return fmt.Errorf("render reminder: %w", err)
+	}
+
+	return mailer.Send(ctx, msg)
+}
+
+// retryBackoff doubles the wait after every failed attempt: 1m, 2m, 4m, ...
+func retryBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
 	}
+	return time.Minute << (attempts - 1)
 }
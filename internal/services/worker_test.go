@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+)
+
+// queue is a deliveryQueue holding one batch. It records what
+// deliverReminders does with every notification of it.
+type queue struct {
+	batch    []models.Notification
+	sent     []uint
+	failed   map[uint]time.Time
+	released []uint
+}
+
+func (q *queue) ClaimDue(now time.Time, lease time.Duration, maxAttempts, limit int) ([]models.Notification, error) {
+	batch := q.batch
+	q.batch = nil
+	return batch, nil
+}
+
+func (q *queue) Release(ids []uint, at time.Time) error {
+	q.released = append(q.released, ids...)
+	return nil
+}
+
+func (q *queue) MarkSent(id uint, at time.Time) error {
+	q.sent = append(q.sent, id)
+	return nil
+}
+
+func (q *queue) MarkFailed(id uint, cause string, next time.Time) error {
+	if q.failed == nil {
+		q.failed = map[uint]time.Time{}
+	}
+	q.failed[id] = next
+	return nil
+}
+
+var tick = time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+
+func notification(id uint, email, title string, attempts int) models.Notification {
+	return models.Notification{
+		ID:       id,
+		Attempts: attempts,
+		Deadline: models.Deadline{
+			ID:      id,
+			DueDate: tick.Add(time.Hour),
+			User:    models.User{Name: "Ada", Email: email},
+			Task:    models.Task{Title: title, Subject: models.Subject{Name: "Calculus"}},
+		},
+	}
+}
+
+func TestDeliverReminders(t *testing.T) {
+	q := &queue{batch: []models.Notification{
+		notification(1, "ada@example.com", "Problem set", 0),
+		notification(2, "bob@example.com", "Essay", 0),
+	}}
+	mailer := &FakeMailer{}
+	deliverReminders(context.Background(), q, mailer, tick)
+
+	sent := mailer.Sent()
+	if len(sent) != 2 {
+		t.Fatalf("sent %d emails, want one per due reminder: %+v", len(sent), sent)
+	}
+	for i, want := range []struct{ to, title string }{{"ada@example.com", "Problem set"}, {"bob@example.com", "Essay"}} {
+		msg := sent[i]
+		if msg.To != want.to || !strings.Contains(msg.Subject, want.title) {
+			t.Errorf("email %d to %q about %q, want %q about %q", i, msg.To, msg.Subject, want.to, want.title)
+		}
+		if !strings.Contains(msg.Text, "Calculus") || msg.HTML == "" {
+			t.Errorf("email %d: text %q, html %q", i, msg.Text, msg.HTML)
+		}
+	}
+	if len(q.sent) != 2 || len(q.failed) != 0 || len(q.released) != 0 {
+		t.Errorf("marked sent %v, failed %v, released %v", q.sent, q.failed, q.released)
+	}
+
+	// the next tick finds nothing left to send
+	deliverReminders(context.Background(), q, mailer, tick)
+	if len(mailer.Sent()) != 2 {
+		t.Errorf("a delivered reminder was sent again")
+	}
+}
+
+func TestDeliverRemindersTransportError(t *testing.T) {
+	q := &queue{batch: []models.Notification{
+		notification(1, "ada@example.com", "Problem set", 0),
+		notification(2, "bob@example.com", "Essay", 2),
+		notification(3, "", "Lab", 0),
+	}}
+	mailer := &FakeMailer{Err: errors.New("421 service not available")}
+	deliverReminders(context.Background(), q, mailer, tick)
+
+	if len(q.sent) != 0 || len(mailer.Sent()) != 0 {
+		t.Fatalf("marked %v sent after the transport failed", q.sent)
+	}
+	want := map[uint]time.Time{
+		1: tick.Add(time.Minute),
+		2: tick.Add(4 * time.Minute),
+		3: tick.Add(time.Minute),
+	}
+	for id, next := range want {
+		if got, ok := q.failed[id]; !ok || !got.Equal(next) {
+			t.Errorf("notification %d retried at %v, want %v", id, got, next)
+		}
+	}
+}
+
+func TestDeliverRemindersStopped(t *testing.T) {
+	q := &queue{batch: []models.Notification{
+		notification(1, "ada@example.com", "Problem set", 0),
+		notification(2, "bob@example.com", "Essay", 0),
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mailer := &FakeMailer{}
+	deliverReminders(ctx, q, mailer, tick)
+
+	if len(mailer.Sent()) != 0 || len(q.failed) != 0 {
+		t.Errorf("a stopped worker sent %d emails and failed %v", len(mailer.Sent()), q.failed)
+	}
+	if len(q.released) != 2 {
+		t.Errorf("released %v, want the whole batch", q.released)
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kadyrbayev2005/studysync/internal/models"
+)
+
+// StatusError is a status a task cannot take. Unknown is set when the
+// workflow has no such status; otherwise it does not allow the move.
+type StatusError struct {
+	Reason  string
+	Unknown bool
+}
+
+func (e *StatusError) Error() string { return e.Reason }
+
+// IsStatusError reports whether err is a StatusError.
+func IsStatusError(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se)
+}
+
+func unknownStatus(wf models.Workflow, status string) *StatusError {
+	names := make([]string, len(wf.States))
+	for i, s := range wf.States {
+		names[i] = s.Name
+	}
+	return &StatusError{
+		Reason:  fmt.Sprintf("unknown status %q, want one of %s", status, strings.Join(names, ", ")),
+		Unknown: true,
+	}
+}
+
+// ChangeStatus checks the "status" set by data, a task update, against the
+// workflow and adds the started_at and completed_at that go with it.
+// Updates that leave the status alone are not touched.
+func ChangeStatus(wf models.Workflow, task models.Task, data map[string]interface{}, now time.Time) error {
+	v, ok := data["status"]
+	if !ok {
+		return nil
+	}
+	to, _ := v.(string)
+	if to == task.Status {
+		return nil
+	}
+	state, ok := wf.State(to)
+	if !ok {
+		return unknownStatus(wf, to)
+	}
+	if !wf.Allows(task.Status, to) {
+		return &StatusError{Reason: fmt.Sprintf("a task cannot go from %q to %q", task.Status, to)}
+	}
+	for k, v := range stamp(state, task.StartedAt, now) {
+		data[k] = v
+	}
+	return nil
+}
+
+// StartStatus gives a new task the workflow's first status unless it has
+// one, and the timestamps of that status. A task can start in any state.
+func StartStatus(wf models.Workflow, task *models.Task, now time.Time) error {
+	if task.Status == "" {
+		task.Status = wf.Initial()
+	}
+	state, ok := wf.State(task.Status)
+	if !ok {
+		return unknownStatus(wf, task.Status)
+	}
+	task.StartedAt, task.CompletedAt = nil, nil
+	ts := stamp(state, nil, now)
+	if t, ok := ts["started_at"].(time.Time); ok {
+		task.StartedAt = &t
+	}
+	if t, ok := ts["completed_at"].(time.Time); ok {
+		task.CompletedAt = &t
+	}
+	return nil
+}
+
+// stamp returns the columns a task entering state gets. Work starts when a
+// task first becomes active or done and is forgotten when it goes back to
+// a todo state; only done states have a completion time.
+func stamp(state models.WorkflowState, startedAt *time.Time, now time.Time) map[string]interface{} {
+	c := map[string]interface{}{"status": state.Name, "completed_at": nil}
+	started := now
+	if startedAt != nil {
+		started = *startedAt
+	}
+	switch state.Kind {
+	case models.KindTodo:
+		c["started_at"] = nil
+	case models.KindActive:
+		c["started_at"] = started
+	case models.KindDone:
+		c["started_at"] = started
+		c["completed_at"] = now
+	}
+	return c
+}